@@ -7,15 +7,35 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"blog-api/db"
+	analyticsHandler "blog-api/handlers/analytics"
 	blogHandler "blog-api/handlers/blog"
+	feedHandler "blog-api/handlers/feed"
+	mediaHandler "blog-api/handlers/media"
+	seriesHandler "blog-api/handlers/series"
+	sitemapHandler "blog-api/handlers/sitemap"
 	corsmiddleware "blog-api/middlewares/cors"
 	loggingmiddleware "blog-api/middlewares/logging"
+	"blog-api/objectstore"
+	analyticsRepo "blog-api/repositories/analytics"
 	blogRepo "blog-api/repositories/blog"
+	commentRepo "blog-api/repositories/comment"
+	likeRepo "blog-api/repositories/like"
+	mediaRepo "blog-api/repositories/media"
+	revisionRepo "blog-api/repositories/revision"
+	seriesRepo "blog-api/repositories/series"
+	analyticsService "blog-api/services/analytics"
 	blogService "blog-api/services/blog"
+	commentService "blog-api/services/comment"
+	feedService "blog-api/services/feed"
+	mediaService "blog-api/services/media"
+	seriesService "blog-api/services/series"
+	sitemapService "blog-api/services/sitemap"
+	storageService "blog-api/services/storage"
 
 	emailService "blog-api/services/email"
 
@@ -75,31 +95,155 @@ func main() {
 		log.Fatal("Port value unavailable")
 	}
 
+	// public site the blogs are read on, used to build post links
+	siteURL, hasSiteURL := os.LookupEnv("SITE_URL")
+	if !hasSiteURL {
+		log.Fatal("Site URL value unavailable")
+	}
+
+	// public URL the api is served from, used to build feed links
+	apiURL, hasAPIURL := os.LookupEnv("API_URL")
+	if !hasAPIURL || apiURL == "" {
+		apiURL = "http://localhost:" + port
+	}
+
+	siteName, hasSiteName := os.LookupEnv("SITE_NAME")
+	if !hasSiteName {
+		siteName = "Blog"
+	}
+
+	// comma seperated paths of the site robots.txt asks crawlers to skip
+	var robotsDisallow []string
+	for _, path := range strings.Split(os.Getenv("ROBOTS_DISALLOW"), ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			robotsDisallow = append(robotsDisallow, path)
+		}
+	}
+
+	// how often orphaned uploads are removed, never when unset
+	var gcInterval time.Duration
+	if interval, hasInterval := os.LookupEnv("STORAGE_GC_INTERVAL"); hasInterval && interval != "" {
+		gcInterval, err = time.ParseDuration(interval)
+		if err != nil || gcInterval <= 0 {
+			log.Fatalf("Invalid storage collector interval: %s", interval)
+		}
+	}
+
+	gcOptions := storageService.CollectOptions{
+		DryRun: os.Getenv("STORAGE_GC_DRY_RUN") == "true",
+	}
+
+	if grace, hasGrace := os.LookupEnv("STORAGE_GC_GRACE"); hasGrace && grace != "" {
+		gcOptions.Grace, err = time.ParseDuration(grace)
+		if err != nil {
+			log.Fatalf("Invalid storage collector grace period: %s", grace)
+		}
+	}
+
+	// where uploads are stored, chosen by STORAGE_BACKEND
+	store, err := objectstore.FromEnv(context.Background())
+	if err != nil {
+		log.Fatalf("Unable to configure storage: %v", err)
+	}
+
 	// initialize repos
 	blogRepo := blogRepo.NewBlogRepository(db.DB)
+	revisionRepo := revisionRepo.NewRevisionRepository(db.DB)
+	seriesRepo := seriesRepo.NewSeriesRepository(db.DB)
+	commentRepo := commentRepo.NewCommentRepository(db.DB)
+	likeRepo := likeRepo.NewLikeRepository(db.DB)
+	analyticsRepo := analyticsRepo.NewAnalyticsRepository(db.DB)
+	mediaRepo := mediaRepo.NewMediaRepository(db.DB)
 	userRepo := userRepo.NewUserRepository(db.DB)
 	passwordResetRepo := passwordResetRepo.NewPasswordResetRepository(db.DB)
 
 	// initialize services
 	emailService := emailService.NewEmailService()
 	passwordResetService := passwordResetService.NewPasswordResetService(passwordResetRepo)
-	blogService := blogService.NewBlogService(blogRepo)
+	mediaService := mediaService.NewMediaService(mediaRepo, blogRepo, revisionRepo, store)
+	blogService := blogService.NewBlogService(blogRepo, revisionRepo, seriesRepo, commentRepo, likeRepo, mediaService, store)
+	commentService := commentService.NewCommentService(commentRepo, blogRepo)
+	analyticsService := analyticsService.NewAnalyticsService(analyticsRepo, blogRepo, likeRepo, siteURL)
+	feedService := feedService.NewFeedService(blogRepo, siteURL, apiURL, siteName)
+	seriesService := seriesService.NewSeriesService(seriesRepo, blogRepo)
+	sitemapService := sitemapService.NewSitemapService(blogRepo, sitemapService.SitemapConfig{
+		SiteURL:  siteURL,
+		Disallow: robotsDisallow,
+	})
+	storageService := storageService.NewStorageService(blogRepo, revisionRepo, userRepo, mediaRepo, store)
 	userService := userService.NewUserService(
 		userRepo,
 		*passwordResetService,
 		*emailService,
+		mediaService,
+		store,
 	)
 
+	// create the blog indexes and index blogs saved before search existed
+	indexCtx, cancelIndexes := context.WithTimeout(context.Background(), time.Minute)
+	if err := blogService.PrepareIndexes(indexCtx); err != nil {
+		log.Fatalf("Unable to prepare blog indexes: %v", err)
+	}
+	if err := analyticsService.EnsureIndexes(indexCtx); err != nil {
+		log.Fatalf("Unable to prepare analytics indexes: %v", err)
+	}
+	if err := mediaService.EnsureIndexes(indexCtx); err != nil {
+		log.Fatalf("Unable to prepare media indexes: %v", err)
+	}
+	cancelIndexes()
+
+	// build the search suggestions and related blogs, later writes keep them current
+	cachesCtx, cancelCaches := context.WithTimeout(context.Background(), time.Minute)
+	if err := blogService.RefreshCaches(cachesCtx); err != nil {
+		log.Printf("Unable to build blog caches: %v", err)
+	}
+	cancelCaches()
+
+	// publish scheduled blogs in the background
+	publisherCtx, stopPublisher := context.WithCancel(context.Background())
+	defer stopPublisher()
+
+	go blogService.RunScheduledPublisher(publisherCtx, time.Minute)
+
+	// rank trending blogs in the background
+	trendingCtx, stopTrending := context.WithCancel(context.Background())
+	defer stopTrending()
+
+	go analyticsService.RunTrendingRanker(trendingCtx, 10*time.Minute)
+
+	// remove orphaned uploads in the background when an interval is set
+	if gcInterval > 0 {
+		collectorCtx, stopCollector := context.WithCancel(context.Background())
+		defer stopCollector()
+
+		go storageService.RunStorageCollector(collectorCtx, gcInterval, gcOptions)
+	}
+
 	// initialize handlers
-	blogHandler := blogHandler.NewBlogHandler(blogService)
+	blogHandler := blogHandler.NewBlogHandler(blogService, commentService, analyticsService)
+	analyticsHandler := analyticsHandler.NewAnalyticsHandler(analyticsService)
+	mediaHandler := mediaHandler.NewMediaHandler(mediaService)
+	seriesHandler := seriesHandler.NewSeriesHandler(seriesService)
+	feedHandler := feedHandler.NewFeedHandler(feedService)
+	sitemapHandler := sitemapHandler.NewSitemapHandler(sitemapService)
 	userHandler := userHandler.NewUserHandler(userService)
 
 	// initialize server
 	mux := http.NewServeMux()
 
 	blogHandler.RegisterBlogRoutes("/blog", mux)
+	seriesHandler.RegisterSeriesRoutes("/blog/series", mux)
+	analyticsHandler.RegisterAnalyticsRoutes("/blog/analytics", mux)
+	mediaHandler.RegisterMediaRoutes("/blog/media", mux)
+	feedHandler.RegisterFeedRoutes("/blog", mux)
+	sitemapHandler.RegisterSitemapRoutes("", mux)
 	userHandler.RegisterUserRoutes("/user", mux)
 
+	// the local and memory backends are served by the api itself
+	if files, ok := store.(http.Handler); ok {
+		mux.Handle("GET "+objectstore.FILES_PATH, http.StripPrefix(strings.TrimSuffix(objectstore.FILES_PATH, "/"), files))
+	}
+
 	loggedMux := loggingmiddleware.LogRequest(mux)
 	corsMux := corsmiddleware.ValidateCors(loggedMux)
 
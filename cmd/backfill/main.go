@@ -0,0 +1,102 @@
+// Command backfill recomputes the fields derived from each blog's html,
+// heading anchors, search text, excerpt, word count, reading time and
+// table of contents, for blogs saved before they were stored. It also
+// normalizes categories saved before they were normalized on write.
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"blog-api/db"
+	"blog-api/objectstore"
+	blogRepo "blog-api/repositories/blog"
+	commentRepo "blog-api/repositories/comment"
+	likeRepo "blog-api/repositories/like"
+	mediaRepo "blog-api/repositories/media"
+	revisionRepo "blog-api/repositories/revision"
+	seriesRepo "blog-api/repositories/series"
+	blogService "blog-api/services/blog"
+	mediaService "blog-api/services/media"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+
+	// load env
+	if os.Getenv("RUNNING_IN_DOCKER") == "true" {
+		err := godotenv.Load(".env")
+		if err != nil {
+			log.Fatalf("Unable to load env inside Docker: %v", err)
+		}
+	} else {
+		err := godotenv.Load("../../.env") // Load from the repo root for local dev
+		if err != nil {
+			log.Fatalf("Unable to load env in local environment: %v", err)
+		}
+	}
+
+	// connect to db
+	uri, hasURI := os.LookupEnv("MONGO_DB_URI")
+	if !hasURI {
+		log.Fatal("Unable to load database URI")
+	}
+
+	dbName, hasDbName := os.LookupEnv("MONGO_DB_NAME")
+	if !hasDbName {
+		log.Fatal("Unable to load database name")
+	}
+
+	db, err := db.ConnecToMongo(uri, dbName)
+	if err != nil {
+		log.Fatal("Unable to connect to database: " + err.Error())
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if err := db.Disconnect(ctx); err != nil {
+			log.Printf("Error disconnecting from database: %v", err)
+		}
+	}()
+
+	store, err := objectstore.FromEnv(context.Background())
+	if err != nil {
+		log.Fatalf("Unable to configure storage: %v", err)
+	}
+
+	blogRepo := blogRepo.NewBlogRepository(db.DB)
+	revisionRepo := revisionRepo.NewRevisionRepository(db.DB)
+
+	blogService := blogService.NewBlogService(
+		blogRepo,
+		revisionRepo,
+		seriesRepo.NewSeriesRepository(db.DB),
+		commentRepo.NewCommentRepository(db.DB),
+		likeRepo.NewLikeRepository(db.DB),
+		mediaService.NewMediaService(mediaRepo.NewMediaRepository(db.DB), blogRepo, revisionRepo, store),
+		store,
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	updated, err := blogService.BackfillDerivedFields(ctx)
+	if err != nil {
+		log.Printf("Backfill stopped after %d blogs: %v", updated, err)
+		return
+	}
+
+	log.Printf("Backfilled %d blogs", updated)
+
+	normalized, err := blogService.NormalizeStoredCategories(ctx)
+	if err != nil {
+		log.Printf("Normalizing categories stopped: %v", err)
+		return
+	}
+
+	log.Printf("Normalized the categories of %d blogs", normalized)
+}
@@ -0,0 +1,107 @@
+// Command gc removes uploaded objects no blog, profile or media library
+// entry references any more. Objects younger than the grace period are
+// kept since they may belong to a blog still being saved.
+//
+//	go run ./cmd/gc -dry-run
+//	go run ./cmd/gc -grace 72h
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"blog-api/db"
+	"blog-api/objectstore"
+	blogRepo "blog-api/repositories/blog"
+	mediaRepo "blog-api/repositories/media"
+	revisionRepo "blog-api/repositories/revision"
+	userRepo "blog-api/repositories/user"
+	storageService "blog-api/services/storage"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report unreferenced objects without deleting them")
+	grace := flag.Duration("grace", storageService.DEFAULT_GRACE_PERIOD, "keep unreferenced objects younger than this")
+	flag.Parse()
+
+	// load env
+	if os.Getenv("RUNNING_IN_DOCKER") == "true" {
+		err := godotenv.Load(".env")
+		if err != nil {
+			log.Fatalf("Unable to load env inside Docker: %v", err)
+		}
+	} else {
+		err := godotenv.Load("../../.env") // Load from the repo root for local dev
+		if err != nil {
+			log.Fatalf("Unable to load env in local environment: %v", err)
+		}
+	}
+
+	// connect to db
+	uri, hasURI := os.LookupEnv("MONGO_DB_URI")
+	if !hasURI {
+		log.Fatal("Unable to load database URI")
+	}
+
+	dbName, hasDbName := os.LookupEnv("MONGO_DB_NAME")
+	if !hasDbName {
+		log.Fatal("Unable to load database name")
+	}
+
+	db, err := db.ConnecToMongo(uri, dbName)
+	if err != nil {
+		log.Fatal("Unable to connect to database: " + err.Error())
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if err := db.Disconnect(ctx); err != nil {
+			log.Printf("Error disconnecting from database: %v", err)
+		}
+	}()
+
+	opts := storageService.CollectOptions{
+		DryRun: *dryRun,
+		Grace:  *grace,
+	}
+
+	store, err := objectstore.FromEnv(context.Background())
+	if err != nil {
+		log.Fatalf("Unable to configure storage: %v", err)
+	}
+
+	storageService := storageService.NewStorageService(
+		blogRepo.NewBlogRepository(db.DB),
+		revisionRepo.NewRevisionRepository(db.DB),
+		userRepo.NewUserRepository(db.DB),
+		mediaRepo.NewMediaRepository(db.DB),
+		store,
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	report, err := storageService.CollectGarbage(ctx, opts)
+
+	for _, orphan := range report.Orphans {
+		fmt.Printf("%s\t%d\t%s\n", orphan.Key, orphan.Size, orphan.LastModified.Format(time.RFC3339))
+	}
+
+	for _, key := range report.Failed {
+		log.Printf("Failed to delete %s", key)
+	}
+
+	if err != nil {
+		log.Printf("Collection stopped: %v", err)
+		return
+	}
+
+	log.Println(report)
+}
@@ -3,3 +3,6 @@ package contextkeys
 type contextKey string
 
 const UserIDKey contextKey = "userID"
+
+// VisitorIDKey identifies the reader of a public route, see the visitor middleware
+const VisitorIDKey contextKey = "visitorID"
@@ -0,0 +1,47 @@
+package analytics
+
+import (
+	s "blog-api/services/analytics"
+	u "blog-api/utilities"
+	"fmt"
+	"net/http"
+)
+
+type AnalyticsHandler struct {
+	analyticsService *s.AnalyticsService
+}
+
+func NewAnalyticsHandler(service *s.AnalyticsService) *AnalyticsHandler {
+	return &AnalyticsHandler{analyticsService: service}
+}
+
+/*
+/blog/analytics
+/blog/analytics/{id}
+
+	Accepts the following query params:
+	from: date or RFC 3339 time, widened to the start of its day
+	to: date or RFC 3339 time, a date includes that day
+	the last 30 days are used when neither is set, at most 366 days
+
+	 Returns the total and unique views of each day in the range for
+	 all of the author's blogs, or only the blog with the id, along
+	 with the most viewed posts and the top referrer domains.
+*/
+func (h *AnalyticsHandler) handleAnalytics(w http.ResponseWriter, req *http.Request) {
+	from, to, err := u.ParseDateRange(req.URL.Query())
+	if err != nil {
+		error := fmt.Errorf("invalid query params: %v", err)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	response, err := h.analyticsService.GetAnalytics(req.Context(), req.PathValue("id"), from, to)
+	if err != nil {
+		error := fmt.Errorf("failed to get analytics: %v", err)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	u.WriteJSON(w, http.StatusOK, response)
+}
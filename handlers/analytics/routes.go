@@ -0,0 +1,13 @@
+package analytics
+
+import (
+	authmiddleware "blog-api/middlewares/auth"
+	"net/http"
+)
+
+func (h *AnalyticsHandler) RegisterAnalyticsRoutes(prefix string, server *http.ServeMux) {
+	// views of all of the author's blogs
+	server.HandleFunc("GET "+prefix, authmiddleware.BearerAuthMiddleware(h.handleAnalytics))
+	// views of one of the author's blogs
+	server.HandleFunc("GET "+prefix+"/{id}", authmiddleware.BearerAuthMiddleware(h.handleAnalytics))
+}
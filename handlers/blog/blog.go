@@ -2,18 +2,30 @@ package blog
 
 import (
 	r "blog-api/repositories/blog"
+	as "blog-api/services/analytics"
 	s "blog-api/services/blog"
+	cs "blog-api/services/comment"
 	u "blog-api/utilities"
 	"fmt"
 	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
 )
 
 type BlogHandler struct {
-	blogService *s.BlogService
+	blogService      *s.BlogService
+	commentService   *cs.CommentService
+	analyticsService *as.AnalyticsService
 }
 
-func NewBlogHandler(service *s.BlogService) *BlogHandler {
-	return &BlogHandler{blogService: service}
+func NewBlogHandler(service *s.BlogService, commentService *cs.CommentService, analyticsService *as.AnalyticsService) *BlogHandler {
+	return &BlogHandler{
+		blogService:      service,
+		commentService:   commentService,
+		analyticsService: analyticsService,
+	}
 }
 
 /*
@@ -22,15 +34,27 @@ func NewBlogHandler(service *s.BlogService) *BlogHandler {
 	Blog index accepts the following query params:
 
 	offset: 0 / 10 / 20 / 30 /  etc
+	cursor: nextCursor or prevCursor of a previous page, used instead of offset
+	limit: page size, 10 by default and at most 50
+	sort: newest (default) / oldest / most-viewed / most-liked
+	from: date or RFC 3339 time, blogs created from then on
+	to: date or RFC 3339 time, blogs created before then, a date includes that day
+	category: comma,seperated,categories
+	match: all (default) / any, how the categories are matched
 
 	 Retruns array of blogs and hasMore boolean indicating more are available after
-	 the set offset.
+	 the set offset or cursor, along with nextCursor and prevCursor when there are
+	 pages after or before this one.
 */
 func (h *BlogHandler) handleBlogIndex(w http.ResponseWriter, req *http.Request) {
 	blogQuery := new(r.BlogQuery)
 	queryValues := req.URL.Query()
 
-	u.ParseBlogQueryParams(blogQuery, queryValues)
+	if err := u.ParseBlogQueryParams(blogQuery, queryValues); err != nil {
+		error := fmt.Errorf("invalid query params: %v", err)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
 
 	blogs, err := h.blogService.GetBlogIndex(req.Context(), blogQuery)
 	if err != nil {
@@ -42,6 +66,13 @@ func (h *BlogHandler) handleBlogIndex(w http.ResponseWriter, req *http.Request)
 	u.WriteJSON(w, http.StatusOK, blogs)
 }
 
+/*
+/blog/user/{userID}
+
+	Accepts the same query params as the blog index
+
+	 Returns the user's published blogs paged the same way as the index.
+*/
 func (h *BlogHandler) handleBlogsByUser(w http.ResponseWriter, req *http.Request) {
 	userID := req.PathValue("userID")
 	if userID == "" {
@@ -53,7 +84,11 @@ func (h *BlogHandler) handleBlogsByUser(w http.ResponseWriter, req *http.Request
 	blogQuery := new(r.BlogQuery)
 	queryValues := req.URL.Query()
 
-	u.ParseBlogQueryParams(blogQuery, queryValues)
+	if err := u.ParseBlogQueryParams(blogQuery, queryValues); err != nil {
+		error := fmt.Errorf("invalid query params: %v", err)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
 
 	blogs, err := h.blogService.GetBlogsByUser(req.Context(), blogQuery, userID)
 	if err != nil {
@@ -70,7 +105,12 @@ func (h *BlogHandler) handleBlogsByUser(w http.ResponseWriter, req *http.Request
 
 	Lookup blog by slug accepts slug value by route parameter
 
-	 Returns the blog in question and its two surrounding blogs if any otherwise those values are null
+	Accepts the following query params:
+	ref: the page the reader came from, counted as the view's referrer
+	instead of the Referer header which names the site making the request
+
+	 Returns the blog in question and its two surrounding blogs if any otherwise those values are null.
+	 A slug the blog has since changed from is answered with a 301 to the current slug.
 */
 func (h *BlogHandler) handleBlogBySlug(w http.ResponseWriter, req *http.Request) {
 	slug := req.PathValue("slug")
@@ -88,14 +128,50 @@ func (h *BlogHandler) handleBlogBySlug(w http.ResponseWriter, req *http.Request)
 	}
 
 	if blogs.Blog == nil {
-		error := fmt.Errorf("failed to lookup blog by slug: %s", slug)
-		u.WriteJSONErr(w, http.StatusNotFound, error)
+		h.redirectRetiredSlug(w, req, slug)
 		return
 	}
 
+	h.recordView(req, blogs.Blog)
+
 	u.WriteJSON(w, http.StatusOK, blogs)
 }
 
+/*
+/blog/{slug}/related
+
+	Accepts the following query params:
+	limit: number of blogs, 5 by default and at most 10
+
+	 Returns the published blogs most similar to the blog, scored by
+	 shared categories and the similarity of their title and text.
+*/
+func (h *BlogHandler) handleRelatedBlogs(w http.ResponseWriter, req *http.Request) {
+	// blog resources share the id segment, it holds the slug here
+	slug := req.PathValue("id")
+
+	limit := 0
+	if value := req.URL.Query().Get("limit"); value != "" {
+		parsedLimit, err := strconv.Atoi(value)
+		if err != nil || parsedLimit < 1 || parsedLimit > s.MAX_RELATED {
+			error := fmt.Errorf("limit must be a number from 1 to %d", s.MAX_RELATED)
+			u.WriteJSONErr(w, http.StatusBadRequest, error)
+			return
+		}
+
+		limit = parsedLimit
+	}
+
+	response, err := h.blogService.GetRelatedBlogs(req.Context(), slug, limit)
+	if err != nil {
+		error := fmt.Errorf("failed to get related blogs: %v", err)
+		u.WriteJSONErr(w, http.StatusNotFound, error)
+		return
+	}
+
+	u.WriteJSON(w, http.StatusOK, response)
+}
+
 /*
 /blog/random
 
@@ -111,14 +187,94 @@ func (h *BlogHandler) handleRandomBlog(w http.ResponseWriter, req *http.Request)
 		return
 	}
 
+	h.recordView(req, response.Blog)
+
+	u.WriteJSON(w, http.StatusOK, response)
+}
+
+/*
+/blog/trending
+
+	Accepts the following query params:
+	window: day / week (default) / month
+	limit: number of blogs, 10 by default and at most 50
+
+	 Returns the published blogs with the most views and likes within
+	 the window, recent activity weighing more. The ranking is refreshed
+	 in the background every few minutes.
+*/
+func (h *BlogHandler) handleTrending(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	window := query.Get("window")
+	if window == "" {
+		window = as.TRENDING_WEEK
+	}
+
+	if !as.ValidTrendingWindow(window) {
+		error := fmt.Errorf("window must be one of %s, %s or %s", as.TRENDING_DAY, as.TRENDING_WEEK, as.TRENDING_MONTH)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	limit := 0
+	if value := query.Get("limit"); value != "" {
+		parsedLimit, err := strconv.Atoi(value)
+		if err != nil || parsedLimit < 1 || parsedLimit > as.MAX_TRENDING {
+			error := fmt.Errorf("limit must be a number from 1 to %d", as.MAX_TRENDING)
+			u.WriteJSONErr(w, http.StatusBadRequest, error)
+			return
+		}
+
+		limit = parsedLimit
+	}
+
+	response := h.analyticsService.GetTrending(window, limit)
+
 	u.WriteJSON(w, http.StatusOK, response)
 }
 
+// redirectRetiredSlug answers a lookup of a slug a blog has
+// retired with a permanent redirect, otherwise a not found
+func (h *BlogHandler) redirectRetiredSlug(w http.ResponseWriter, req *http.Request, slug string) {
+	current, err := h.blogService.GetSlugRedirect(req.Context(), slug)
+	if err != nil || current == "" {
+		error := fmt.Errorf("failed to lookup blog by slug: %s", slug)
+		u.WriteJSONErr(w, http.StatusNotFound, error)
+		return
+	}
+
+	location := path.Dir(req.URL.Path) + "/" + url.PathEscape(current)
+	if req.URL.RawQuery != "" {
+		location += "?" + req.URL.RawQuery
+	}
+
+	w.Header().Set("Location", location)
+
+	u.WriteJSON(w, http.StatusMovedPermanently, r.SlugRedirectResponse{Slug: current})
+}
+
+// recordView counts the view in the blog's daily analytics
+func (h *BlogHandler) recordView(req *http.Request, blog *r.BlogWithAuthor) {
+	referrer := req.URL.Query().Get("ref")
+	if referrer == "" {
+		referrer = req.Referer()
+	}
+
+	h.analyticsService.RecordView(req.Context(), blog, req.UserAgent(), referrer)
+}
+
 /*
 /blog/{category}/comma,seperated,categories
 
 	Accepts the following query params:
 	offset: 0 / 10 / 20 / 30...
+	cursor: nextCursor or prevCursor of a previous page, used instead of offset
+	limit: page size, 10 by default and at most 50
+	sort: newest (default) / oldest / most-viewed / most-liked
+	from: date or RFC 3339 time, blogs created from then on
+	to: date or RFC 3339 time, blogs created before then, a date includes that day
+	match: all (default) / any, how the path categories are matched
 
 	Queries blogs that contain each of the provided categories
 
@@ -135,7 +291,11 @@ func (h *BlogHandler) handleBlogsByCategory(w http.ResponseWriter, req *http.Req
 
 	blogQuery := new(r.BlogQuery)
 
-	u.ParseBlogQueryParams(blogQuery, req.URL.Query())
+	if err := u.ParseBlogQueryParams(blogQuery, req.URL.Query()); err != nil {
+		error := fmt.Errorf("invalid query params: %v", err)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
 
 	response, err := h.blogService.GetBlogsByCategory(req.Context(), category, blogQuery)
 	if err != nil {
@@ -152,8 +312,14 @@ func (h *BlogHandler) handleBlogsByCategory(w http.ResponseWriter, req *http.Req
 
 	Accepts the following query params:
 	offset: 0 / 10 / 20 / 30...
+	cursor: nextCursor or prevCursor of a previous page, used instead of offset
+	limit: page size, 10 by default and at most 50
+	sort: newest (default) / oldest / most-viewed / most-liked
+	from: date or RFC 3339 time, blogs created from then on
+	to: date or RFC 3339 time, blogs created before then, a date includes that day
 
-	Queries drafts for the provided user with offset
+	Queries drafts for the provided user with offset. Blogs
+	scheduled for publishing are included with their publishAt time.
 
 	Protected endpoint requiring authorized token
 
@@ -163,7 +329,11 @@ func (h *BlogHandler) handleBlogsByCategory(w http.ResponseWriter, req *http.Req
 func (h *BlogHandler) handleDrafts(w http.ResponseWriter, req *http.Request) {
 	blogQuery := new(r.BlogQuery)
 
-	u.ParseBlogQueryParams(blogQuery, req.URL.Query())
+	if err := u.ParseBlogQueryParams(blogQuery, req.URL.Query()); err != nil {
+		error := fmt.Errorf("invalid query params: %v", err)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
 
 	response, err := h.blogService.GetDraftsByUser(req.Context(), blogQuery)
 	if err != nil {
@@ -198,19 +368,32 @@ func (h *BlogHandler) handleDraft(w http.ResponseWriter, req *http.Request) {
 
 	Accepts the following query params:
 	offset: 0 / 10 / 20 / 30...
-
-	Queries published blogs by search query where:
-	- text can contain search query
-	- title can contain search query
-	- categories can contain search query
-
-	 Retruns array of blogs and hasMore boolean indicating more are available after
-	 the set offset.
+	cursor: nextCursor or prevCursor of a previous page, used instead of offset
+	limit: page size, 10 by default and at most 50
+	sort: relevance (default) / newest / oldest / most-viewed / most-liked
+	from: date or RFC 3339 time, blogs created from then on
+	to: date or RFC 3339 time, blogs created before then, a date includes that day
+	category: comma,seperated,categories
+	match: all (default) / any, how the categories are matched
+
+	Full text search of published blogs where:
+	- multiple words match blogs containing any of them
+	- "quoted phrases" must match exactly
+	- -word excludes blogs containing the word
+	- title matches rank above category and text matches
+
+	 Retruns array of blogs, most relevant first, each with a snippet
+	 of the text where <mark> wraps the matched words, and hasMore
+	 boolean indicating more are available after the set offset.
 */
 func (h *BlogHandler) handleBlogSearch(w http.ResponseWriter, req *http.Request) {
 	blogQuery := new(r.BlogQuery)
 
-	u.ParseBlogQueryParams(blogQuery, req.URL.Query())
+	if err := u.ParseBlogQueryParams(blogQuery, req.URL.Query()); err != nil {
+		error := fmt.Errorf("invalid query params: %v", err)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
 
 	searchQuery := req.PathValue("query")
 	if searchQuery == "" {
@@ -229,14 +412,43 @@ func (h *BlogHandler) handleBlogSearch(w http.ResponseWriter, req *http.Request)
 	u.WriteJSON(w, http.StatusOK, response)
 }
 
+/*
+/blog/suggestions
+
+	Accepts the following query params:
+	q: prefix to complete
+	limit: suggestions per group, 5 by default and at most 10
+
+	 Returns post titles, categories and authors with a word starting
+	 with the prefix, most viewed and liked first. Served from memory
+	 so it can be called on every keystroke.
+*/
+func (h *BlogHandler) handleSuggestions(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	prefix := strings.TrimSpace(query.Get("q"))
+	if prefix == "" {
+		error := fmt.Errorf("suggestion prefix is empty")
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	limit, _ := strconv.Atoi(query.Get("limit"))
+
+	response := h.blogService.GetSuggestions(prefix, limit)
+
+	u.WriteJSON(w, http.StatusOK, response)
+}
+
 /*
 POST
 /blog/{id}/like
 
-Validates the provided object id path value and increments
-the blog's rating property by one
+Validates the provided object id path value and likes the blog
+for the visitor, raising its rating by one the first time.
 
-	Returns the updated document.
+	Returns the blog along with liked and alreadyLiked, which is true
+	when the visitor had liked the blog before and the rating is unchanged.
 */
 func (h *BlogHandler) handleBlogLike(w http.ResponseWriter, req *http.Request) {
 	blogID := req.PathValue("id")
@@ -253,6 +465,70 @@ func (h *BlogHandler) handleBlogLike(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if response.Blog == nil {
+		error := fmt.Errorf("failed to lookup blog: %s", blogID)
+		u.WriteJSONErr(w, http.StatusNotFound, error)
+		return
+	}
+
+	u.WriteJSON(w, http.StatusOK, response)
+}
+
+/*
+DELETE
+/blog/{id}/like
+
+Validates the provided object id path value and removes the
+visitor's like of the blog, lowering its rating by one.
+
+	Returns the blog along with liked and alreadyLiked, which is false
+	when the visitor had not liked the blog and the rating is unchanged.
+*/
+func (h *BlogHandler) handleBlogUnlike(w http.ResponseWriter, req *http.Request) {
+	blogID := req.PathValue("id")
+	if blogID == "" {
+		error := fmt.Errorf("not a valid post ID")
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	response, err := h.blogService.UnlikeBlog(req.Context(), blogID)
+	if err != nil {
+		error := fmt.Errorf("failed to update blog rating: %v", err)
+		u.WriteJSONErr(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	if response.Blog == nil {
+		error := fmt.Errorf("failed to lookup blog: %s", blogID)
+		u.WriteJSONErr(w, http.StatusNotFound, error)
+		return
+	}
+
+	u.WriteJSON(w, http.StatusOK, response)
+}
+
+/*
+/blog/{id}/like
+
+	Returns the blog and whether the visitor has liked it.
+*/
+func (h *BlogHandler) handleLikeStatus(w http.ResponseWriter, req *http.Request) {
+	blogID := req.PathValue("id")
+
+	response, err := h.blogService.GetLikeStatus(req.Context(), blogID)
+	if err != nil {
+		error := fmt.Errorf("failed to get like status: %v", err)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	if response.Blog == nil {
+		error := fmt.Errorf("failed to lookup blog: %s", blogID)
+		u.WriteJSONErr(w, http.StatusNotFound, error)
+		return
+	}
+
 	u.WriteJSON(w, http.StatusOK, response)
 }
 
@@ -276,7 +552,7 @@ func (h *BlogHandler) handleUpdatetBlog(w http.ResponseWriter, req *http.Request
 	input, err := u.ParseMultiPartFormBlogUpdate(reader)
 	if err != nil {
 		error := fmt.Errorf("error parsing mutlipart form: %v", err)
-		u.WriteJSONErr(w, http.StatusInternalServerError, error)
+		u.WriteJSONErr(w, u.UploadErrorStatus(err, http.StatusInternalServerError), error)
 		return
 	}
 
@@ -297,6 +573,41 @@ func (h *BlogHandler) handleUpdatetBlog(w http.ResponseWriter, req *http.Request
 	u.WriteJSON(w, http.StatusOK, response)
 }
 
+/*
+/blog/edit/{id}
+
+	Protected endpoint requiring authorized token
+
+	 Returns the author's blog and the source to load into the
+	 editor, markdown for markdown posts otherwise html.
+*/
+func (h *BlogHandler) handleBlogEdit(w http.ResponseWriter, req *http.Request) {
+	blogID := req.PathValue("id")
+	if blogID == "" {
+		error := fmt.Errorf("not a valid post ID")
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	response, err := h.blogService.GetBlogForEdit(req.Context(), blogID)
+	if err != nil {
+		error := fmt.Errorf("failed to get blog for editing: %v", err)
+		u.WriteJSONErr(w, http.StatusNotFound, error)
+		return
+	}
+
+	u.WriteJSON(w, http.StatusOK, response)
+}
+
+/*
+/blog/validate-slug/{slug}
+
+	Protected endpoint requiring authorized token, accepts a slug or a
+	title to preview the slug generated from it
+
+	 Returns the slug the value would be saved as, whether it is available
+	 and not reserved by a route, and a free numbered suggestion otherwise.
+*/
 func (h *BlogHandler) handleSlugValidation(w http.ResponseWriter, req *http.Request) {
 	slug := req.PathValue("slug")
 	if slug == "" {
@@ -308,7 +619,7 @@ func (h *BlogHandler) handleSlugValidation(w http.ResponseWriter, req *http.Requ
 	response, err := h.blogService.ValidateSlug(req.Context(), slug)
 	if err != nil {
 		error := fmt.Errorf("error validating slug: %v", err)
-		u.WriteJSONErr(w, http.StatusInternalServerError, error)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
 		return
 	}
 
@@ -337,7 +648,7 @@ func (h *BlogHandler) handleNewBlog(w http.ResponseWriter, req *http.Request) {
 	input, err := u.ParseMultiPartFormBlogCreate(reader)
 	if err != nil {
 		error := fmt.Errorf("error parsing mutlipart form: %v", err)
-		u.WriteJSONErr(w, http.StatusInternalServerError, error)
+		u.WriteJSONErr(w, u.UploadErrorStatus(err, http.StatusInternalServerError), error)
 		return
 	}
 
@@ -0,0 +1,53 @@
+package blog
+
+import (
+	r "blog-api/repositories/blog"
+	s "blog-api/services/blog"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// slugRepository stubs a blog that moved from old-post to new-post
+type slugRepository struct {
+	r.BlogRepository
+}
+
+func (s *slugRepository) GetBlogBySlug(ctx context.Context, slug string) (*r.BlogWithAuthor, error) {
+	return nil, nil
+}
+
+func (s *slugRepository) GetSlugRedirect(ctx context.Context, slug string) (*r.BlogSlug, error) {
+	if slug != "old-post" {
+		return nil, nil
+	}
+
+	return &r.BlogSlug{Slug: "new-post"}, nil
+}
+
+func TestRetiredSlugRedirect(t *testing.T) {
+	service := s.NewBlogService(&slugRepository{}, nil, nil, nil, nil, nil, nil)
+	handler := NewBlogHandler(service, nil, nil)
+
+	mux := http.NewServeMux()
+	handler.RegisterBlogRoutes("/blog", mux)
+
+	res := httptest.NewRecorder()
+	mux.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/blog/old-post?ref=feed", nil))
+
+	if res.Code != http.StatusMovedPermanently {
+		t.Fatalf("wanted 301 for a retired slug, got %d: %s", res.Code, res.Body)
+	}
+
+	if got := res.Header().Get("Location"); got != "/blog/new-post?ref=feed" {
+		t.Errorf("wanted the new slug in Location, got %s", got)
+	}
+
+	res = httptest.NewRecorder()
+	mux.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/blog/missing-post", nil))
+
+	if res.Code != http.StatusNotFound {
+		t.Errorf("wanted 404 for an unknown slug, got %d", res.Code)
+	}
+}
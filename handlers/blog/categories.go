@@ -0,0 +1,94 @@
+package blog
+
+import (
+	r "blog-api/repositories/blog"
+	u "blog-api/utilities"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+/*
+/blog/categories
+
+	Returns every category used by published blogs with its post
+	count and the date of its most recent post, most used first.
+*/
+func (h *BlogHandler) handleCategories(w http.ResponseWriter, req *http.Request) {
+	response, err := h.blogService.GetCategories(req.Context())
+	if err != nil {
+		error := fmt.Errorf("failed to get categories: %v", err)
+		u.WriteJSONErr(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	u.WriteJSON(w, http.StatusOK, response)
+}
+
+/*
+PUT
+/blog/categories/{category}/rename
+
+	Accepts a JSON payload with the new category name
+
+	Protected endpoint requiring authorized token
+
+	 Renames the category across the author's blogs and returns
+	 the number of blogs affected.
+*/
+func (h *BlogHandler) handleCategoryRename(w http.ResponseWriter, req *http.Request) {
+	category := req.PathValue("category")
+	if category == "" {
+		error := fmt.Errorf("not a valid category: %s", category)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	input := new(r.CategoryRenamePost)
+
+	if err := json.NewDecoder(req.Body).Decode(input); err != nil {
+		error := fmt.Errorf("failed to decode rename payload: %s", err)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	response, err := h.blogService.RenameCategory(req.Context(), category, input.Name)
+	if err != nil {
+		error := fmt.Errorf("failed to rename category: %v", err)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	u.WriteJSON(w, http.StatusOK, response)
+}
+
+/*
+POST
+/blog/categories/merge
+
+	Accepts a JSON payload of the categories to merge from and the
+	category to merge into
+
+	Protected endpoint requiring authorized token
+
+	 Merges the categories across the author's blogs and returns
+	 the number of blogs affected.
+*/
+func (h *BlogHandler) handleCategoryMerge(w http.ResponseWriter, req *http.Request) {
+	input := new(r.CategoryMergePost)
+
+	if err := json.NewDecoder(req.Body).Decode(input); err != nil {
+		error := fmt.Errorf("failed to decode merge payload: %s", err)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	response, err := h.blogService.MergeCategories(req.Context(), input)
+	if err != nil {
+		error := fmt.Errorf("failed to merge categories: %v", err)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	u.WriteJSON(w, http.StatusOK, response)
+}
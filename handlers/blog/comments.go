@@ -0,0 +1,150 @@
+package blog
+
+import (
+	r "blog-api/repositories/blog"
+	cr "blog-api/repositories/comment"
+	u "blog-api/utilities"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+/*
+POST
+/blog/{id}/comments
+
+	Accepts a JSON payload of name, body and an optional parent
+	comment ID when replying.
+
+	 Returns the created comment which stays pending until the
+	 blog's author approves it.
+*/
+func (h *BlogHandler) handleNewComment(w http.ResponseWriter, req *http.Request) {
+	blogID := req.PathValue("id")
+	if blogID == "" {
+		error := fmt.Errorf("not a valid post ID")
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	req.Body = http.MaxBytesReader(w, req.Body, 64*u.KB)
+
+	input := new(cr.CommentPost)
+
+	if err := json.NewDecoder(req.Body).Decode(input); err != nil {
+		error := fmt.Errorf("failed to decode comment payload: %s", err)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	response, err := h.commentService.CreateComment(req.Context(), blogID, input)
+	if err != nil {
+		error := fmt.Errorf("failed to create comment: %v", err)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	u.WriteJSON(w, http.StatusCreated, response)
+}
+
+/*
+/blog/{id}/comments
+
+	Accepts the following query params:
+	offset: 0 / 10 / 20 / 30...
+
+	 Returns approved top level comments with their approved replies
+	 and hasMore boolean indicating more are available after the
+	 set offset.
+*/
+func (h *BlogHandler) handleComments(w http.ResponseWriter, req *http.Request) {
+	blogID := req.PathValue("id")
+
+	blogQuery := new(r.BlogQuery)
+
+	if err := u.ParseBlogQueryParams(blogQuery, req.URL.Query()); err != nil {
+		error := fmt.Errorf("invalid query params: %v", err)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	response, err := h.commentService.GetComments(req.Context(), blogID, blogQuery.Offset)
+	if err != nil {
+		error := fmt.Errorf("failed to get comments: %v", err)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	u.WriteJSON(w, http.StatusOK, response)
+}
+
+/*
+/blog/comments/moderation
+
+	Accepts the following query params:
+	status: pending (default) / approved / rejected
+	offset: 0 / 10 / 20 / 30...
+
+	Protected endpoint requiring authorized token
+
+	 Returns comments left on the author's blogs with the status.
+*/
+func (h *BlogHandler) handleCommentModeration(w http.ResponseWriter, req *http.Request) {
+	status := req.URL.Query().Get("status")
+	if status == "" {
+		status = cr.STATUS_PENDING
+	}
+
+	blogQuery := new(r.BlogQuery)
+
+	if err := u.ParseBlogQueryParams(blogQuery, req.URL.Query()); err != nil {
+		error := fmt.Errorf("invalid query params: %v", err)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	response, err := h.commentService.GetModerationQueue(req.Context(), status, blogQuery.Offset)
+	if err != nil {
+		error := fmt.Errorf("failed to get moderation queue: %v", err)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	u.WriteJSON(w, http.StatusOK, response)
+}
+
+/*
+PUT
+/blog/comments/{commentID}/status
+
+	Accepts a JSON payload with a status of approved or rejected
+
+	Protected endpoint requiring authorized token
+
+	 Returns the moderated comment.
+*/
+func (h *BlogHandler) handleCommentStatus(w http.ResponseWriter, req *http.Request) {
+	commentID := req.PathValue("commentID")
+	if commentID == "" {
+		error := fmt.Errorf("not a valid comment ID")
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	input := new(cr.CommentModerationPost)
+
+	if err := json.NewDecoder(req.Body).Decode(input); err != nil {
+		error := fmt.Errorf("failed to decode moderation payload: %s", err)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	response, err := h.commentService.ModerateComment(req.Context(), commentID, input.Status)
+	if err != nil {
+		error := fmt.Errorf("failed to moderate comment: %v", err)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	u.WriteJSON(w, http.StatusOK, response)
+}
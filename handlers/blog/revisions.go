@@ -0,0 +1,124 @@
+package blog
+
+import (
+	u "blog-api/utilities"
+	"fmt"
+	"net/http"
+)
+
+/*
+/blog/revisions/{id}
+
+	Protected endpoint requiring authorized token
+
+	 Returns the revisions of the author's blog, newest first,
+	 without their text.
+*/
+func (h *BlogHandler) handleRevisions(w http.ResponseWriter, req *http.Request) {
+	blogID := req.PathValue("id")
+	if blogID == "" {
+		error := fmt.Errorf("not a valid post ID")
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	response, err := h.blogService.GetRevisions(req.Context(), blogID)
+	if err != nil {
+		error := fmt.Errorf("failed to get revisions: %v", err)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	u.WriteJSON(w, http.StatusOK, response)
+}
+
+/*
+/blog/revisions/{id}/{revisionID}
+
+	Protected endpoint requiring authorized token
+
+	 Returns a single revision including its text.
+*/
+func (h *BlogHandler) handleRevision(w http.ResponseWriter, req *http.Request) {
+	blogID := req.PathValue("id")
+	revisionID := req.PathValue("revisionID")
+	if blogID == "" || revisionID == "" {
+		error := fmt.Errorf("post ID and revision ID are required")
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	response, err := h.blogService.GetRevision(req.Context(), blogID, revisionID)
+	if err != nil {
+		error := fmt.Errorf("failed to get revision: %v", err)
+		u.WriteJSONErr(w, http.StatusNotFound, error)
+		return
+	}
+
+	u.WriteJSON(w, http.StatusOK, response)
+}
+
+/*
+/blog/revisions/{id}/diff
+
+	Accepts the following query params:
+	from: revision ID
+	to: revision ID or "current" (default)
+
+	Protected endpoint requiring authorized token
+
+	 Returns the title change, added and removed categories and
+	 a block level diff of the text between the two revisions.
+*/
+func (h *BlogHandler) handleRevisionDiff(w http.ResponseWriter, req *http.Request) {
+	blogID := req.PathValue("id")
+	from := req.URL.Query().Get("from")
+	to := req.URL.Query().Get("to")
+
+	if to == "" {
+		to = "current"
+	}
+
+	if blogID == "" || from == "" {
+		error := fmt.Errorf("post ID and from revision are required")
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	response, err := h.blogService.DiffRevisions(req.Context(), blogID, from, to)
+	if err != nil {
+		error := fmt.Errorf("failed to diff revisions: %v", err)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	u.WriteJSON(w, http.StatusOK, response)
+}
+
+/*
+POST
+/blog/revisions/{id}/{revisionID}/restore
+
+	Protected endpoint requiring authorized token
+
+	 Restores the revision's title, text and categories as the
+	 current version and returns the updated document.
+*/
+func (h *BlogHandler) handleRevisionRestore(w http.ResponseWriter, req *http.Request) {
+	blogID := req.PathValue("id")
+	revisionID := req.PathValue("revisionID")
+	if blogID == "" || revisionID == "" {
+		error := fmt.Errorf("post ID and revision ID are required")
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	response, err := h.blogService.RestoreRevision(req.Context(), blogID, revisionID)
+	if err != nil {
+		error := fmt.Errorf("failed to restore revision: %v", err)
+		u.WriteJSONErr(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	u.WriteJSON(w, http.StatusOK, response)
+}
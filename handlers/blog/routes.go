@@ -2,6 +2,7 @@ package blog
 
 import (
 	authmiddleware "blog-api/middlewares/auth"
+	visitormiddleware "blog-api/middlewares/visitor"
 	"net/http"
 )
 
@@ -15,30 +16,87 @@ func (h *BlogHandler) RegisterBlogRoutes(prefix string, server *http.ServeMux) {
 	// new blog
 	server.HandleFunc("POST "+prefix, authmiddleware.BearerAuthMiddleware(h.handleNewBlog))
 	// get random blog
-	server.HandleFunc("GET "+prefix+"/random", h.handleRandomBlog)
+	server.HandleFunc("GET "+prefix+"/random", visitormiddleware.VisitorMiddleware(h.handleRandomBlog))
 	// checks if the provided slug value is available
 	server.HandleFunc("GET "+prefix+"/validate-slug/{slug}", authmiddleware.BearerAuthMiddleware(h.handleSlugValidation))
+	// published blogs ranked by recent views and likes
+	server.HandleFunc("GET "+prefix+"/trending", h.handleTrending)
 	// search blogs
 	server.HandleFunc("GET "+prefix+"/search/{query}", h.handleBlogSearch)
+	// search as you type suggestions
+	server.HandleFunc("GET "+prefix+"/suggestions", h.handleSuggestions)
 	// lookup blog by slug
-	server.HandleFunc("GET "+prefix+"/{slug}", h.handleBlogBySlug)
+	server.HandleFunc("GET "+prefix+"/{slug}", visitormiddleware.VisitorMiddleware(h.handleBlogBySlug))
 	// get published blogs by user
 	server.HandleFunc("GET "+prefix+"/user/{userID}", h.handleBlogsByUser)
 	// delete blog by id
 	server.HandleFunc("DELETE "+prefix+"/{id}", authmiddleware.BearerAuthMiddleware(h.handleDeleteBlog))
-	// update blog rating
-	server.HandleFunc("POST "+prefix+"/{id}/like", h.handleBlogLike)
+	// like a blog, once per visitor
+	server.HandleFunc("POST "+prefix+"/{id}/like", visitormiddleware.VisitorMiddleware(h.handleBlogLike))
+	// get blog source for editing
+	server.HandleFunc("GET "+prefix+"/edit/{id}", authmiddleware.BearerAuthMiddleware(h.handleBlogEdit))
 	// update blog
 	server.HandleFunc("PUT "+prefix+"/{id}/edit", authmiddleware.BearerAuthMiddleware(h.handleUpdatetBlog))
 	// delete blog featured image
 	server.HandleFunc("DELETE "+prefix+"/featured-image/{id}", authmiddleware.BearerAuthMiddleware(h.handleImageDelete))
 
+	//
+	// BLOG RESOURCES
+	//
+
+	// /blog/{id}/comments can't be registered beside literal routes
+	// like /blog/drafts/{slug}, neither is more specific, so the
+	// resources of a single blog are routed by a mux of their own
+	resources := http.NewServeMux()
+
+	// get approved blog comments
+	resources.HandleFunc("GET "+prefix+"/{id}/comments", h.handleComments)
+	// get related blogs
+	resources.HandleFunc("GET "+prefix+"/{id}/related", h.handleRelatedBlogs)
+	// whether the visitor likes a blog
+	resources.HandleFunc("GET "+prefix+"/{id}/like", visitormiddleware.VisitorMiddleware(h.handleLikeStatus))
+	// remove the visitor's like of a blog
+	resources.HandleFunc("DELETE "+prefix+"/{id}/like", visitormiddleware.VisitorMiddleware(h.handleBlogUnlike))
+
+	server.Handle("GET "+prefix+"/{id}/{resource}", resources)
+	server.Handle("DELETE "+prefix+"/{id}/{resource}", resources)
+
+	//
+	// BLOG REVISIONS
+	//
+
+	// list revisions of a blog
+	server.HandleFunc("GET "+prefix+"/revisions/{id}", authmiddleware.BearerAuthMiddleware(h.handleRevisions))
+	// diff two revisions of a blog
+	server.HandleFunc("GET "+prefix+"/revisions/{id}/diff", authmiddleware.BearerAuthMiddleware(h.handleRevisionDiff))
+	// get a single revision
+	server.HandleFunc("GET "+prefix+"/revisions/{id}/{revisionID}", authmiddleware.BearerAuthMiddleware(h.handleRevision))
+	// restore a revision as the current version
+	server.HandleFunc("POST "+prefix+"/revisions/{id}/{revisionID}/restore", authmiddleware.BearerAuthMiddleware(h.handleRevisionRestore))
+
+	//
+	// BLOG COMMENTS
+	//
+
+	// add a comment to a blog
+	server.HandleFunc("POST "+prefix+"/{id}/comments", h.handleNewComment)
+	// get comments awaiting moderation
+	server.HandleFunc("GET "+prefix+"/comments/moderation", authmiddleware.BearerAuthMiddleware(h.handleCommentModeration))
+	// approve or reject a comment
+	server.HandleFunc("PUT "+prefix+"/comments/{commentID}/status", authmiddleware.BearerAuthMiddleware(h.handleCommentStatus))
+
 	//
 	// BLOG CATEGORIES
 	//
 
 	// lookup blogs by category
 	server.HandleFunc("GET "+prefix+"/category/{category}", h.handleBlogsByCategory)
+	// list categories with post counts
+	server.HandleFunc("GET "+prefix+"/categories", h.handleCategories)
+	// rename a category across the author's blogs
+	server.HandleFunc("PUT "+prefix+"/categories/{category}/rename", authmiddleware.BearerAuthMiddleware(h.handleCategoryRename))
+	// merge categories across the author's blogs
+	server.HandleFunc("POST "+prefix+"/categories/merge", authmiddleware.BearerAuthMiddleware(h.handleCategoryMerge))
 
 	//
 	// BLOG DRAFTS
@@ -0,0 +1,80 @@
+package feed
+
+import (
+	s "blog-api/services/feed"
+	u "blog-api/utilities"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+type FeedHandler struct {
+	feedService *s.FeedService
+}
+
+func NewFeedHandler(service *s.FeedService) *FeedHandler {
+	return &FeedHandler{feedService: service}
+}
+
+/*
+/blog/feed.rss
+/blog/feed.atom
+/blog/feed.json
+
+	Accepts the following query params:
+	category: comma,seperated,categories
+	userID: only include blogs by the user
+
+	 Returns the latest published blogs as an RSS 2.0, Atom 1.0
+	 or JSON Feed 1.1 document.
+*/
+func (h *FeedHandler) handleRSS(w http.ResponseWriter, req *http.Request) {
+	category, userID := feedFilters(req)
+
+	body, err := h.feedService.GetRSS(req.Context(), category, userID, req.URL.Path)
+	if err != nil {
+		error := fmt.Errorf("failed to build rss feed: %v", err)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	u.WriteBody(w, http.StatusOK, "application/rss+xml; charset=utf-8", body)
+}
+
+func (h *FeedHandler) handleAtom(w http.ResponseWriter, req *http.Request) {
+	category, userID := feedFilters(req)
+
+	body, err := h.feedService.GetAtom(req.Context(), category, userID, req.URL.Path)
+	if err != nil {
+		error := fmt.Errorf("failed to build atom feed: %v", err)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	u.WriteBody(w, http.StatusOK, "application/atom+xml; charset=utf-8", body)
+}
+
+func (h *FeedHandler) handleJSONFeed(w http.ResponseWriter, req *http.Request) {
+	category, userID := feedFilters(req)
+
+	feed, err := h.feedService.GetJSONFeed(req.Context(), category, userID, req.URL.Path)
+	if err != nil {
+		error := fmt.Errorf("failed to build json feed: %v", err)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	body, err := json.Marshal(feed)
+	if err != nil {
+		error := fmt.Errorf("failed to encode json feed: %v", err)
+		u.WriteJSONErr(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	u.WriteBody(w, http.StatusOK, "application/feed+json; charset=utf-8", body)
+}
+
+func feedFilters(req *http.Request) (string, string) {
+	query := req.URL.Query()
+	return query.Get("category"), query.Get("userID")
+}
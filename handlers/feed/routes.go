@@ -0,0 +1,14 @@
+package feed
+
+import (
+	"net/http"
+)
+
+func (h *FeedHandler) RegisterFeedRoutes(prefix string, server *http.ServeMux) {
+	// rss 2.0 feed
+	server.HandleFunc("GET "+prefix+"/feed.rss", h.handleRSS)
+	// atom 1.0 feed
+	server.HandleFunc("GET "+prefix+"/feed.atom", h.handleAtom)
+	// json feed 1.1
+	server.HandleFunc("GET "+prefix+"/feed.json", h.handleJSONFeed)
+}
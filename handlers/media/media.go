@@ -0,0 +1,168 @@
+package media
+
+import (
+	br "blog-api/repositories/blog"
+	r "blog-api/repositories/media"
+	s "blog-api/services/media"
+	u "blog-api/utilities"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+type MediaHandler struct {
+	mediaService *s.MediaService
+}
+
+func NewMediaHandler(service *s.MediaService) *MediaHandler {
+	return &MediaHandler{mediaService: service}
+}
+
+/*
+/blog/media
+
+	Protected endpoint requiring authorized token
+
+	Accepts the following query params:
+	offset: 0 / 24 / 48 / 72...
+
+	 Returns the author's uploads, newest first, and hasMore boolean
+	 indicating more are available after the set offset.
+*/
+func (h *MediaHandler) handleMedia(w http.ResponseWriter, req *http.Request) {
+	blogQuery := new(br.BlogQuery)
+
+	if err := u.ParseBlogQueryParams(blogQuery, req.URL.Query()); err != nil {
+		error := fmt.Errorf("invalid query params: %v", err)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	response, err := h.mediaService.GetMedia(req.Context(), blogQuery.Offset)
+	if err != nil {
+		error := fmt.Errorf("failed to get media: %v", err)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	u.WriteJSON(w, http.StatusOK, response)
+}
+
+/*
+/blog/media
+
+	Protected endpoint requiring authorized token, accepts a multipart
+	form with the image in the image field
+
+	 Returns the stored media with its url for use in blog text.
+*/
+func (h *MediaHandler) handleMediaUpload(w http.ResponseWriter, req *http.Request) {
+	input, ok := parseMediaUpload(w, req)
+	if !ok {
+		return
+	}
+
+	response, err := h.mediaService.UploadMedia(req.Context(), input)
+	if err != nil {
+		error := fmt.Errorf("error uploading media: %v", err)
+		u.WriteJSONErr(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	u.WriteJSON(w, http.StatusCreated, response)
+}
+
+/*
+/blog/media/body-images
+
+	Protected endpoint requiring authorized token, accepts a multipart
+	form with a single image in the image field, validated like
+	featured images
+
+	 Returns the stored media with its url, width and height for the
+	 editor to insert into the blog text.
+*/
+func (h *MediaHandler) handleBodyImageUpload(w http.ResponseWriter, req *http.Request) {
+	input, ok := parseMediaUpload(w, req)
+	if !ok {
+		return
+	}
+
+	response, err := h.mediaService.UploadBodyImage(req.Context(), input)
+	if err != nil {
+		error := fmt.Errorf("error uploading image: %v", err)
+		u.WriteJSONErr(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	u.WriteJSON(w, http.StatusCreated, response)
+}
+
+/*
+/blog/media/{id}
+
+	Protected endpoint requiring authorized token
+
+	 Deletes the upload and its resized variants. Responds with a 409
+	 listing the blogs using it while any blog's featured image or
+	 text still references the upload.
+*/
+func (h *MediaHandler) handleMediaDelete(w http.ResponseWriter, req *http.Request) {
+	mediaID := req.PathValue("id")
+	if mediaID == "" {
+		error := fmt.Errorf("not a valid media ID")
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	response, err := h.mediaService.DeleteMedia(req.Context(), mediaID)
+	if errors.Is(err, s.ErrMediaInUse) || errors.Is(err, s.ErrProfileMedia) {
+		response.Error = err.Error()
+		u.WriteJSON(w, http.StatusConflict, response)
+		return
+	}
+
+	if err != nil {
+		error := fmt.Errorf("failed to delete media: %v", err)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	if response.Affected == 0 {
+		error := fmt.Errorf("media not found")
+		u.WriteJSONErr(w, http.StatusNotFound, error)
+		return
+	}
+
+	u.WriteJSON(w, http.StatusOK, response)
+}
+
+// parseMediaUpload reads the image of a multipart upload, writing
+// the error response and returning false when it is invalid
+func parseMediaUpload(w http.ResponseWriter, req *http.Request) (*r.MediaUploadInput, bool) {
+	req.Body = http.MaxBytesReader(w, req.Body, 32*u.MB)
+
+	isValidMime := u.ValidateRequestMime(req.Header.Get("Content-Type"), "multipart/form-data")
+	if !isValidMime {
+		error := fmt.Errorf("invalid content type")
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return nil, false
+	}
+
+	reader, err := req.MultipartReader()
+	if err != nil {
+		error := fmt.Errorf("error reading mutlipart form: %v", err)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return nil, false
+	}
+
+	input := new(r.MediaUploadInput)
+
+	if err := u.ParseMultiPartForm(reader, input); err != nil {
+		error := fmt.Errorf("error parsing mutlipart form: %v", err)
+		u.WriteJSONErr(w, u.UploadErrorStatus(err, http.StatusBadRequest), error)
+		return nil, false
+	}
+
+	return input, true
+}
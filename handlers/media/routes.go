@@ -0,0 +1,17 @@
+package media
+
+import (
+	authmiddleware "blog-api/middlewares/auth"
+	"net/http"
+)
+
+func (h *MediaHandler) RegisterMediaRoutes(prefix string, server *http.ServeMux) {
+	// the author's uploads
+	server.HandleFunc("GET "+prefix, authmiddleware.BearerAuthMiddleware(h.handleMedia))
+	// upload an image to the author's library
+	server.HandleFunc("POST "+prefix, authmiddleware.BearerAuthMiddleware(h.handleMediaUpload))
+	// upload an image pasted into a blog's text
+	server.HandleFunc("POST "+prefix+"/body-images", authmiddleware.BearerAuthMiddleware(h.handleBodyImageUpload))
+	// delete an upload no blog uses
+	server.HandleFunc("DELETE "+prefix+"/{id}", authmiddleware.BearerAuthMiddleware(h.handleMediaDelete))
+}
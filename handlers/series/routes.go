@@ -0,0 +1,19 @@
+package series
+
+import (
+	authmiddleware "blog-api/middlewares/auth"
+	"net/http"
+)
+
+func (h *SeriesHandler) RegisterSeriesRoutes(prefix string, server *http.ServeMux) {
+	// list series
+	server.HandleFunc("GET "+prefix, h.handleSeriesIndex)
+	// new series
+	server.HandleFunc("POST "+prefix, authmiddleware.BearerAuthMiddleware(h.handleNewSeries))
+	// get series and its posts
+	server.HandleFunc("GET "+prefix+"/{id}", h.handleSeries)
+	// update series
+	server.HandleFunc("PUT "+prefix+"/{id}/edit", authmiddleware.BearerAuthMiddleware(h.handleUpdateSeries))
+	// delete series by id
+	server.HandleFunc("DELETE "+prefix+"/{id}", authmiddleware.BearerAuthMiddleware(h.handleDeleteSeries))
+}
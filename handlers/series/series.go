@@ -0,0 +1,140 @@
+package series
+
+import (
+	r "blog-api/repositories/series"
+	s "blog-api/services/series"
+	u "blog-api/utilities"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+type SeriesHandler struct {
+	seriesService *s.SeriesService
+}
+
+func NewSeriesHandler(service *s.SeriesService) *SeriesHandler {
+	return &SeriesHandler{seriesService: service}
+}
+
+/*
+/blog/series
+
+	Accepts the following query params:
+	userID: only return series owned by the user
+
+	 Returns every series, most recently updated first.
+*/
+func (h *SeriesHandler) handleSeriesIndex(w http.ResponseWriter, req *http.Request) {
+	userID := req.URL.Query().Get("userID")
+
+	response, err := h.seriesService.GetSeriesIndex(req.Context(), userID)
+	if err != nil {
+		error := fmt.Errorf("failed to get series: %v", err)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	u.WriteJSON(w, http.StatusOK, response)
+}
+
+/*
+/blog/series/{id}
+
+	Returns the series and its published posts in series order.
+*/
+func (h *SeriesHandler) handleSeries(w http.ResponseWriter, req *http.Request) {
+	seriesID := req.PathValue("id")
+	if seriesID == "" {
+		error := fmt.Errorf("not a valid series ID")
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	response, err := h.seriesService.GetSeries(req.Context(), seriesID)
+	if err != nil {
+		error := fmt.Errorf("failed to lookup series %s: %v", seriesID, err)
+		u.WriteJSONErr(w, http.StatusNotFound, error)
+		return
+	}
+
+	u.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *SeriesHandler) handleNewSeries(w http.ResponseWriter, req *http.Request) {
+	input := new(r.SeriesInput)
+
+	if err := json.NewDecoder(req.Body).Decode(input); err != nil {
+		error := fmt.Errorf("failed to decode series payload: %s", err)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	if input.Title == "" {
+		error := fmt.Errorf("missing required value: title")
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	response, err := h.seriesService.CreateSeries(req.Context(), input)
+	if err != nil {
+		error := fmt.Errorf("error creating series: %v", err)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	u.WriteJSON(w, http.StatusOK, response)
+}
+
+/*
+PUT
+/blog/series/{id}/edit
+
+	Protected endpoint requiring authorized token
+
+	Updates the title and description when provided. When posts
+	is provided it replaces the ordered list of series posts.
+*/
+func (h *SeriesHandler) handleUpdateSeries(w http.ResponseWriter, req *http.Request) {
+	seriesID := req.PathValue("id")
+	if seriesID == "" {
+		error := fmt.Errorf("not a valid series ID")
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	input := new(r.SeriesInput)
+
+	if err := json.NewDecoder(req.Body).Decode(input); err != nil {
+		error := fmt.Errorf("failed to decode series payload: %s", err)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	response, err := h.seriesService.UpdateSeries(req.Context(), seriesID, input)
+	if err != nil {
+		error := fmt.Errorf("error updating series: %v", err)
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	u.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *SeriesHandler) handleDeleteSeries(w http.ResponseWriter, req *http.Request) {
+	seriesID := req.PathValue("id")
+	if seriesID == "" {
+		error := fmt.Errorf("series id can not be empty")
+		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		return
+	}
+
+	response, err := h.seriesService.DeleteSeries(req.Context(), seriesID)
+	if err != nil {
+		error := fmt.Errorf("error deleting series: %s", err)
+		u.WriteJSONErr(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	u.WriteJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,20 @@
+package sitemap
+
+import (
+	"net/http"
+)
+
+/*
+RegisterSitemapRoutes serves the site's sitemap and robots.txt. They
+list SITE_URL pages and crawlers ignore a sitemap on another host, so
+the site must proxy /sitemap.xml, /sitemaps/ and /robots.txt to these
+routes rather than link to the api.
+*/
+func (h *SitemapHandler) RegisterSitemapRoutes(prefix string, server *http.ServeMux) {
+	// sitemap or sitemap index
+	server.HandleFunc("GET "+prefix+"/sitemap.xml", h.handleSitemap)
+	// numbered pages of a sitemap index
+	server.HandleFunc("GET "+prefix+"/sitemaps/{file}", h.handleSitemapPage)
+	// robots rules
+	server.HandleFunc("GET "+prefix+"/robots.txt", h.handleRobots)
+}
@@ -0,0 +1,71 @@
+package sitemap
+
+import (
+	s "blog-api/services/sitemap"
+	u "blog-api/utilities"
+	"fmt"
+	"net/http"
+)
+
+// crawlers re-fetch the sitemap regularly, an hour keeps it fresh enough
+const CACHE_CONTROL = "public, max-age=3600"
+
+type SitemapHandler struct {
+	sitemapService *s.SitemapService
+}
+
+func NewSitemapHandler(service *s.SitemapService) *SitemapHandler {
+	return &SitemapHandler{sitemapService: service}
+}
+
+/*
+/sitemap.xml
+
+	Returns a sitemap of every published blog, category and author
+	page. When the site has more URLs than a single sitemap allows
+	a sitemap index of numbered pages is returned instead.
+*/
+func (h *SitemapHandler) handleSitemap(w http.ResponseWriter, req *http.Request) {
+	body, err := h.sitemapService.GetSitemap(req.Context())
+	if err != nil {
+		error := fmt.Errorf("failed to build sitemap: %v", err)
+		u.WriteJSONErr(w, http.StatusInternalServerError, error)
+		return
+	}
+
+	w.Header().Set("Cache-Control", CACHE_CONTROL)
+	u.WriteBody(w, http.StatusOK, "application/xml; charset=utf-8", body)
+}
+
+/*
+/sitemaps/{file}
+
+	Returns a numbered page, sitemap-{n}.xml, of a sitemap index.
+*/
+func (h *SitemapHandler) handleSitemapPage(w http.ResponseWriter, req *http.Request) {
+	page, err := s.ParsePageFilename(req.PathValue("file"))
+	if err != nil {
+		u.WriteJSONErr(w, http.StatusNotFound, err)
+		return
+	}
+
+	body, err := h.sitemapService.GetSitemapPage(req.Context(), page)
+	if err != nil {
+		error := fmt.Errorf("failed to build sitemap: %v", err)
+		u.WriteJSONErr(w, http.StatusNotFound, error)
+		return
+	}
+
+	w.Header().Set("Cache-Control", CACHE_CONTROL)
+	u.WriteBody(w, http.StatusOK, "application/xml; charset=utf-8", body)
+}
+
+/*
+/robots.txt
+
+	Returns the robots rules along with the sitemap location.
+*/
+func (h *SitemapHandler) handleRobots(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Cache-Control", CACHE_CONTROL)
+	u.WriteBody(w, http.StatusOK, "text/plain; charset=utf-8", h.sitemapService.GetRobots())
+}
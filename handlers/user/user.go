@@ -203,7 +203,7 @@ func (h *UserHandler) updateUser(w http.ResponseWriter, req *http.Request) {
 	input, err := u.ParseMultiPartFormUserUpdate(reader)
 	if err != nil {
 		error := fmt.Errorf("failed to parse form %s", err)
-		u.WriteJSONErr(w, http.StatusBadRequest, error)
+		u.WriteJSONErr(w, u.UploadErrorStatus(err, http.StatusBadRequest), error)
 		return
 	}
 
@@ -0,0 +1,579 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+var errTruncated = fmt.Errorf("invalid image: truncated")
+
+/*
+StripMetadata removes exif, xmp, iptc and comment data from an image
+without re-encoding it, so no quality is lost. The jpeg orientation
+is the only exif value kept since browsers rotate photos by it.
+*/
+func StripMetadata(data []byte, contentType string) ([]byte, error) {
+	switch contentType {
+	case JPEG:
+		return stripJPEG(data)
+	case PNG:
+		return stripPNG(data)
+	case GIF:
+		return stripGIF(data)
+	case WEBP:
+		return stripWEBP(data)
+	case AVIF:
+		return stripAVIF(data)
+	}
+
+	return nil, ErrUnsupportedType
+}
+
+// jpeg markers of segments dropped from uploads, APP0 jfif, APP2 icc
+// profiles and APP14 adobe color transforms are needed to render
+var jpegMetadata = map[byte]bool{
+	0xE1: true, // exif and xmp
+	0xE3: true, 0xE4: true, 0xE5: true, 0xE6: true, 0xE7: true,
+	0xE8: true, 0xE9: true, 0xEA: true, 0xEB: true, 0xEC: true,
+	0xED: true, // iptc
+	0xEF: true,
+	0xFE: true, // comments
+}
+
+func stripJPEG(data []byte) ([]byte, error) {
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:2])
+
+	oriented := false
+
+	for i := 2; i < len(data); {
+		if data[i] != 0xFF {
+			return nil, fmt.Errorf("invalid image: bad jpeg marker")
+		}
+
+		// fill bytes may precede a marker
+		for i < len(data) && data[i] == 0xFF {
+			i++
+		}
+		if i >= len(data) {
+			return nil, errTruncated
+		}
+
+		marker := data[i]
+		i++
+
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD8) {
+			out.Write([]byte{0xFF, marker})
+			continue
+		}
+
+		if i+2 > len(data) {
+			return nil, errTruncated
+		}
+
+		length := int(binary.BigEndian.Uint16(data[i : i+2]))
+		if length < 2 || i+length > len(data) {
+			return nil, errTruncated
+		}
+
+		switch {
+		case marker == 0xE1 && !oriented:
+			// the exif segment is replaced by one holding only the orientation
+			if orientation := exifOrientation(data[i+2 : i+length]); orientation > 1 {
+				out.Write(orientationSegment(orientation))
+				oriented = true
+			}
+		case !jpegMetadata[marker]:
+			out.Write([]byte{0xFF, marker})
+			out.Write(data[i : i+length])
+		}
+
+		i += length
+
+		// entropy coded data follows the scan header up to the end
+		if marker == 0xDA {
+			out.Write(data[i:])
+			break
+		}
+	}
+
+	return out.Bytes(), nil
+}
+
+// exifOrientation reads the orientation tag from an APP1 exif payload
+func exifOrientation(segment []byte) uint16 {
+	if !bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+		return 0
+	}
+
+	tiff := segment[6:]
+	if len(tiff) < 8 {
+		return 0
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd+2 > len(tiff) {
+		return 0
+	}
+
+	count := int(order.Uint16(tiff[ifd : ifd+2]))
+	for n := 0; n < count; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			orientation := order.Uint16(tiff[entry+8 : entry+10])
+			if orientation >= 1 && orientation <= 8 {
+				return orientation
+			}
+			return 0
+		}
+	}
+
+	return 0
+}
+
+// jpegOrientation returns the exif orientation of a jpeg, 1 when it
+// has none or the segments before the scan can't be read
+func jpegOrientation(data []byte) uint16 {
+	for i := 2; i+4 <= len(data) && data[i] == 0xFF; {
+		marker := data[i+1]
+		length := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+
+		if marker == 0xDA || length < 2 || i+2+length > len(data) {
+			break
+		}
+
+		if marker == 0xE1 {
+			if orientation := exifOrientation(data[i+4 : i+2+length]); orientation > 0 {
+				return orientation
+			}
+		}
+
+		i += 2 + length
+	}
+
+	return 1
+}
+
+// orientationSegment is an APP1 exif segment holding only the orientation
+func orientationSegment(orientation uint16) []byte {
+	payload := []byte("Exif\x00\x00")
+	// big endian tiff header with the first ifd straight after it
+	payload = append(payload, 'M', 'M', 0x00, 0x2A, 0x00, 0x00, 0x00, 0x08)
+	// one entry: orientation, SHORT, count 1, value
+	payload = append(payload, 0x00, 0x01)
+	payload = append(payload, 0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01)
+	payload = binary.BigEndian.AppendUint16(payload, orientation)
+	payload = append(payload, 0x00, 0x00)
+	// no next ifd
+	payload = append(payload, 0x00, 0x00, 0x00, 0x00)
+
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+
+	return append(segment, payload...)
+}
+
+// png chunks dropped from uploads
+var pngMetadata = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"tIME": true,
+}
+
+func stripPNG(data []byte) ([]byte, error) {
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:8])
+
+	for i := 8; i < len(data); {
+		if i+8 > len(data) {
+			return nil, errTruncated
+		}
+
+		length := int(binary.BigEndian.Uint32(data[i : i+4]))
+		chunkType := string(data[i+4 : i+8])
+
+		// length, type, data and crc
+		end := i + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, errTruncated
+		}
+
+		if !pngMetadata[chunkType] {
+			out.Write(data[i:end])
+		}
+
+		i = end
+
+		if chunkType == "IEND" {
+			break
+		}
+	}
+
+	return out.Bytes(), nil
+}
+
+func stripGIF(data []byte) ([]byte, error) {
+	// header and logical screen descriptor
+	if len(data) < 13 {
+		return nil, errTruncated
+	}
+
+	i := 13
+	if data[10]&0x80 != 0 {
+		i += 3 << ((data[10] & 0x07) + 1)
+	}
+	if i > len(data) {
+		return nil, errTruncated
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:i])
+
+	for i < len(data) {
+		start := i
+
+		switch data[i] {
+		case 0x21:
+			if i+2 > len(data) {
+				return nil, errTruncated
+			}
+
+			label := data[i+1]
+			end, err := gifSubBlocks(data, i+2)
+			if err != nil {
+				return nil, err
+			}
+
+			// comments and application data other than animation looping
+			keep := label != 0xFE
+			if label == 0xFF {
+				keep = i+3+11 <= len(data) && (string(data[i+3:i+3+11]) == "NETSCAPE2.0" || string(data[i+3:i+3+11]) == "ANIMEXTS1.0")
+			}
+
+			if keep {
+				out.Write(data[start:end])
+			}
+
+			i = end
+		case 0x2C:
+			// image descriptor, local color table, lzw code size
+			if i+10 > len(data) {
+				return nil, errTruncated
+			}
+
+			end := i + 10
+			if data[i+9]&0x80 != 0 {
+				end += 3 << ((data[i+9] & 0x07) + 1)
+			}
+			end++
+
+			end, err := gifSubBlocks(data, end)
+			if err != nil {
+				return nil, err
+			}
+
+			out.Write(data[start:end])
+			i = end
+		case 0x3B:
+			out.WriteByte(0x3B)
+			return out.Bytes(), nil
+		default:
+			return nil, fmt.Errorf("invalid image: bad gif block")
+		}
+	}
+
+	return nil, errTruncated
+}
+
+// gifSubBlocks returns the offset after the sub-blocks starting at i
+func gifSubBlocks(data []byte, i int) (int, error) {
+	for {
+		if i >= len(data) {
+			return 0, errTruncated
+		}
+
+		size := int(data[i])
+		i += 1 + size
+
+		if size == 0 {
+			return i, nil
+		}
+	}
+}
+
+func stripWEBP(data []byte) ([]byte, error) {
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:12])
+
+	for i := 12; i < len(data); {
+		if i+8 > len(data) {
+			return nil, errTruncated
+		}
+
+		fourCC := string(data[i : i+4])
+		size := int(binary.LittleEndian.Uint32(data[i+4 : i+8]))
+
+		end := i + 8 + size
+		if size < 0 || end > len(data) {
+			return nil, errTruncated
+		}
+
+		// chunks are padded to an even size, some encoders drop the last pad
+		if size%2 == 1 && end < len(data) {
+			end++
+		}
+
+		switch fourCC {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := bytes.Clone(data[i:end])
+			if len(chunk) > 8 {
+				// clear the exif and xmp flags
+				chunk[8] &^= 0x08 | 0x04
+			}
+			out.Write(chunk)
+		default:
+			out.Write(data[i:end])
+		}
+
+		i = end
+	}
+
+	stripped := out.Bytes()
+	binary.LittleEndian.PutUint32(stripped[4:8], uint32(len(stripped)-8))
+
+	return stripped, nil
+}
+
+/*
+stripAVIF blanks the exif and xmp items of an avif in place. Removing
+them would mean rewriting every item offset in the file, zeroing the
+payload keeps the offsets valid while leaving nothing to read.
+*/
+func stripAVIF(data []byte) ([]byte, error) {
+	stripped := bytes.Clone(data)
+
+	meta, ok := findBox(stripped, "meta")
+	if !ok || len(meta) < 4 {
+		return stripped, nil
+	}
+
+	// meta is a full box, version and flags precede its children
+	children := meta[4:]
+
+	iinf, ok := findBox(children, "iinf")
+	if !ok {
+		return stripped, nil
+	}
+
+	iloc, ok := findBox(children, "iloc")
+	if !ok {
+		return stripped, nil
+	}
+
+	items, err := avifMetadataItems(iinf)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(items) == 0 {
+		return stripped, nil
+	}
+
+	if err := blankAVIFItems(stripped, iloc, items); err != nil {
+		return nil, err
+	}
+
+	return stripped, nil
+}
+
+// findBox returns the payload of the first box of the type in data
+func findBox(data []byte, boxType string) ([]byte, bool) {
+	for i := 0; i+8 <= len(data); {
+		size := int(binary.BigEndian.Uint32(data[i : i+4]))
+		header := 8
+
+		switch size {
+		case 0:
+			size = len(data) - i
+		case 1:
+			if i+16 > len(data) {
+				return nil, false
+			}
+			size = int(binary.BigEndian.Uint64(data[i+8 : i+16]))
+			header = 16
+		}
+
+		if size < header || i+size > len(data) {
+			return nil, false
+		}
+
+		if string(data[i+4:i+8]) == boxType {
+			return data[i+header : i+size], true
+		}
+
+		i += size
+	}
+
+	return nil, false
+}
+
+// avifMetadataItems returns the ids of exif and xmp items in an iinf box
+func avifMetadataItems(iinf []byte) (map[uint32]bool, error) {
+	items := map[uint32]bool{}
+
+	if len(iinf) < 6 {
+		return items, errTruncated
+	}
+
+	entries := iinf[6:]
+	if iinf[0] != 0 {
+		entries = iinf[8:]
+	}
+
+	for i := 0; i+8 <= len(entries); {
+		size := int(binary.BigEndian.Uint32(entries[i : i+4]))
+		if size < 8 || i+size > len(entries) {
+			return items, errTruncated
+		}
+
+		if string(entries[i+4:i+8]) == "infe" {
+			infe := entries[i+8 : i+size]
+
+			// only version 2 and 3 entries carry an item type
+			if len(infe) >= 4 && infe[0] >= 2 {
+				var id uint32
+				rest := infe[4:]
+
+				if infe[0] == 2 && len(rest) >= 2 {
+					id = uint32(binary.BigEndian.Uint16(rest[:2]))
+					rest = rest[2:]
+				} else if infe[0] == 3 && len(rest) >= 4 {
+					id = binary.BigEndian.Uint32(rest[:4])
+					rest = rest[4:]
+				}
+
+				// protection index then the item type
+				if len(rest) >= 6 {
+					itemType := string(rest[2:6])
+
+					if itemType == "Exif" || (itemType == "mime" && bytes.Contains(rest[6:], []byte("rdf+xml"))) {
+						items[id] = true
+					}
+				}
+			}
+		}
+
+		i += size
+	}
+
+	return items, nil
+}
+
+// blankAVIFItems zeroes the file extents of the items listed in an iloc box
+func blankAVIFItems(data, iloc []byte, items map[uint32]bool) error {
+	r := &boxReader{data: iloc}
+
+	version := r.uint(1)
+	r.skip(3)
+
+	sizes := r.uint(1)
+	offsetSize, lengthSize := int(sizes>>4), int(sizes&0x0F)
+
+	sizes = r.uint(1)
+	baseOffsetSize, indexSize := int(sizes>>4), 0
+	if version == 1 || version == 2 {
+		indexSize = int(sizes & 0x0F)
+	}
+
+	count := r.uint(2)
+	if version == 2 {
+		count = r.uint(4)
+	}
+
+	for n := uint64(0); n < count && r.err == nil; n++ {
+		id := r.uint(2)
+		if version == 2 {
+			id = r.uint(4)
+		}
+
+		method := uint64(0)
+		if version == 1 || version == 2 {
+			method = r.uint(2) & 0x0F
+		}
+
+		// data reference index
+		r.skip(2)
+
+		base := r.uint(baseOffsetSize)
+		extents := r.uint(2)
+
+		for e := uint64(0); e < extents && r.err == nil; e++ {
+			r.skip(indexSize)
+			offset := r.uint(offsetSize)
+			length := r.uint(lengthSize)
+
+			// only extents stored by file offset can be blanked
+			if r.err != nil || !items[uint32(id)] || method != 0 {
+				continue
+			}
+
+			start, end := base+offset, base+offset+length
+			if length == 0 || end > uint64(len(data)) || start > end {
+				return fmt.Errorf("invalid image: bad avif item location")
+			}
+
+			clear(data[start:end])
+		}
+	}
+
+	return r.err
+}
+
+// boxReader reads big endian integers of a variable byte size
+type boxReader struct {
+	data []byte
+	at   int
+	err  error
+}
+
+func (r *boxReader) uint(size int) uint64 {
+	if r.err != nil {
+		return 0
+	}
+
+	if r.at+size > len(r.data) {
+		r.err = errTruncated
+		return 0
+	}
+
+	value := uint64(0)
+	for _, b := range r.data[r.at : r.at+size] {
+		value = value<<8 | uint64(b)
+	}
+
+	r.at += size
+
+	return value
+}
+
+func (r *boxReader) skip(size int) {
+	r.uint(size)
+}
@@ -0,0 +1,211 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"path"
+	"strings"
+)
+
+const (
+	JPEG = "image/jpeg"
+	PNG  = "image/png"
+	GIF  = "image/gif"
+	WEBP = "image/webp"
+	AVIF = "image/avif"
+)
+
+const (
+	// longest side accepted for an upload
+	MAX_DIMENSION = 12000
+	// decoded pixels accepted for an upload, about 160MB as RGBA
+	MAX_PIXELS = 40_000_000
+)
+
+var (
+	ErrUnsupportedType = errors.New("unsupported image type, expected jpeg, png, gif, webp or avif")
+	ErrTooLarge        = errors.New("image dimensions are too large")
+)
+
+// Upload is a validated image with its metadata removed
+type Upload struct {
+	ContentType string
+	Width       int
+	Height      int
+	Data        []byte
+}
+
+var extensions = map[string]string{
+	JPEG: ".jpg",
+	PNG:  ".png",
+	GIF:  ".gif",
+	WEBP: ".webp",
+	AVIF: ".avif",
+}
+
+/*
+Sniff returns the content type of an image from its magic bytes,
+or ErrUnsupportedType when the data is not one of the allowed types.
+*/
+func Sniff(data []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF}):
+		return JPEG, nil
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return PNG, nil
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		return GIF, nil
+	case len(data) >= 12 && bytes.Equal(data[:4], []byte("RIFF")) && bytes.Equal(data[8:12], []byte("WEBP")):
+		return WEBP, nil
+	case isAVIF(data):
+		return AVIF, nil
+	}
+
+	return "", ErrUnsupportedType
+}
+
+// Extension returns the file extension for an allowed content type
+func Extension(contentType string) string {
+	return extensions[contentType]
+}
+
+// WithExtension replaces the filename's extension with the one
+// matching the content type, photo.png holding a jpeg becomes photo.jpg
+func WithExtension(filename, contentType string) string {
+	ext := Extension(contentType)
+	if ext == "" || strings.EqualFold(path.Ext(filename), ext) {
+		return filename
+	}
+
+	if contentType == JPEG && strings.EqualFold(path.Ext(filename), ".jpeg") {
+		return filename
+	}
+
+	return strings.TrimSuffix(filename, path.Ext(filename)) + ext
+}
+
+/*
+Validate checks an upload against the allowed types and reads its
+dimensions from the header alone, so an image that would decode into
+more than MAX_PIXELS is rejected before any pixels are allocated. The
+returned upload has its metadata stripped.
+*/
+func Validate(data []byte) (Upload, error) {
+	upload := Upload{}
+
+	contentType, err := Sniff(data)
+	if err != nil {
+		return upload, err
+	}
+
+	width, height, err := dimensions(data, contentType)
+	if err != nil {
+		return upload, err
+	}
+
+	if width <= 0 || height <= 0 {
+		return upload, fmt.Errorf("invalid image dimensions %dx%d", width, height)
+	}
+
+	if width > MAX_DIMENSION || height > MAX_DIMENSION || width*height > MAX_PIXELS {
+		return upload, fmt.Errorf("%w: %dx%d", ErrTooLarge, width, height)
+	}
+
+	stripped, err := StripMetadata(data, contentType)
+	if err != nil {
+		return upload, err
+	}
+
+	upload.ContentType = contentType
+	upload.Width = width
+	upload.Height = height
+	upload.Data = stripped
+
+	return upload, nil
+}
+
+// Dimensions returns the width and height of an allowed image
+// from its header without decoding it
+func Dimensions(data []byte) (int, int, error) {
+	contentType, err := Sniff(data)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return dimensions(data, contentType)
+}
+
+func dimensions(data []byte, contentType string) (int, int, error) {
+	if contentType == AVIF {
+		return avifDimensions(data)
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid image: %w", err)
+	}
+
+	return config.Width, config.Height, nil
+}
+
+// isAVIF checks the ftyp box for an avif brand
+func isAVIF(data []byte) bool {
+	if len(data) < 16 || !bytes.Equal(data[4:8], []byte("ftyp")) {
+		return false
+	}
+
+	size := int(binary.BigEndian.Uint32(data[:4]))
+	if size < 16 || size > len(data) {
+		return false
+	}
+
+	// major brand, then compatible brands after the minor version
+	brands := [][]byte{data[8:12]}
+	for i := 16; i+4 <= size; i += 4 {
+		brands = append(brands, data[i:i+4])
+	}
+
+	for _, brand := range brands {
+		if bytes.Equal(brand, []byte("avif")) || bytes.Equal(brand, []byte("avis")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// avifDimensions reads the largest image spatial extents property,
+// there is no pure go avif decoder to read them from
+func avifDimensions(data []byte) (int, int, error) {
+	var width, height uint32
+
+	marker := []byte("ispe")
+	for i := 0; ; {
+		at := bytes.Index(data[i:], marker)
+		if at < 0 {
+			break
+		}
+
+		// box size precedes the type, then version and flags
+		start := i + at
+		if start >= 4 && start+16 <= len(data) && binary.BigEndian.Uint32(data[start-4:start]) == 20 {
+			w := binary.BigEndian.Uint32(data[start+8 : start+12])
+			h := binary.BigEndian.Uint32(data[start+12 : start+16])
+
+			if uint64(w)*uint64(h) > uint64(width)*uint64(height) {
+				width, height = w, h
+			}
+		}
+
+		i = start + len(marker)
+	}
+
+	if width == 0 || height == 0 {
+		return 0, 0, fmt.Errorf("invalid image: avif without dimensions")
+	}
+
+	return int(width), int(height), nil
+}
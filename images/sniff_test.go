@@ -0,0 +1,284 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"testing"
+)
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		Name string
+		Data []byte
+		Want string
+	}{
+		{Name: "jpeg", Data: []byte{0xFF, 0xD8, 0xFF, 0xE0}, Want: JPEG},
+		{Name: "png", Data: []byte("\x89PNG\r\n\x1a\n...."), Want: PNG},
+		{Name: "gif", Data: []byte("GIF89a...."), Want: GIF},
+		{Name: "webp", Data: []byte("RIFF\x00\x00\x00\x00WEBPVP8 "), Want: WEBP},
+		{Name: "avif", Data: avifBox("ftyp", []byte("avif\x00\x00\x00\x00mif1miaf")), Want: AVIF},
+		{Name: "heic", Data: avifBox("ftyp", []byte("heic\x00\x00\x00\x00mif1heic")), Want: ""},
+		{Name: "html", Data: []byte("<!doctype html><svg onload=alert(1)>"), Want: ""},
+		{Name: "pdf", Data: []byte("%PDF-1.7"), Want: ""},
+	}
+
+	for _, test := range tests {
+		got, err := Sniff(test.Data)
+
+		if test.Want == "" {
+			if !errors.Is(err, ErrUnsupportedType) {
+				t.Errorf("%s: wanted unsupported type, got %s %v", test.Name, got, err)
+			}
+			continue
+		}
+
+		if got != test.Want {
+			t.Errorf("%s: wanted %s, got %s %v", test.Name, test.Want, got, err)
+		}
+	}
+}
+
+func TestWithExtension(t *testing.T) {
+	tests := []struct {
+		Filename    string
+		ContentType string
+		Want        string
+	}{
+		{Filename: "photo.png", ContentType: JPEG, Want: "photo.jpg"},
+		{Filename: "photo.JPEG", ContentType: JPEG, Want: "photo.JPEG"},
+		{Filename: "photo", ContentType: WEBP, Want: "photo.webp"},
+		{Filename: "photo.gif", ContentType: GIF, Want: "photo.gif"},
+	}
+
+	for _, test := range tests {
+		if got := WithExtension(test.Filename, test.ContentType); got != test.Want {
+			t.Errorf("wanted %s, got %s", test.Want, got)
+		}
+	}
+}
+
+func TestValidateRejectsDecompressionBomb(t *testing.T) {
+	data := testImage(10, 10, 255)
+
+	// claim 30000x30000 in the IHDR chunk and fix up its crc
+	binary.BigEndian.PutUint32(data[16:20], 30000)
+	binary.BigEndian.PutUint32(data[20:24], 30000)
+	binary.BigEndian.PutUint32(data[29:33], crc32.ChecksumIEEE(data[12:29]))
+
+	if _, err := Validate(data); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("wanted ErrTooLarge, got %v", err)
+	}
+
+	if _, _, err := Decode(data); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("wanted Decode to refuse the image, got %v", err)
+	}
+}
+
+func TestStripJPEG(t *testing.T) {
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 8, 8)), nil)
+	encoded := buf.Bytes()
+
+	// exif with the orientation and a gps marker, then a comment
+	exif := []byte("Exif\x00\x00II*\x00\x08\x00\x00\x00\x02\x00")
+	exif = append(exif, 0x12, 0x01, 0x03, 0x00, 0x01, 0x00, 0x00, 0x00, 0x06, 0x00, 0x00, 0x00)
+	exif = append(exif, 0x25, 0x88, 0x04, 0x00, 0x01, 0x00, 0x00, 0x00, 0x26, 0x00, 0x00, 0x00)
+	exif = append(exif, 0x00, 0x00, 0x00, 0x00)
+	exif = append(exif, []byte("GPS 51.5074 N 0.1278 W")...)
+
+	data := append([]byte{}, encoded[:2]...)
+	data = append(data, jpegSegment(0xE1, exif)...)
+	data = append(data, jpegSegment(0xFE, []byte("taken at home"))...)
+	data = append(data, encoded[2:]...)
+
+	upload, err := Validate(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(upload.Data, []byte("GPS")) || bytes.Contains(upload.Data, []byte("taken at home")) {
+		t.Error("wanted exif and comments removed")
+	}
+
+	start := bytes.Index(upload.Data, []byte("Exif\x00\x00"))
+	if start < 0 || exifOrientation(upload.Data[start:]) != 6 {
+		t.Error("wanted the orientation kept")
+	}
+
+	if _, err := jpeg.Decode(bytes.NewReader(upload.Data)); err != nil {
+		t.Errorf("wanted a valid jpeg, got %v", err)
+	}
+}
+
+func TestStripPNG(t *testing.T) {
+	data := testImage(4, 4, 255)
+
+	text := pngChunk("tEXt", []byte("Comment\x00GPS 51.5074 N"))
+	data = append(data[:33:33], append(text, data[33:]...)...)
+
+	upload, err := Validate(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(upload.Data, []byte("GPS")) {
+		t.Error("wanted text chunks removed")
+	}
+
+	if _, _, err := Decode(upload.Data); err != nil {
+		t.Errorf("wanted a valid png, got %v", err)
+	}
+}
+
+func TestStripGIF(t *testing.T) {
+	var buf bytes.Buffer
+	gif.Encode(&buf, image.NewPaletted(image.Rect(0, 0, 4, 4), color.Palette{color.Black, color.White}), nil)
+	encoded := buf.Bytes()
+
+	// comment extension straight after the global color table
+	header := 13
+	if encoded[10]&0x80 != 0 {
+		header += 3 << ((encoded[10] & 0x07) + 1)
+	}
+
+	comment := []byte{0x21, 0xFE, 11}
+	comment = append(comment, []byte("GPS 51.5074")...)
+	comment = append(comment, 0x00)
+
+	data := append([]byte{}, encoded[:header]...)
+	data = append(data, comment...)
+	data = append(data, encoded[header:]...)
+
+	upload, err := Validate(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(upload.Data, []byte("GPS")) {
+		t.Error("wanted comments removed")
+	}
+
+	if _, err := gif.Decode(bytes.NewReader(upload.Data)); err != nil {
+		t.Errorf("wanted a valid gif, got %v", err)
+	}
+}
+
+func TestStripWEBP(t *testing.T) {
+	vp8x := make([]byte, 10)
+	vp8x[0] = 0x08 | 0x04
+
+	data := []byte("RIFF\x00\x00\x00\x00WEBP")
+	data = append(data, riffChunk("VP8X", vp8x)...)
+	data = append(data, riffChunk("VP8L", []byte{0x2F, 0x00})...)
+	data = append(data, riffChunk("EXIF", []byte("GPS 51.5074 N"))...)
+	data = append(data, riffChunk("XMP ", []byte("<x:xmpmeta/>"))...)
+	binary.LittleEndian.PutUint32(data[4:8], uint32(len(data)-8))
+
+	stripped, err := StripMetadata(data, WEBP)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(stripped, []byte("GPS")) || bytes.Contains(stripped, []byte("xmpmeta")) {
+		t.Error("wanted exif and xmp chunks removed")
+	}
+
+	if stripped[20]&(0x08|0x04) != 0 {
+		t.Error("wanted the exif and xmp flags cleared")
+	}
+
+	if size := int(binary.LittleEndian.Uint32(stripped[4:8])); size != len(stripped)-8 {
+		t.Errorf("wanted riff size %d, got %d", len(stripped)-8, size)
+	}
+}
+
+func TestStripAVIF(t *testing.T) {
+	secret := []byte("\x00\x00\x00\x00II*\x00GPS 51.5074 N")
+
+	ispe := append([]byte{0, 0, 0, 0}, binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, 1200), 800)...)
+
+	// item 1 is the image, item 2 exif
+	infe := func(id uint16, itemType string) []byte {
+		payload := append([]byte{2, 0, 0, 0}, binary.BigEndian.AppendUint16(nil, id)...)
+		payload = append(payload, 0, 0)
+		payload = append(payload, itemType...)
+		return avifBox("infe", payload)
+	}
+	iinf := append([]byte{0, 0, 0, 0, 0, 2}, infe(1, "av01")...)
+	iinf = append(iinf, infe(2, "Exif")...)
+
+	ftyp := avifBox("ftyp", []byte("avif\x00\x00\x00\x00mif1miaf"))
+	iprp := avifBox("iprp", avifBox("ipco", avifBox("ispe", ispe)))
+
+	iloc := func(offset uint32) []byte {
+		payload := []byte{0, 0, 0, 0, 0x44, 0x00, 0, 1}
+		payload = append(payload, 0, 2, 0, 0, 0, 1)
+		payload = binary.BigEndian.AppendUint32(payload, offset)
+		payload = binary.BigEndian.AppendUint32(payload, uint32(len(secret)))
+		return avifBox("iloc", payload)
+	}
+
+	meta := func(offset uint32) []byte {
+		children := append([]byte{0, 0, 0, 0}, avifBox("iinf", iinf)...)
+		children = append(children, iloc(offset)...)
+		children = append(children, iprp...)
+		return avifBox("meta", children)
+	}
+
+	// the exif payload starts after the mdat header
+	offset := uint32(len(ftyp) + len(meta(0)) + 8)
+
+	data := append(ftyp, meta(offset)...)
+	data = append(data, avifBox("mdat", secret)...)
+
+	upload, err := Validate(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if upload.ContentType != AVIF || upload.Width != 1200 || upload.Height != 800 {
+		t.Errorf("wanted a 1200x800 avif, got %s %dx%d", upload.ContentType, upload.Width, upload.Height)
+	}
+
+	if bytes.Contains(upload.Data, []byte("GPS")) {
+		t.Error("wanted the exif item blanked")
+	}
+
+	if len(upload.Data) != len(data) {
+		t.Error("wanted item offsets left unchanged")
+	}
+}
+
+func jpegSegment(marker byte, payload []byte) []byte {
+	segment := []byte{0xFF, marker}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+func pngChunk(chunkType string, payload []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(payload)))
+	chunk = append(chunk, chunkType...)
+	chunk = append(chunk, payload...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+func riffChunk(fourCC string, payload []byte) []byte {
+	chunk := append([]byte(fourCC), binary.LittleEndian.AppendUint32(nil, uint32(len(payload)))...)
+	chunk = append(chunk, payload...)
+	if len(payload)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+func avifBox(boxType string, payload []byte) []byte {
+	box := binary.BigEndian.AppendUint32(nil, uint32(len(payload)+8))
+	box = append(box, boxType...)
+	return append(box, payload...)
+}
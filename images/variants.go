@@ -0,0 +1,200 @@
+package images
+
+/*
+==== Images ======================================================
+|                                                                |
+| Pure Go image processing for uploads:                          |
+| - Decoding jpeg, png, gif and webp uploads                     |
+| - Rendering resized variants for responsive srcsets            |
+|                                                                |
+==================================================================
+*/
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+
+	// decoders registered for image.Decode
+	_ "image/gif"
+
+	_ "golang.org/x/image/webp"
+
+	"golang.org/x/image/draw"
+)
+
+const JPEG_QUALITY = 82
+
+// Spec names a variant and the width it is scaled down to
+type Spec struct {
+	Name  string
+	Width int
+}
+
+// Variant is an encoded image ready to upload
+type Variant struct {
+	Name        string
+	Width       int
+	Height      int
+	ContentType string
+	Extension   string
+	Data        []byte
+}
+
+// VARIANTS are rendered for featured images, narrowest first
+var VARIANTS = []Spec{
+	{Name: "thumbnail", Width: 320},
+	{Name: "medium", Width: 768},
+	{Name: "large", Width: 1600},
+}
+
+/*
+Decode reads the dimensions and pixels of an uploaded image,
+returning the name of its format: jpeg, png, gif or webp. Images
+larger than MAX_PIXELS are rejected before they are decoded. A jpeg
+is turned upright by its exif orientation, as browsers display it.
+*/
+func Decode(data []byte) (image.Image, string, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("unsupported or invalid image: %w", err)
+	}
+
+	// bound the allocation before decoding any pixels
+	if config.Width > MAX_DIMENSION || config.Height > MAX_DIMENSION || config.Width*config.Height > MAX_PIXELS {
+		return nil, "", fmt.Errorf("%w: %dx%d", ErrTooLarge, config.Width, config.Height)
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("unsupported or invalid image: %w", err)
+	}
+
+	if format == "jpeg" {
+		img = orient(img, jpegOrientation(data))
+	}
+
+	return img, format, nil
+}
+
+/*
+orient applies an exif orientation to the image: 2 to 4 mirror or
+turn it half way, 5 to 8 swap its width and height. Orientation 1,
+or an unknown one, returns the image as is.
+*/
+func orient(img image.Image, orientation uint16) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	src := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < dstHeight; y++ {
+		for x := 0; x < dstWidth; x++ {
+			// the source pixel shown at x, y once oriented
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = width-1-x, y
+			case 3:
+				sx, sy = width-1-x, height-1-y
+			case 4:
+				sx, sy = x, height-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, height-1-x
+			case 7:
+				sx, sy = width-1-y, height-1-x
+			case 8:
+				sx, sy = width-1-y, x
+			}
+
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], src.Pix[src.PixOffset(sx, sy):src.PixOffset(sx, sy)+4])
+		}
+	}
+
+	return dst
+}
+
+/*
+Resize renders a variant of the image for every spec narrower than
+it, never scaling up. Opaque images are encoded as jpeg, images with
+transparency as png so it survives.
+*/
+func Resize(img image.Image, specs []Spec) ([]Variant, error) {
+	bounds := img.Bounds()
+	variants := []Variant{}
+
+	for _, spec := range specs {
+		if spec.Width >= bounds.Dx() {
+			continue
+		}
+
+		height := max(1, bounds.Dy()*spec.Width/bounds.Dx())
+
+		scaled := image.NewRGBA(image.Rect(0, 0, spec.Width, height))
+		draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, bounds, draw.Src, nil)
+
+		variant, err := Encode(scaled, isOpaque(img))
+		if err != nil {
+			return variants, err
+		}
+
+		variant.Name = spec.Name
+		variants = append(variants, variant)
+	}
+
+	return variants, nil
+}
+
+// Encode writes the image as a jpeg when it is opaque, otherwise as a png
+func Encode(img image.Image, opaque bool) (Variant, error) {
+	var buf bytes.Buffer
+
+	variant := Variant{
+		Width:  img.Bounds().Dx(),
+		Height: img.Bounds().Dy(),
+	}
+
+	if opaque {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: JPEG_QUALITY}); err != nil {
+			return variant, err
+		}
+
+		variant.ContentType = "image/jpeg"
+		variant.Extension = ".jpg"
+	} else {
+		if err := png.Encode(&buf, img); err != nil {
+			return variant, err
+		}
+
+		variant.ContentType = "image/png"
+		variant.Extension = ".png"
+	}
+
+	variant.Data = buf.Bytes()
+
+	return variant, nil
+}
+
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+
+	return false
+}
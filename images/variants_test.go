@@ -0,0 +1,180 @@
+package images
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testImage(width, height int, alpha uint8) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 100, A: alpha})
+		}
+	}
+
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+
+	return buf.Bytes()
+}
+
+func TestResize(t *testing.T) {
+	img, format, err := Decode(testImage(1000, 500, 255))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if format != "png" {
+		t.Errorf("wanted png, got %s", format)
+	}
+
+	variants, err := Resize(img, VARIANTS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// large is wider than the image and is never scaled up
+	if len(variants) != 2 {
+		t.Fatalf("wanted 2 variants, got %d", len(variants))
+	}
+
+	wants := []Variant{
+		{Name: "thumbnail", Width: 320, Height: 160},
+		{Name: "medium", Width: 768, Height: 384},
+	}
+
+	for i, want := range wants {
+		got := variants[i]
+
+		if got.Name != want.Name || got.Width != want.Width || got.Height != want.Height {
+			t.Errorf("wanted %s %dx%d, got %s %dx%d", want.Name, want.Width, want.Height, got.Name, got.Width, got.Height)
+		}
+
+		if got.ContentType != "image/jpeg" {
+			t.Errorf("wanted an opaque image to be a jpeg, got %s", got.ContentType)
+		}
+
+		decoded, _, err := Decode(got.Data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if decoded.Bounds().Dx() != want.Width {
+			t.Errorf("wanted encoded width %d, got %d", want.Width, decoded.Bounds().Dx())
+		}
+	}
+}
+
+func TestResizeKeepsTransparency(t *testing.T) {
+	img, _, err := Decode(testImage(400, 400, 128))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	variants, err := Resize(img, VARIANTS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(variants) != 1 || variants[0].ContentType != "image/png" {
+		t.Errorf("wanted a single png variant, got %+v", variants)
+	}
+}
+
+// orientedJPEG is a phone photo stored sideways, red on the left and
+// blue on the right, with the exif orientation to display it upright
+func orientedJPEG(width, height int, orientation uint16) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if x < width/2 {
+				img.Set(x, y, color.RGBA{R: 255, A: 255})
+			} else {
+				img.Set(x, y, color.RGBA{B: 255, A: 255})
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, img, nil)
+
+	data := buf.Bytes()
+	if orientation == 0 {
+		return data
+	}
+
+	// the exif segment goes straight after the start of image marker
+	oriented := append([]byte{}, data[:2]...)
+	oriented = append(oriented, orientationSegment(orientation)...)
+
+	return append(oriented, data[2:]...)
+}
+
+func isRed(c color.Color) bool {
+	r, _, b, _ := c.RGBA()
+	return r > 0xC000 && b < 0x4000
+}
+
+func TestDecodeOrientation(t *testing.T) {
+	tests := []struct {
+		Orientation uint16
+		Width       int
+		Height      int
+		RedOnTop    bool
+	}{
+		{Orientation: 0, Width: 800, Height: 400},
+		{Orientation: 6, Width: 400, Height: 800, RedOnTop: true},
+		{Orientation: 8, Width: 400, Height: 800, RedOnTop: false},
+	}
+
+	for _, test := range tests {
+		img, _, err := Decode(orientedJPEG(800, 400, test.Orientation))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if img.Bounds().Dx() != test.Width || img.Bounds().Dy() != test.Height {
+			t.Errorf("orientation %d: wanted %dx%d, got %v", test.Orientation, test.Width, test.Height, img.Bounds())
+			continue
+		}
+
+		if test.Width > test.Height {
+			continue
+		}
+
+		variants, err := Resize(img, VARIANTS)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		thumbnail, _, err := Decode(variants[0].Data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if thumbnail.Bounds().Dx() != 320 || thumbnail.Bounds().Dy() != 640 {
+			t.Errorf("orientation %d: wanted an upright 320x640 thumbnail, got %v", test.Orientation, thumbnail.Bounds())
+		}
+
+		if got := isRed(thumbnail.At(160, 100)); got != test.RedOnTop {
+			t.Errorf("orientation %d: wanted red on top %v, got %v", test.Orientation, test.RedOnTop, got)
+		}
+
+		if got := isRed(thumbnail.At(160, 540)); got == test.RedOnTop {
+			t.Errorf("orientation %d: wanted red at the bottom %v, got %v", test.Orientation, !test.RedOnTop, got)
+		}
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	if _, _, err := Decode([]byte("not an image")); err == nil {
+		t.Error("wanted an error decoding invalid data")
+	}
+}
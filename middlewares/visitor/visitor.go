@@ -0,0 +1,224 @@
+package visitormiddleware
+
+import (
+	ck "blog-api/contextkeys"
+	r "blog-api/repositories/user"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	VISITOR_COOKIE     = "visitor_id"
+	VISITOR_COOKIE_AGE = 365 * 24 * 60 * 60 // seconds
+)
+
+/*
+VisitorMiddleware identifies the reader of a public route so their
+actions can be counted once. Signed in readers are identified by the
+user in their token, anonymous readers by a signed visitor cookie or,
+until they send one back, by a hash of their ip and user agent salted
+with the day. The fingerprint is what the issued cookie carries so
+both name the same visitor. The identity is stored under
+ck.VisitorIDKey and a missing or invalid token is not an error.
+*/
+func VisitorMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		visitorID := identifyVisitor(w, req, visitorSecret(), time.Now())
+
+		ctx := context.WithValue(req.Context(), ck.VisitorIDKey, visitorID)
+
+		next(w, req.WithContext(ctx))
+	}
+}
+
+func identifyVisitor(w http.ResponseWriter, req *http.Request, secret []byte, now time.Time) string {
+	if userID, ok := bearerUser(req); ok {
+		return "user:" + userID
+	}
+
+	if cookie, err := req.Cookie(VISITOR_COOKIE); err == nil {
+		if id, ok := verifyVisitorCookie(cookie.Value, secret); ok {
+			return "visitor:" + id
+		}
+	}
+
+	id := fingerprint(req, secret, now)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     VISITOR_COOKIE,
+		Value:    signVisitorCookie(id, secret),
+		Path:     "/",
+		MaxAge:   VISITOR_COOKIE_AGE,
+		HttpOnly: true,
+		Secure:   isSecure(req),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return "visitor:" + id
+}
+
+// visitorSecret signs visitor cookies and salts fingerprints,
+// falling back to the jwt secret when no dedicated one is set
+func visitorSecret() []byte {
+	if secret := os.Getenv("VISITOR_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+
+	return []byte(os.Getenv("JWT_SECRET_KEY"))
+}
+
+func bearerUser(req *http.Request) (string, bool) {
+	parts := strings.Split(req.Header.Get("Authorization"), " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return "", false
+	}
+
+	userID, err := r.VerifyJWT(parts[1])
+	if err != nil || userID == "" {
+		return "", false
+	}
+
+	return userID, true
+}
+
+// fingerprint hashes the reader's ip and user agent with a salt
+// that changes daily so the hash can't be tracked across days
+func fingerprint(req *http.Request, secret []byte, now time.Time) string {
+	salt := hmacSum(secret, now.UTC().Format(time.DateOnly))
+	sum := hmacSum(salt, clientIP(req)+"\n"+req.UserAgent())
+
+	return hex.EncodeToString(sum[:16])
+}
+
+func signVisitorCookie(id string, secret []byte) string {
+	return id + "." + base64.RawURLEncoding.EncodeToString(hmacSum(secret, id))
+}
+
+func verifyVisitorCookie(value string, secret []byte) (string, bool) {
+	id, signature, found := strings.Cut(value, ".")
+	if !found || id == "" {
+		return "", false
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(decoded, hmacSum(secret, id)) {
+		return "", false
+	}
+
+	return id, true
+}
+
+func hmacSum(key []byte, value string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+
+	return mac.Sum(nil)
+}
+
+/*
+clientIP is the address of the connection unless the connection comes
+from one of TRUSTED_PROXIES, only then are the forwarding headers
+honoured. Anyone else could send a new X-Forwarded-For with every
+request and be counted as a new visitor each time.
+*/
+func clientIP(req *http.Request) string {
+	remote := remoteIP(req)
+
+	proxies := trustedProxies()
+	if !isTrusted(remote, proxies) {
+		return remote
+	}
+
+	// proxies append the address they received from, the nearest
+	// one no trusted proxy added is the client
+	if forwarded := req.Header.Get("X-Forwarded-For"); forwarded != "" {
+		hops := strings.Split(forwarded, ",")
+
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if _, err := netip.ParseAddr(hop); err != nil {
+				break
+			}
+
+			if !isTrusted(hop, proxies) || i == 0 {
+				return hop
+			}
+		}
+	}
+
+	if realIP := strings.TrimSpace(req.Header.Get("X-Real-IP")); realIP != "" {
+		if _, err := netip.ParseAddr(realIP); err == nil {
+			return realIP
+		}
+	}
+
+	return remote
+}
+
+func remoteIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+
+	return host
+}
+
+// trustedProxies parses TRUSTED_PROXIES, comma separated addresses
+// or CIDR ranges of the proxies in front of the api
+func trustedProxies() []netip.Prefix {
+	proxies := []netip.Prefix{}
+
+	for _, entry := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			proxies = append(proxies, prefix.Masked())
+			continue
+		}
+
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			addr = addr.Unmap()
+			proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+		}
+	}
+
+	return proxies
+}
+
+func isTrusted(ip string, proxies []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+
+	addr = addr.Unmap()
+
+	for _, proxy := range proxies {
+		if proxy.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isSecure(req *http.Request) bool {
+	if req.TLS != nil {
+		return true
+	}
+
+	return isTrusted(remoteIP(req), trustedProxies()) && req.Header.Get("X-Forwarded-Proto") == "https"
+}
@@ -0,0 +1,136 @@
+package visitormiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var secret = []byte("test secret")
+
+func TestVisitorCookieRoundTrip(t *testing.T) {
+	value := signVisitorCookie("abc123", secret)
+
+	id, ok := verifyVisitorCookie(value, secret)
+	if !ok || id != "abc123" {
+		t.Fatalf("verifyVisitorCookie(%q) = %q, %v", value, id, ok)
+	}
+
+	for _, tampered := range []string{
+		"abc124" + value[6:],
+		value + "x",
+		"abc123",
+		"." + value[7:],
+	} {
+		if _, ok := verifyVisitorCookie(tampered, secret); ok {
+			t.Errorf("verifyVisitorCookie(%q) accepted a tampered cookie", tampered)
+		}
+	}
+
+	if _, ok := verifyVisitorCookie(value, []byte("other secret")); ok {
+		t.Error("verifyVisitorCookie accepted a cookie signed with another secret")
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	day := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	newRequest := func(ip, agent string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/blog/1/like", nil)
+		req.RemoteAddr = ip + ":5000"
+		req.Header.Set("User-Agent", agent)
+		return req
+	}
+
+	first := fingerprint(newRequest("10.0.0.1", "firefox"), secret, day)
+
+	if got := fingerprint(newRequest("10.0.0.1", "firefox"), secret, day.Add(10*time.Hour)); got != first {
+		t.Error("fingerprint changed within the same day")
+	}
+
+	if got := fingerprint(newRequest("10.0.0.1", "firefox"), secret, day.Add(24*time.Hour)); got == first {
+		t.Error("fingerprint did not change with the day")
+	}
+
+	if got := fingerprint(newRequest("10.0.0.2", "firefox"), secret, day); got == first {
+		t.Error("fingerprint did not change with the ip")
+	}
+
+	if got := fingerprint(newRequest("10.0.0.1", "chrome"), secret, day); got == first {
+		t.Error("fingerprint did not change with the user agent")
+	}
+}
+
+func TestIdentifyVisitor(t *testing.T) {
+	now := time.Now()
+
+	req := httptest.NewRequest(http.MethodPost, "/blog/1/like", nil)
+	w := httptest.NewRecorder()
+
+	visitor := identifyVisitor(w, req, secret, now)
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != VISITOR_COOKIE {
+		t.Fatalf("expected a visitor cookie to be issued, got %v", cookies)
+	}
+
+	// the issued cookie names the same visitor on later requests
+	next := httptest.NewRequest(http.MethodDelete, "/blog/1/like", nil)
+	next.RemoteAddr = "192.0.2.50:1234"
+	next.AddCookie(cookies[0])
+	nextW := httptest.NewRecorder()
+
+	if got := identifyVisitor(nextW, next, secret, now.Add(48*time.Hour)); got != visitor {
+		t.Errorf("identifyVisitor with cookie = %q, want %q", got, visitor)
+	}
+
+	if len(nextW.Result().Cookies()) != 0 {
+		t.Error("a new cookie was issued to a visitor with a valid cookie")
+	}
+
+	// an invalid token falls back to the anonymous identity
+	bad := httptest.NewRequest(http.MethodPost, "/blog/1/like", nil)
+	bad.Header.Set("Authorization", "Bearer not-a-token")
+
+	if got := identifyVisitor(httptest.NewRecorder(), bad, secret, now); got != visitor {
+		t.Errorf("identifyVisitor with invalid token = %q, want %q", got, visitor)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.0.2.1")
+
+	tests := []struct {
+		Name       string
+		RemoteAddr string
+		Forwarded  string
+		RealIP     string
+		Want       string
+	}{
+		{Name: "direct", RemoteAddr: "203.0.113.7:5000", Want: "203.0.113.7"},
+		{Name: "spoofed from an untrusted peer", RemoteAddr: "203.0.113.7:5000", Forwarded: "198.51.100.1", RealIP: "198.51.100.2", Want: "203.0.113.7"},
+		{Name: "behind a proxy", RemoteAddr: "10.1.2.3:5000", Forwarded: "198.51.100.1", Want: "198.51.100.1"},
+		{Name: "spoofed through a proxy", RemoteAddr: "10.1.2.3:5000", Forwarded: "1.1.1.1, 198.51.100.1", Want: "198.51.100.1"},
+		{Name: "chained proxies", RemoteAddr: "192.0.2.1:5000", Forwarded: "198.51.100.1, 10.9.9.9", Want: "198.51.100.1"},
+		{Name: "real ip header", RemoteAddr: "10.1.2.3:5000", RealIP: "198.51.100.2", Want: "198.51.100.2"},
+		{Name: "invalid header", RemoteAddr: "10.1.2.3:5000", Forwarded: "unknown", Want: "10.1.2.3"},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/blog/1/like", nil)
+		req.RemoteAddr = test.RemoteAddr
+
+		if test.Forwarded != "" {
+			req.Header.Set("X-Forwarded-For", test.Forwarded)
+		}
+
+		if test.RealIP != "" {
+			req.Header.Set("X-Real-IP", test.RealIP)
+		}
+
+		if got := clientIP(req); got != test.Want {
+			t.Errorf("%s: clientIP = %s, want %s", test.Name, got, test.Want)
+		}
+	}
+}
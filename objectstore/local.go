@@ -0,0 +1,187 @@
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LocalStore keeps objects as files under a directory and serves
+// them through the api, uploads work without an AWS account
+type LocalStore struct {
+	root string
+	base baseURL
+}
+
+func NewLocalStore(dir string, base string) (*LocalStore, error) {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	return &LocalStore{
+		root: root,
+		base: newBaseURL(base),
+	}, nil
+}
+
+func (s *LocalStore) Put(ctx context.Context, key string, data []byte) (string, error) {
+	if !validKey(key) {
+		return "", ErrInvalidKey
+	}
+
+	name := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return "", err
+	}
+
+	// write beside the target and rename so a reader never sees
+	// a partially written file
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return "", err
+	}
+
+	return s.URL(key), nil
+}
+
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+
+	err := os.Remove(s.path(key))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (s *LocalStore) List(ctx context.Context, prefix string) ([]Object, error) {
+	objects := []Object{}
+
+	// walk the deepest directory the prefix names rather than the root
+	dir := s.root
+	if i := strings.LastIndex(prefix, "/"); i > 0 {
+		if !validKey(path.Clean(prefix[:i])) {
+			return objects, ErrInvalidKey
+		}
+		dir = s.path(path.Clean(prefix[:i]))
+	}
+
+	err := filepath.WalkDir(dir, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".upload-") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.root, name)
+		if err != nil {
+			return err
+		}
+
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		objects = append(objects, Object{
+			Key:          key,
+			Size:         info.Size(),
+			LastModified: info.ModTime(),
+		})
+
+		return nil
+	})
+	if err != nil {
+		return objects, err
+	}
+
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+
+	return objects, nil
+}
+
+func (s *LocalStore) URL(key string) string {
+	return s.base.url(key)
+}
+
+func (s *LocalStore) KeyFromURL(source string) (string, bool) {
+	return s.base.keyFromURL(source)
+}
+
+func (s *LocalStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key, ok := requestKey(r)
+	if !ok || strings.HasPrefix(path.Base(key), ".upload-") {
+		http.NotFound(w, r)
+		return
+	}
+
+	name := s.path(key)
+
+	info, err := os.Stat(name)
+	if err != nil || !info.Mode().IsRegular() {
+		http.NotFound(w, r)
+		return
+	}
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		http.Error(w, "failed to read file", http.StatusInternalServerError)
+		return
+	}
+
+	serveObject(w, r, key, data, info.ModTime())
+}
+
+// path joins a validated key onto the root directory
+func (s *LocalStore) path(key string) string {
+	return filepath.Join(s.root, filepath.FromSlash(key))
+}
@@ -0,0 +1,119 @@
+package objectstore
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryObject struct {
+	data     []byte
+	modified time.Time
+}
+
+// MemoryStore keeps objects in memory and serves them through the api
+type MemoryStore struct {
+	mu      sync.RWMutex
+	objects map[string]memoryObject
+	base    baseURL
+}
+
+func NewMemoryStore(base string) *MemoryStore {
+	return &MemoryStore{
+		objects: map[string]memoryObject{},
+		base:    newBaseURL(base),
+	}
+}
+
+func (s *MemoryStore) Put(ctx context.Context, key string, data []byte) (string, error) {
+	if !validKey(key) {
+		return "", ErrInvalidKey
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.objects[key] = memoryObject{
+		data:     append([]byte(nil), data...),
+		modified: time.Now(),
+	}
+
+	return s.URL(key), nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.objects, key)
+
+	return nil
+}
+
+func (s *MemoryStore) List(ctx context.Context, prefix string) ([]Object, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	objects := []Object{}
+	for key, object := range s.objects {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		objects = append(objects, Object{
+			Key:          key,
+			Size:         int64(len(object.data)),
+			LastModified: object.modified,
+		})
+	}
+
+	// keys in lexicographic order like an s3 listing
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+
+	return objects, nil
+}
+
+func (s *MemoryStore) URL(key string) string {
+	return s.base.url(key)
+}
+
+func (s *MemoryStore) KeyFromURL(source string) (string, bool) {
+	return s.base.keyFromURL(source)
+}
+
+// Get returns a copy of the object's data
+func (s *MemoryStore) Get(key string) ([]byte, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	object, found := s.objects[key]
+	if !found {
+		return nil, false
+	}
+
+	return append([]byte(nil), object.data...), true
+}
+
+func (s *MemoryStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key, ok := requestKey(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.mu.RLock()
+	object, found := s.objects[key]
+	s.mu.RUnlock()
+
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+
+	serveObject(w, r, key, object.data, object.modified)
+}
@@ -0,0 +1,165 @@
+package objectstore
+
+/*
+==== Object Store ================================================
+|			                                                           |
+| Storage for uploaded files behind a single interface with  	   |
+| the following backends, chosen by STORAGE_BACKEND: 	           |
+| - s3, the default, a public-read AWS bucket			             |
+|	- local, a directory served through the api                    |
+|	- memory, for tests and throwaway environments                 |
+|																						                     |
+==================================================================
+*/
+
+import (
+	"blog-api/images"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const DefaultContentType = "application/octet-stream"
+
+// FILES_PATH is where the api serves the local and memory backends
+const FILES_PATH = "/files/"
+
+const (
+	BACKEND_S3     = "s3"
+	BACKEND_LOCAL  = "local"
+	BACKEND_MEMORY = "memory"
+)
+
+const (
+	USER_PROFILE    = "uploads/users/"
+	FEATURED_IMAGES = "featured_images/users/"
+	MEDIA_LIBRARY   = "media/users/"
+	BODY_IMAGES     = "body_images/users/"
+)
+
+// UPLOAD_PREFIXES hold every object uploaded through the api
+var UPLOAD_PREFIXES = []string{FEATURED_IMAGES, USER_PROFILE, MEDIA_LIBRARY, BODY_IMAGES}
+
+var ErrInvalidKey = errors.New("invalid object key")
+
+// Object is a stored object as listed by List
+type Object struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"lastModified"`
+}
+
+type ObjectStore interface {
+	// Put stores the data under the key and returns its public url
+	Put(ctx context.Context, key string, data []byte) (string, error)
+	// Delete removes the object, a missing object is not an error
+	Delete(ctx context.Context, key string) error
+	// List returns every object whose key starts with the prefix
+	List(ctx context.Context, prefix string) ([]Object, error)
+	// URL returns the public url of the key
+	URL(key string) string
+	// KeyFromURL returns the key of an object in this store from its
+	// url, urls pointing anywhere else return false
+	KeyFromURL(source string) (string, bool)
+}
+
+/*
+FromEnv builds the backend named by STORAGE_BACKEND:
+
+	s3      AWS_REGION, AWS_BUCKET and the AWS credentials
+	local   STORAGE_LOCAL_DIR, defaults to ./uploads
+	memory  nothing, the objects are lost on restart
+
+The local and memory backends link their objects under
+STORAGE_BASE_URL, by default http://localhost:$PORT/files.
+*/
+func FromEnv(ctx context.Context) (ObjectStore, error) {
+	backend := os.Getenv("STORAGE_BACKEND")
+
+	baseURL := os.Getenv("STORAGE_BASE_URL")
+	if baseURL == "" {
+		port := os.Getenv("PORT")
+		if port == "" {
+			port = "8080"
+		}
+		baseURL = fmt.Sprintf("http://localhost:%s%s", port, strings.TrimSuffix(FILES_PATH, "/"))
+	}
+
+	switch backend {
+	case "", BACKEND_S3:
+		return NewS3Store(ctx, os.Getenv("AWS_BUCKET"), os.Getenv("AWS_REGION")), nil
+	case BACKEND_LOCAL:
+		dir := os.Getenv("STORAGE_LOCAL_DIR")
+		if dir == "" {
+			dir = "uploads"
+		}
+		return NewLocalStore(dir, baseURL)
+	case BACKEND_MEMORY:
+		return NewMemoryStore(baseURL), nil
+	}
+
+	return nil, fmt.Errorf("unknown storage backend %q, expected s3, local or memory", backend)
+}
+
+func BuildKey(dir string, authorID string, filename string) string {
+	var builder strings.Builder
+
+	builder.WriteString(dir)
+	builder.WriteString(authorID)
+	builder.WriteString("/")
+
+	escapedFilename := strings.ReplaceAll(filename, " ", "-")
+
+	builder.WriteString(escapedFilename)
+
+	return builder.String()
+}
+
+// ContentType prefers the type sniffed from the data, the key's
+// extension is only a fallback for files that aren't images
+func ContentType(key string, data []byte) string {
+	if contentType, err := images.Sniff(data); err == nil {
+		return contentType
+	}
+
+	switch strings.ToLower(path.Ext(key)) {
+	case ".jpg", ".jpeg":
+		return images.JPEG
+	case ".png":
+		return images.PNG
+	case ".gif":
+		return images.GIF
+	case ".webp":
+		return images.WEBP
+	case ".avif":
+		return images.AVIF
+	}
+
+	return DefaultContentType
+}
+
+// validKey rejects keys that would escape the store's root once
+// used as a path, such as ../ segments or absolute paths
+func validKey(key string) bool {
+	if key == "" || strings.Contains(key, "\\") || path.Clean(key) != key {
+		return false
+	}
+
+	return filepath.IsLocal(filepath.FromSlash(key))
+}
+
+// escapeKey escapes each segment of the key for use in a url path
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,150 @@
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testBaseURL = "http://localhost:8080/files"
+
+var png = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR")
+
+func testStores(t *testing.T) map[string]ObjectStore {
+	local, err := NewLocalStore(t.TempDir(), testBaseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return map[string]ObjectStore{
+		"local":  local,
+		"memory": NewMemoryStore(testBaseURL),
+	}
+}
+
+func TestStorePutListDelete(t *testing.T) {
+	ctx := context.Background()
+
+	for name, store := range testStores(t) {
+		key := BuildKey(FEATURED_IMAGES, "1", "my photo.png")
+
+		link, err := store.Put(ctx, key, png)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if link != testBaseURL+"/featured_images/users/1/my-photo.png" {
+			t.Errorf("%s: unexpected url %s", name, link)
+		}
+
+		if got, ok := store.KeyFromURL(link); !ok || got != key {
+			t.Errorf("%s: wanted key %s from the url, got %q %v", name, key, got, ok)
+		}
+
+		store.Put(ctx, BuildKey(USER_PROFILE, "1", "me.png"), png)
+
+		objects, err := store.List(ctx, FEATURED_IMAGES)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if len(objects) != 1 || objects[0].Key != key || objects[0].Size != int64(len(png)) || objects[0].LastModified.IsZero() {
+			t.Errorf("%s: wanted only %s listed, got %+v", name, key, objects)
+		}
+
+		if err := store.Delete(ctx, key); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if err := store.Delete(ctx, key); err != nil {
+			t.Errorf("%s: wanted deleting a missing object to succeed, got %v", name, err)
+		}
+
+		if objects, _ := store.List(ctx, FEATURED_IMAGES); len(objects) != 0 {
+			t.Errorf("%s: wanted nothing listed after the delete, got %+v", name, objects)
+		}
+	}
+}
+
+func TestStoreRejectsEscapingKeys(t *testing.T) {
+	for name, store := range testStores(t) {
+		for _, key := range []string{"../outside.png", "/etc/passwd", "a/../../b.png", "a\\..\\b.png", ""} {
+			if _, err := store.Put(context.Background(), key, png); !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("%s: wanted %q rejected, got %v", name, key, err)
+			}
+		}
+
+		if _, ok := store.KeyFromURL(testBaseURL + "/a/%2E%2E/%2E%2E/b.png"); ok {
+			t.Errorf("%s: wanted an escaping url rejected", name)
+		}
+
+		if _, ok := store.KeyFromURL("https://example.com/files/a.png"); ok {
+			t.Errorf("%s: wanted a url of another host rejected", name)
+		}
+	}
+}
+
+func TestStoreServesObjects(t *testing.T) {
+	for name, store := range testStores(t) {
+		store.Put(context.Background(), "body_images/users/1/a.png", png)
+
+		mux := http.NewServeMux()
+		mux.Handle("GET "+FILES_PATH, http.StripPrefix(FILES_PATH, store.(http.Handler)))
+
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		res, err := http.Get(server.URL + "/files/body_images/users/1/a.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+
+		if res.StatusCode != http.StatusOK || string(body) != string(png) {
+			t.Errorf("%s: wanted the object served, got %d", name, res.StatusCode)
+		}
+
+		if got := res.Header.Get("Content-Type"); got != "image/png" {
+			t.Errorf("%s: wanted image/png, got %s", name, got)
+		}
+
+		if res.Header.Get("X-Content-Type-Options") != "nosniff" {
+			t.Errorf("%s: wanted nosniff", name)
+		}
+
+		res, err = http.Get(server.URL + "/files/body_images/users/1/missing.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+
+		if res.StatusCode != http.StatusNotFound {
+			t.Errorf("%s: wanted 404 for a missing object, got %d", name, res.StatusCode)
+		}
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", BACKEND_MEMORY)
+	t.Setenv("STORAGE_BASE_URL", "")
+	t.Setenv("PORT", "9000")
+
+	store, err := FromEnv(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := store.URL("a.png"); got != "http://localhost:9000/files/a.png" {
+		t.Errorf("unexpected url %s", got)
+	}
+
+	t.Setenv("STORAGE_BACKEND", "ftp")
+
+	if _, err := FromEnv(context.Background()); err == nil {
+		t.Error("wanted an unknown backend rejected")
+	}
+}
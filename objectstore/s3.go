@@ -0,0 +1,160 @@
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// S3Store keeps objects in a public-read bucket, sharing one client
+// across every call
+type S3Store struct {
+	client *s3.Client
+	bucket string
+	region string
+	// set when the store could not be configured, returned by every
+	// call so the api still starts without AWS credentials
+	err error
+}
+
+func NewS3Store(ctx context.Context, bucket string, region string) *S3Store {
+	store := &S3Store{
+		bucket: bucket,
+		region: region,
+	}
+
+	if bucket == "" || region == "" || os.Getenv("AWS_ACCESS_KEY_ID") == "" || os.Getenv("AWS_SECRET_ACCESS_KEY") == "" {
+		store.err = fmt.Errorf("no s3 credentials founds")
+		return store
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		store.err = fmt.Errorf("failed to load AWS config: %w", err)
+		return store
+	}
+
+	store.client = s3.NewFromConfig(cfg)
+
+	return store
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, data []byte) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+
+	contentType := ContentType(key, data)
+	size := int64(len(data))
+
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        &s.bucket,
+		Key:           &key,
+		Body:          bytes.NewReader(data),
+		ContentLength: &size,
+		ContentType:   &contentType,
+		ACL:           types.ObjectCannedACLPublicRead,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return s.URL(key), nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: &s.bucket,
+		Key:    &key, // the sdk escapes the key itself
+	})
+
+	return err
+}
+
+func (s *S3Store) List(ctx context.Context, prefix string) ([]Object, error) {
+	objects := []Object{}
+
+	if s.err != nil {
+		return objects, s.err
+	}
+
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: &s.bucket,
+		Prefix: &prefix,
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return objects, err
+		}
+
+		for _, object := range page.Contents {
+			if object.Key == nil {
+				continue
+			}
+
+			listed := Object{Key: *object.Key}
+
+			if object.Size != nil {
+				listed.Size = *object.Size
+			}
+
+			if object.LastModified != nil {
+				listed.LastModified = *object.LastModified
+			}
+
+			objects = append(objects, listed)
+		}
+	}
+
+	return objects, nil
+}
+
+func (s *S3Store) URL(key string) string {
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.bucket, s.region, escapeKey(key))
+}
+
+/*
+KeyFromURL returns the key of an object in the bucket from its url,
+linked by the global or a regional host, virtual hosted or path
+style. Urls of other hosts and buckets return false.
+*/
+func (s *S3Store) KeyFromURL(source string) (string, bool) {
+	parsed, err := url.Parse(source)
+	if err != nil || s.bucket == "" {
+		return "", false
+	}
+
+	host := parsed.Hostname()
+	if !strings.HasSuffix(host, ".amazonaws.com") {
+		return "", false
+	}
+
+	// path is unescaped, keys are stored unescaped
+	key := strings.TrimPrefix(parsed.Path, "/")
+
+	switch {
+	case strings.HasPrefix(host, s.bucket+".s3.") || strings.HasPrefix(host, s.bucket+".s3-"):
+	case strings.HasPrefix(host, "s3.") || strings.HasPrefix(host, "s3-"):
+		if !strings.HasPrefix(key, s.bucket+"/") {
+			return "", false
+		}
+		key = strings.TrimPrefix(key, s.bucket+"/")
+	default:
+		return "", false
+	}
+
+	return key, key != ""
+}
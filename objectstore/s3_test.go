@@ -0,0 +1,61 @@
+package objectstore
+
+import "testing"
+
+func TestKeyFromURL(t *testing.T) {
+	store := &S3Store{bucket: "dev-blog-resources", region: "us-east-1"}
+
+	tests := []struct {
+		Input  string
+		Want   string
+		WantOK bool
+	}{
+		{
+			Input:  "https://dev-blog-resources.s3.amazonaws.com/canvas_1736739686719.png",
+			Want:   "canvas_1736739686719.png",
+			WantOK: true,
+		},
+		{
+			Input:  "https://dev-blog-resources.s3.amazonaws.com/featured_images/users/1/Screenshot%202025.png",
+			Want:   "featured_images/users/1/Screenshot 2025.png",
+			WantOK: true,
+		},
+		{
+			Input:  "https://dev-blog-resources.s3.us-east-1.amazonaws.com/body_images/users/1/3f2a.png",
+			Want:   "body_images/users/1/3f2a.png",
+			WantOK: true,
+		},
+		{
+			Input:  "https://s3.us-east-1.amazonaws.com/dev-blog-resources/body_images/users/1/3f2a.png",
+			Want:   "body_images/users/1/3f2a.png",
+			WantOK: true,
+		},
+		{Input: "https://other-bucket.s3.amazonaws.com/canvas.png"},
+		{Input: "https://s3.amazonaws.com/other-bucket/canvas.png"},
+		{Input: "https://dev-blog-resources.s3.amazonaws.com.evil.com/canvas.png"},
+		{Input: "https://example.com/dev-blog-resources/canvas.png"},
+	}
+
+	for _, test := range tests {
+		got, ok := store.KeyFromURL(test.Input)
+
+		if got != test.Want || ok != test.WantOK {
+			t.Errorf("%s: wanted %q %v, got %q %v", test.Input, test.Want, test.WantOK, got, ok)
+		}
+	}
+}
+
+func TestS3URLRoundTrip(t *testing.T) {
+	store := &S3Store{bucket: "dev-blog-resources", region: "us-east-1"}
+
+	key := "featured_images/users/1/café #1.png"
+	link := store.URL(key)
+
+	if link != "https://dev-blog-resources.s3.us-east-1.amazonaws.com/featured_images/users/1/caf%C3%A9%20%231.png" {
+		t.Errorf("unexpected url %s", link)
+	}
+
+	if got, ok := store.KeyFromURL(link); !ok || got != key {
+		t.Errorf("wanted %q, got %q %v", key, got, ok)
+	}
+}
@@ -0,0 +1,61 @@
+package objectstore
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// baseURL links the objects of a backend the api serves itself,
+// key a/b.png of http://localhost:8080/files is at .../files/a/b.png
+type baseURL string
+
+func newBaseURL(base string) baseURL {
+	return baseURL(strings.TrimSuffix(base, "/"))
+}
+
+func (b baseURL) url(key string) string {
+	return string(b) + "/" + escapeKey(key)
+}
+
+func (b baseURL) keyFromURL(source string) (string, bool) {
+	escaped, found := strings.CutPrefix(source, string(b)+"/")
+	if !found {
+		return "", false
+	}
+
+	// drop a query or fragment the editor may have added
+	if i := strings.IndexAny(escaped, "?#"); i >= 0 {
+		escaped = escaped[:i]
+	}
+
+	key, err := url.PathUnescape(escaped)
+	if err != nil || !validKey(key) {
+		return "", false
+	}
+
+	return key, true
+}
+
+// requestKey returns the key of a request routed with the files
+// path stripped, the mux has already cleaned the path
+func requestKey(r *http.Request) (string, bool) {
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	return key, validKey(key)
+}
+
+/*
+serveObject writes a stored object with the content type sniffed from
+its data. Uploads are validated images, but nosniff and the sandbox
+policy keep anything else from running as a page on the api's origin.
+*/
+func serveObject(w http.ResponseWriter, r *http.Request, key string, data []byte, modified time.Time) {
+	w.Header().Set("Content-Type", ContentType(key, data))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; sandbox")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+
+	http.ServeContent(w, r, "", modified, bytes.NewReader(data))
+}
@@ -0,0 +1,261 @@
+package analytics
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// how long a visitor hash is kept to tell repeat views of a day apart
+const VISITOR_RETENTION = 48 * time.Hour
+
+type AnalyticsRepository interface {
+	RecordView(ctx context.Context, view *View) error
+	GetDailyViews(ctx context.Context, q *AnalyticsQuery) ([]DailyViews, error)
+	GetTopPosts(ctx context.Context, q *AnalyticsQuery, limit int) ([]PostViews, error)
+	GetTopReferrers(ctx context.Context, q *AnalyticsQuery, limit int) ([]ReferrerViews, error)
+	GetViewsSince(ctx context.Context, since time.Time) ([]BlogDayViews, error)
+	EnsureIndexes(ctx context.Context) error
+}
+
+type MongoAnalyticsRepository struct {
+	buckets  *mongo.Collection
+	visitors *mongo.Collection
+}
+
+func NewAnalyticsRepository(db *mongo.Database) AnalyticsRepository {
+	return &MongoAnalyticsRepository{
+		buckets:  db.Collection("blogviews"),
+		visitors: db.Collection("blogviewvisitors"),
+	}
+}
+
+/*
+*
+
+	Accepts: context
+
+	Creates the bucket indexes and the unique visitor index, which
+	expires visitor hashes once their day can no longer be viewed.
+*/
+func (r *MongoAnalyticsRepository) EnsureIndexes(ctx context.Context) error {
+	buckets := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "blog", Value: 1},
+				{Key: "day", Value: 1},
+				{Key: "referrer", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "author", Value: 1},
+				{Key: "day", Value: 1},
+			},
+		},
+	}
+
+	if _, err := r.buckets.Indexes().CreateMany(ctx, buckets); err != nil {
+		return err
+	}
+
+	visitors := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "blog", Value: 1},
+				{Key: "day", Value: 1},
+				{Key: "visitor", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "createdAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(VISITOR_RETENTION.Seconds())),
+		},
+	}
+
+	_, err := r.visitors.Indexes().CreateMany(ctx, visitors)
+
+	return err
+}
+
+/*
+*
+
+	Accepts: context, view
+
+	Counts the view in the bucket of its blog, day and referrer,
+	counting it as unique when the visitor hasn't viewed the blog
+	earlier in the day.
+*/
+func (r *MongoAnalyticsRepository) RecordView(ctx context.Context, view *View) error {
+	unique := 1
+
+	_, err := r.visitors.InsertOne(ctx, bson.M{
+		"blog":      view.Blog,
+		"day":       view.Day,
+		"visitor":   view.Visitor,
+		"createdAt": time.Now(),
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		unique = 0
+	} else if err != nil {
+		return err
+	}
+
+	filter := bson.M{
+		"blog":     view.Blog,
+		"day":      view.Day,
+		"referrer": view.Referrer,
+	}
+
+	update := bson.M{
+		"$inc":         bson.M{"views": 1, "unique": unique},
+		"$setOnInsert": bson.M{"author": view.Author},
+	}
+
+	_, err = r.buckets.UpdateOne(ctx, filter, update, options.UpdateOne().SetUpsert(true))
+
+	return err
+}
+
+func (q *AnalyticsQuery) filter() bson.M {
+	filter := bson.M{
+		"author": q.Author,
+		"day":    bson.M{"$gte": q.From, "$lt": q.To},
+	}
+
+	if q.Blog != nil {
+		filter["blog"] = *q.Blog
+	}
+
+	return filter
+}
+
+/*
+*
+
+	Accepts: context, AnalyticsQuery
+
+	Sums the views of each day in the range that has any,
+	oldest day first.
+*/
+func (r *MongoAnalyticsRepository) GetDailyViews(ctx context.Context, q *AnalyticsQuery) ([]DailyViews, error) {
+	days := []DailyViews{}
+
+	err := r.aggregate(ctx, q, "$day", bson.D{{Key: "_id", Value: 1}}, 0, &days)
+
+	return days, err
+}
+
+/*
+*
+
+	Accepts: context, AnalyticsQuery, limit
+
+	Sums the views of each blog in the range, most viewed first.
+*/
+func (r *MongoAnalyticsRepository) GetTopPosts(ctx context.Context, q *AnalyticsQuery, limit int) ([]PostViews, error) {
+	posts := []PostViews{}
+
+	err := r.aggregate(ctx, q, "$blog", bson.D{{Key: "views", Value: -1}, {Key: "_id", Value: 1}}, limit, &posts)
+
+	return posts, err
+}
+
+/*
+*
+
+	Accepts: context, AnalyticsQuery, limit
+
+	Sums the views from each referrer domain in the range, most
+	viewed first. Direct views have no referrer and are left out.
+*/
+func (r *MongoAnalyticsRepository) GetTopReferrers(ctx context.Context, q *AnalyticsQuery, limit int) ([]ReferrerViews, error) {
+	referrers := []ReferrerViews{}
+
+	notDirect := bson.M{"referrer": bson.M{"$ne": ""}}
+	err := r.aggregate(ctx, q, "$referrer", bson.D{{Key: "views", Value: -1}, {Key: "_id", Value: 1}}, limit, &referrers, notDirect)
+
+	return referrers, err
+}
+
+// aggregate sums the views and unique views of the query's buckets grouped by key
+func (r *MongoAnalyticsRepository) aggregate(ctx context.Context, q *AnalyticsQuery, key string, sort bson.D, limit int, results any, additionalFilters ...bson.M) error {
+	filter := q.filter()
+
+	// combine filters
+	for _, additional := range additionalFilters {
+		for v := range additional {
+			filter[v] = additional[v]
+		}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    key,
+			"views":  bson.M{"$sum": "$views"},
+			"unique": bson.M{"$sum": "$unique"},
+		}}},
+		{{Key: "$sort", Value: sort}},
+	}
+
+	if limit > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: limit}})
+	}
+
+	cursor, err := r.buckets.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+
+	defer cursor.Close(ctx)
+
+	return cursor.All(ctx, results)
+}
+
+/*
+*
+
+	Accepts: context, since
+
+	Sums the views of every blog for each day from the day of
+	since onwards, across all authors and referrers.
+*/
+func (r *MongoAnalyticsRepository) GetViewsSince(ctx context.Context, since time.Time) ([]BlogDayViews, error) {
+	views := []BlogDayViews{}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"day": bson.M{"$gte": since.UTC().Truncate(24 * time.Hour)}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    bson.M{"blog": "$blog", "day": "$day"},
+			"views":  bson.M{"$sum": "$views"},
+			"unique": bson.M{"$sum": "$unique"},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":    0,
+			"blog":   "$_id.blog",
+			"day":    "$_id.day",
+			"views":  1,
+			"unique": 1,
+		}}},
+	}
+
+	cursor, err := r.buckets.Aggregate(ctx, pipeline)
+	if err != nil {
+		return views, err
+	}
+
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &views); err != nil {
+		return views, err
+	}
+
+	return views, nil
+}
@@ -0,0 +1,87 @@
+package analytics
+
+import (
+	br "blog-api/repositories/blog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// View is a single view of a published blog by a reader who isn't a bot
+type View struct {
+	Blog     bson.ObjectID
+	Author   bson.ObjectID
+	Day      time.Time // start of the UTC day of the view
+	Visitor  string    // hash of the visitor identity and the day, never a raw ip
+	Referrer string    // domain the reader came from, empty when direct
+}
+
+// ViewBucket counts the views of a blog from a referrer during a day
+type ViewBucket struct {
+	ID       bson.ObjectID `bson:"_id,omitempty"`
+	Blog     bson.ObjectID `bson:"blog"`
+	Author   bson.ObjectID `bson:"author"`
+	Day      time.Time     `bson:"day"`
+	Referrer string        `bson:"referrer"`
+	Views    int           `bson:"views"`
+	Unique   int           `bson:"unique"`
+}
+
+// BlogDayViews sums the views of a blog during a day
+type BlogDayViews struct {
+	Blog   bson.ObjectID `bson:"blog"`
+	Day    time.Time     `bson:"day"`
+	Views  int           `bson:"views"`
+	Unique int           `bson:"unique"`
+}
+
+// AnalyticsQuery selects the author's buckets, of a single blog when Blog is set
+type AnalyticsQuery struct {
+	Author bson.ObjectID
+	Blog   *bson.ObjectID
+	From   time.Time
+	To     time.Time // exclusive
+}
+
+type DailyViews struct {
+	Day    time.Time `bson:"_id" json:"day"`
+	Views  int       `bson:"views" json:"views"`
+	Unique int       `bson:"unique" json:"unique"`
+}
+
+type PostViews struct {
+	Blog   bson.ObjectID `bson:"_id" json:"_id"`
+	Title  string        `bson:"-" json:"title"`
+	Slug   string        `bson:"-" json:"slug"`
+	Views  int           `bson:"views" json:"views"`
+	Unique int           `bson:"unique" json:"unique"`
+}
+
+type ReferrerViews struct {
+	Domain string `bson:"_id" json:"domain"`
+	Views  int    `bson:"views" json:"views"`
+	Unique int    `bson:"unique" json:"unique"`
+}
+
+// AnalyticsResponse totals the views of the range, Unique sums
+// the unique visitors of each day
+type AnalyticsResponse struct {
+	From         time.Time       `json:"from"`
+	To           time.Time       `json:"to"`
+	Views        int             `json:"views"`
+	Unique       int             `json:"unique"`
+	Days         []DailyViews    `json:"days"`
+	TopPosts     []PostViews     `json:"topPosts"`
+	TopReferrers []ReferrerViews `json:"topReferrers"`
+}
+
+type TrendingBlog struct {
+	br.BlogMinimum
+	Score float64 `json:"score"`
+}
+
+type TrendingResponse struct {
+	Window    string         `json:"window"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+	Blogs     []TrendingBlog `json:"blogs"`
+}
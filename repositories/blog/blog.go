@@ -4,7 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"regexp"
+	"slices"
 	"time"
 
 	ck "blog-api/contextkeys"
@@ -14,8 +14,11 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
+// name of the weighted text index over title, categories and searchText
+const SEARCH_INDEX = "blog_search"
+
 type BlogRepository interface {
-	GetBlogIndex(ctx context.Context, q *BlogQuery) ([]BlogMinimum, bool, error)
+	GetBlogIndex(ctx context.Context, q *BlogQuery) ([]BlogMinimum, BlogPage, error)
 	GetBlogBySlug(ctx context.Context, slug string) (*BlogWithAuthor, error)
 	GetBlogById(ctx context.Context, id bson.ObjectID) (*Blog, error)
 	GetBlogByIdAndAuthor(ctx context.Context, id, author bson.ObjectID) (*Blog, error)
@@ -24,18 +27,36 @@ type BlogRepository interface {
 	GetPreviousDraft(ctx context.Context, id bson.ObjectID) (*BlogMinimum, error)
 	GetNextDraft(ctx context.Context, id bson.ObjectID) (*BlogMinimum, error)
 	GetRandomBlog(ctx context.Context) (*BlogWithAuthor, error)
-	GetBlogsByCategory(ctx context.Context, category string, q *BlogQuery) ([]BlogMinimum, bool, error)
-	GetBlogsBySearchQuery(ctx context.Context, searchQuery string, q *BlogQuery) ([]BlogMinimum, bool, error)
-	GetDraftsByUser(ctx context.Context, q *BlogQuery) ([]BlogMinimum, bool, error)
-	LikeBlog(ctx context.Context, id string) (*Blog, error)
+	GetBlogsByCategory(ctx context.Context, category string, q *BlogQuery) ([]BlogMinimum, BlogPage, error)
+	GetBlogsBySearchQuery(ctx context.Context, searchQuery string, q *BlogQuery) ([]BlogSearchResult, BlogPage, error)
+	GetDraftsByUser(ctx context.Context, q *BlogQuery) ([]BlogMinimum, BlogPage, error)
+	UpdateRating(ctx context.Context, id bson.ObjectID, delta int) (*Blog, error)
 	IncrementViewCount(slug string)
 	UpdateBlog(ctx context.Context, input *UpdateBlogInput) (*Blog, error)
 	ClearBlogFields(ctx context.Context, blogInput, additionalFilters bson.M) (int, error)
 	ValidateSlug(ctx context.Context, slug string) (bool, error)
+	GetSlugRedirect(ctx context.Context, slug string) (*BlogSlug, error)
 	CreateBlog(ctx context.Context, input *CreateBlogInput) (*Blog, error)
 	DeleteBlog(ctx context.Context, id, author bson.ObjectID) (int, error)
 	GetDraftByUser(ctx context.Context, slug string) (*BlogWithAuthor, error)
-	GetBlogsByUser(ctx context.Context, q *BlogQuery, userID bson.ObjectID) ([]BlogMinimum, bool, error)
+	GetBlogsByUser(ctx context.Context, q *BlogQuery, userID bson.ObjectID) ([]BlogMinimum, BlogPage, error)
+	PublishNextScheduledBlog(ctx context.Context, now time.Time) (*BlogMinimum, error)
+	GetBlogsByIDs(ctx context.Context, ids []bson.ObjectID, additionalFilters bson.M) ([]BlogMinimum, error)
+	GetCategories(ctx context.Context) ([]CategorySummary, error)
+	ReplaceCategories(ctx context.Context, author bson.ObjectID, from []string, to string) (int, error)
+	NormalizeStoredCategories(ctx context.Context) (int, error)
+	GetBlogFeed(ctx context.Context, q *FeedQuery) ([]BlogWithAuthor, error)
+	GetPublishedSlugs(ctx context.Context) ([]BlogSlug, error)
+	GetImageReferences(ctx context.Context, keys []string) ([]ImageReference, error)
+	GetAuthors(ctx context.Context) ([]AuthorSummary, error)
+	EnsureIndexes(ctx context.Context) error
+	GetBlogsWithoutSearchText(ctx context.Context) ([]Blog, error)
+	SetSearchText(ctx context.Context, id bson.ObjectID, searchText string) error
+	GetSuggestionSources(ctx context.Context) ([]SuggestionSource, error)
+	GetRelatedSources(ctx context.Context) ([]RelatedSource, error)
+	GetBlogTexts(ctx context.Context) ([]Blog, error)
+	GetImageUsage(ctx context.Context) ([]Blog, error)
+	SetDerivedFields(ctx context.Context, id bson.ObjectID, input *BaseBlogInput) error
 }
 
 type MongoBlogRepository struct {
@@ -53,72 +74,54 @@ func NewBlogRepository(db *mongo.Database) BlogRepository {
 
 	Accepts: context, query
 
-	Takes the provided offset and looks up 10 blogpost documents and returns the slice
-	along with a bool indicating if there are any additional blogs available after the
-	provided offset.
+	Takes the provided cursor or offset and looks up 10 blogpost documents and returns
+	the slice along with the page, indicating if there are any additional blogs
+	available after it and the cursors either side.
 */
-func (r *MongoBlogRepository) GetBlogIndex(ctx context.Context, q *BlogQuery) ([]BlogMinimum, bool, error) {
-	limit := 10
-	var blogs []BlogMinimum
-
+func (r *MongoBlogRepository) GetBlogIndex(ctx context.Context, q *BlogQuery) ([]BlogMinimum, BlogPage, error) {
 	filter := bson.M{"published": true}
 
-	opts := options.Find().
-		SetSort(bson.M{"createdAt": -1}).
-		SetLimit(int64(limit)).
-		SetSkip(int64(q.Offset))
-
-	cursor, err := r.collection.Find(ctx, filter, opts)
-	if err != nil {
-		return blogs, false, err
-	}
-
-	defer cursor.Close(ctx)
+	return r.findBlogPage(ctx, filter, q)
+}
 
-	if err := cursor.All(ctx, &blogs); err != nil {
-		return blogs, false, err
-	}
+func (r *MongoBlogRepository) GetBlogsByUser(ctx context.Context, q *BlogQuery, userID bson.ObjectID) ([]BlogMinimum, BlogPage, error) {
+	filter := bson.M{"published": true, "author": userID}
 
-	totalDocuments, err := r.collection.CountDocuments(ctx, filter)
-	if err != nil {
-		return blogs, false, err
-	}
+	return r.findBlogPage(ctx, filter, q)
+}
 
-	hasMore := q.Offset+limit < int(totalDocuments)
+/*
+*
 
-	return blogs, hasMore, nil
-}
+	Accepts: context, ids, additionalFilters
 
-func (r *MongoBlogRepository) GetBlogsByUser(ctx context.Context, q *BlogQuery, userID bson.ObjectID) ([]BlogMinimum, bool, error) {
-	limit := 10
-	var blogs []BlogMinimum
+	Looks up the blogs with the provided IDs that also match the
+	additional filters. Results are in no particular order.
+*/
+func (r *MongoBlogRepository) GetBlogsByIDs(ctx context.Context, ids []bson.ObjectID, additionalFilters bson.M) ([]BlogMinimum, error) {
+	blogs := []BlogMinimum{}
 
-	filter := bson.M{"published": true, "author": userID}
+	filter := bson.M{
+		"_id": bson.M{"$in": ids},
+	}
 
-	opts := options.Find().
-		SetSort(bson.M{"createdAt": -1}).
-		SetLimit(int64(limit)).
-		SetSkip(int64(q.Offset))
+	// combine filters
+	for v := range additionalFilters {
+		filter[v] = additionalFilters[v]
+	}
 
-	cursor, err := r.collection.Find(ctx, filter, opts)
+	cursor, err := r.collection.Find(ctx, filter)
 	if err != nil {
-		return blogs, false, err
+		return blogs, err
 	}
 
 	defer cursor.Close(ctx)
 
 	if err := cursor.All(ctx, &blogs); err != nil {
-		return blogs, false, err
-	}
-
-	totalDocuments, err := r.collection.CountDocuments(ctx, filter)
-	if err != nil {
-		return blogs, false, err
+		return blogs, err
 	}
 
-	hasMore := q.Offset+limit < int(totalDocuments)
-
-	return blogs, hasMore, nil
+	return blogs, nil
 }
 
 func (r *MongoBlogRepository) GetBlogById(ctx context.Context, id bson.ObjectID) (*Blog, error) {
@@ -234,7 +237,8 @@ func (r *MongoBlogRepository) getBlogWithPipeline(ctx context.Context, pipeline
 
 	Accepts: context, slug
 
-	Looks up a blog by the provided slug
+	Looks up a published blog by the provided slug, returning nil when
+	no published blog currently has it.
 */
 func (r *MongoBlogRepository) GetBlogBySlug(ctx context.Context, slug string) (*BlogWithAuthor, error) {
 	var blog *BlogWithAuthor
@@ -267,6 +271,10 @@ func (r *MongoBlogRepository) GetBlogBySlug(ctx context.Context, slug string) (*
 
 	blog, err := r.getBlogWithPipeline(ctx, pipeline)
 	if err != nil {
+		// an unknown slug may be one the blog has retired
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
 		return blog, err
 	}
 
@@ -356,45 +364,19 @@ func (r *MongoBlogRepository) IncrementViewCount(slug string) {
 	Accepts: context, category, BlogQuery
 
 	Parses comma seperated category value from url path
-	and looks up blogs which contain all of the provided input.
+	and looks up blogs which contain all, or with the any
+	category match any, of the provided input.
 	Returns the found blogs and if the collection contains more
-	after the provided offset.
+	after the provided cursor or offset.
 */
-func (r *MongoBlogRepository) GetBlogsByCategory(ctx context.Context, category string, q *BlogQuery) ([]BlogMinimum, bool, error) {
-	limit := 10
-	var blogs []BlogMinimum
-
-	categorySlice := splitAndTrim(category)
-
-	filter := bson.M{
-		"categories": bson.M{"$all": categorySlice},
-		"published":  true,
-	}
-
-	opts := options.Find().
-		SetSort(bson.M{"createdAt": -1}).
-		SetLimit(int64(limit)).
-		SetSkip(int64(q.Offset))
-
-	cursor, err := r.collection.Find(ctx, filter, opts)
-	if err != nil {
-		return blogs, false, err
-	}
-
-	defer cursor.Close(ctx)
-
-	if err = cursor.All(ctx, &blogs); err != nil {
-		return blogs, false, err
-	}
-
-	totalDocuments, err := r.collection.CountDocuments(ctx, filter)
-	if err != nil {
-		return blogs, false, err
-	}
+func (r *MongoBlogRepository) GetBlogsByCategory(ctx context.Context, category string, q *BlogQuery) ([]BlogMinimum, BlogPage, error) {
+	filter := bson.M{"published": true}
 
-	hasMore := q.Offset+limit < int(totalDocuments)
+	// the path categories replace any from the query params
+	categoryQuery := *q
+	categoryQuery.Categories = splitAndTrim(category)
 
-	return blogs, hasMore, nil
+	return r.findBlogPage(ctx, filter, &categoryQuery)
 }
 
 /*
@@ -402,16 +384,12 @@ func (r *MongoBlogRepository) GetBlogsByCategory(ctx context.Context, category s
 
 	Accepts: context, BlogQuery
 
-	Lookup drafts for a provided offset and userID
+	Lookup drafts for a provided cursor or offset and userID
 	which will be extracted from a verified token.
 	A request with an invalid token or non-existent
 	userID will not reach this repository method.
 */
-func (r *MongoBlogRepository) GetDraftsByUser(ctx context.Context, q *BlogQuery) ([]BlogMinimum, bool, error) {
-	// lots of repeated code - see what i can do about that...
-	limit := 10
-	var blogs []BlogMinimum
-
+func (r *MongoBlogRepository) GetDraftsByUser(ctx context.Context, q *BlogQuery) ([]BlogMinimum, BlogPage, error) {
 	// maybe store the userID in request context
 	// as ObjectID without converting via .Hex()
 	// then just convert it when needed since in
@@ -419,12 +397,12 @@ func (r *MongoBlogRepository) GetDraftsByUser(ctx context.Context, q *BlogQuery)
 	// in its original form
 	userID, ok := ctx.Value(ck.UserIDKey).(string)
 	if !ok {
-		return blogs, false, errors.New("failed to access context values")
+		return []BlogMinimum{}, BlogPage{}, errors.New("failed to access context values")
 	}
 
 	userObjectID, err := bson.ObjectIDFromHex(userID)
 	if err != nil {
-		return blogs, false, err
+		return []BlogMinimum{}, BlogPage{}, err
 	}
 
 	filter := bson.M{
@@ -432,30 +410,7 @@ func (r *MongoBlogRepository) GetDraftsByUser(ctx context.Context, q *BlogQuery)
 		"author":    userObjectID,
 	}
 
-	opts := options.Find().
-		SetSort(bson.M{"createdAt": -1}).
-		SetLimit(int64(limit)).
-		SetSkip(int64(q.Offset))
-
-	cursor, err := r.collection.Find(ctx, filter, opts)
-	if err != nil {
-		return blogs, false, err
-	}
-
-	defer cursor.Close(ctx)
-
-	if err = cursor.All(ctx, &blogs); err != nil {
-		return blogs, false, err
-	}
-
-	totalDocuments, err := r.collection.CountDocuments(ctx, filter)
-	if err != nil {
-		return blogs, false, err
-	}
-
-	hasMore := q.Offset+limit < int(totalDocuments)
-
-	return blogs, hasMore, nil
+	return r.findBlogPage(ctx, filter, q)
 }
 
 func (r *MongoBlogRepository) GetDraftByUser(ctx context.Context, slug string) (*BlogWithAuthor, error) {
@@ -567,68 +522,223 @@ func (r *MongoBlogRepository) GetPreviousDraft(ctx context.Context, id bson.Obje
 	return blog, nil
 }
 
-func (r *MongoBlogRepository) GetBlogsBySearchQuery(ctx context.Context, searchQuery string, q *BlogQuery) ([]BlogMinimum, bool, error) {
-	limit := 10
-	var blogs []BlogMinimum
+func (r *MongoBlogRepository) GetBlogsBySearchQuery(ctx context.Context, searchQuery string, q *BlogQuery) ([]BlogSearchResult, BlogPage, error) {
+	blogs := []BlogSearchResult{}
+	sort := q.sortOrder(true)
 
-	escapedQuery := fmt.Sprintf(`\b%s\b`, regexp.QuoteMeta(searchQuery))
+	c, err := decodeCursor(q.Cursor, sort)
+	if err != nil {
+		return blogs, BlogPage{}, err
+	}
 
-	opts := options.Find().
-		SetLimit(int64(limit)).
-		SetSkip(int64(q.Offset))
+	// $text supports multiple words and "quoted phrases",
+	// weights on the search index rank title matches first.
+	// text search can't use a collation so categories are
+	// matched in their normalized form
+	filter, _ := listingFilter(bson.M{
+		"published": true,
+		"$text":     bson.M{"$search": searchQuery},
+	}, q)
 
-	filter := bson.M{
-		"$and": []bson.M{
-			{"published": true},
-			{
-				"$or": []bson.M{
-					{"text": bson.M{"$regex": escapedQuery, "$options": "i"}},
-					{"title": bson.M{"$regex": escapedQuery, "$options": "i"}},
-					{"categories": bson.M{"$regex": escapedQuery, "$options": "i"}},
-				},
-			},
+	pipeline := mongo.Pipeline{
+		{
+			{Key: "$match", Value: filter},
+		},
+
+		{
+			{Key: "$addFields", Value: bson.M{"score": bson.M{"$meta": "textScore"}}},
 		},
 	}
 
-	cursor, err := r.collection.Find(ctx, filter, opts)
+	if c != nil {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: keysetFilter(c)}})
+	}
+
+	pipeline = append(pipeline, bson.D{{Key: "$sort", Value: pageSort(sort, c)}})
+
+	if c == nil && q.Offset > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$skip", Value: q.Offset}})
+	}
+
+	pipeline = append(pipeline,
+		bson.D{{Key: "$limit", Value: q.pageSize() + 1}},
+		bson.D{{Key: "$project", Value: bson.M{
+			"title":                 1,
+			"slug":                  1,
+			"views":                 1,
+			"rating":                1,
+			"featuredImageLocation": 1,
+			"featuredImageVariants": 1,
+			"featuredImageSrcset":   1,
+			"excerpt":               1,
+			"wordCount":             1,
+			"readingTime":           1,
+			"createdAt":             1,
+			"searchText":            1,
+			"score":                 1,
+		}}},
+	)
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
 	if err != nil {
-		return blogs, false, err
+		return blogs, BlogPage{}, err
 	}
 
 	defer cursor.Close(ctx)
 
 	if err = cursor.All(ctx, &blogs); err != nil {
-		return blogs, false, err
+		return blogs, BlogPage{}, err
+	}
+
+	blogs, page := buildPage(blogs, q, c, searchCursor(sort))
+
+	return blogs, page, nil
+}
+
+/*
+*
+
+	Accepts: context
+
+	Creates the indexes listings and search rely on. The text index
+	weighs title matches the most, then categories, then the body,
+	the others back the keysets listings page by for each sort and
+	the lookup of retired slugs. Creating an index that already
+	exists is a no-op.
+*/
+func (r *MongoBlogRepository) EnsureIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "title", Value: "text"},
+				{Key: "categories", Value: "text"},
+				{Key: "searchText", Value: "text"},
+			},
+			Options: options.Index().
+				SetName(SEARCH_INDEX).
+				SetWeights(bson.M{
+					"title":      10,
+					"categories": 5,
+					"searchText": 1,
+				}),
+		},
+		{
+			Keys: bson.D{
+				{Key: "published", Value: 1},
+				{Key: "createdAt", Value: -1},
+				{Key: "_id", Value: -1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "author", Value: 1},
+				{Key: "published", Value: 1},
+				{Key: "createdAt", Value: -1},
+				{Key: "_id", Value: -1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "published", Value: 1},
+				{Key: "views", Value: -1},
+				{Key: "createdAt", Value: -1},
+				{Key: "_id", Value: -1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "published", Value: 1},
+				{Key: "rating", Value: -1},
+				{Key: "createdAt", Value: -1},
+				{Key: "_id", Value: -1},
+			},
+		},
+		{
+			Keys: bson.D{{Key: "previousSlugs", Value: 1}},
+		},
 	}
 
-	totalDocuments, err := r.collection.CountDocuments(ctx, filter)
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
+
+	return err
+}
+
+/*
+*
+
+	Accepts: context
+
+	Returns blogs saved before search text was stored,
+	with only the fields needed to fill it in.
+*/
+func (r *MongoBlogRepository) GetBlogsWithoutSearchText(ctx context.Context) ([]Blog, error) {
+	blogs := []Blog{}
+
+	filter := bson.M{"searchText": bson.M{"$exists": false}}
+
+	opts := options.Find().SetProjection(bson.M{"text": 1})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
 	if err != nil {
-		return blogs, false, err
+		return blogs, err
 	}
 
-	hasMore := q.Offset+limit < int(totalDocuments)
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &blogs); err != nil {
+		return blogs, err
+	}
+
+	return blogs, nil
+}
+
+/*
+*
+
+	Accepts: context, blog id, search text
+
+	Stores the plain text used by the search index without
+	touching the blog's updatedAt.
+*/
+func (r *MongoBlogRepository) SetSearchText(ctx context.Context, id bson.ObjectID, searchText string) error {
+	filter := bson.M{"_id": id}
+	update := bson.M{"$set": bson.M{"searchText": searchText}}
+
+	_, err := r.collection.UpdateOne(ctx, filter, update)
 
-	return blogs, hasMore, nil
+	return err
 }
 
-func (r *MongoBlogRepository) LikeBlog(ctx context.Context, id string) (*Blog, error) {
+/*
+*
+
+	Accepts: context, id (document ObjectID), delta
+
+	Adds delta to the blog's rating, never taking it below zero,
+	and returns the updated document or nil when no blog matched.
+*/
+func (r *MongoBlogRepository) UpdateRating(ctx context.Context, id bson.ObjectID, delta int) (*Blog, error) {
 	var blog *Blog
 
-	postID, err := bson.ObjectIDFromHex(id)
-	if err != nil {
-		return blog, err
+	filter := bson.M{"_id": id}
+	if delta < 0 {
+		filter["rating"] = bson.M{"$gte": -delta}
 	}
 
-	filter := bson.M{"_id": postID}
-	update := bson.M{"$inc": bson.M{"rating": 1}}
+	update := bson.M{"$inc": bson.M{"rating": delta}}
 
-	err = r.collection.FindOneAndUpdate(
+	err := r.collection.FindOneAndUpdate(
 		ctx,
 		filter,
 		update,
 		options.FindOneAndUpdate().SetReturnDocument(options.After),
 	).Decode(&blog)
-	if err != nil {
+	if err == mongo.ErrNoDocuments && delta < 0 {
+		// the rating is already at zero
+		return r.GetBlogById(ctx, id)
+	}
+
+	if err != nil && err != mongo.ErrNoDocuments {
 		return blog, err
 	}
 
@@ -656,6 +766,24 @@ func (r *MongoBlogRepository) UpdateBlog(ctx context.Context, input *UpdateBlogI
 		"author": hexAuthorID,
 	}
 
+	update := blogUpdate(input)
+
+	err = r.collection.FindOneAndUpdate(
+		ctx,
+		filter,
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&blog)
+	if err != nil {
+		return blog, err
+	}
+
+	return blog, nil
+}
+
+// blogUpdate builds the update document of an edit, only the
+// provided fields change
+func blogUpdate(input *UpdateBlogInput) bson.M {
 	updateFields := bson.M{}
 
 	if len(input.Categories) > 0 {
@@ -664,6 +792,21 @@ func (r *MongoBlogRepository) UpdateBlog(ctx context.Context, input *UpdateBlogI
 
 	if input.Text != "" {
 		updateFields["text"] = input.Text
+		updateFields["searchText"] = input.SearchText
+		updateFields["excerpt"] = input.Excerpt
+		updateFields["wordCount"] = input.WordCount
+		updateFields["readingTime"] = input.ReadingTime
+		updateFields["toc"] = input.TOC
+	}
+
+	unsetFields := bson.M{}
+
+	if input.Markdown != "" {
+		updateFields["markdown"] = input.Markdown
+		updateFields["format"] = input.Format
+	} else if input.Text != "" {
+		updateFields["format"] = input.Format
+		unsetFields["markdown"] = ""
 	}
 
 	if input.Title != "" {
@@ -672,6 +815,8 @@ func (r *MongoBlogRepository) UpdateBlog(ctx context.Context, input *UpdateBlogI
 
 	if input.ImageLocation != "" {
 		updateFields["featuredImageLocation"] = input.ImageLocation
+		updateFields["featuredImageVariants"] = input.ImageVariants
+		updateFields["featuredImageSrcset"] = input.ImageSrcset
 	}
 
 	if input.ImageKey != "" {
@@ -679,46 +824,90 @@ func (r *MongoBlogRepository) UpdateBlog(ctx context.Context, input *UpdateBlogI
 	}
 
 	if input.Slug != "" {
-		updateFields["slug"] = input.ImageKey
+		updateFields["slug"] = input.Slug
+		updateFields["previousSlugs"] = input.PreviousSlugs
 	}
 
 	updateFields["published"] = input.Published
 
+	// feeds, the sitemap and author listings report the last edit
+	updateFields["updatedAt"] = time.Now()
+
+	// a schedule only applies to unpublished blogs, an edit that
+	// doesn't resend the time keeps the stored schedule
+	switch {
+	case input.Published || input.ClearPublishAt:
+		unsetFields["publishAt"] = ""
+	case input.PublishAt != nil:
+		updateFields["publishAt"] = input.PublishAt
+	}
+
 	// $set updates only the provided fields
 	update := bson.M{"$set": updateFields}
 
-	err = r.collection.FindOneAndUpdate(
-		ctx,
-		filter,
-		update,
-		options.FindOneAndUpdate().SetReturnDocument(options.After),
-	).Decode(&blog)
-	if err != nil {
-		return blog, err
+	// older servers reject an empty $unset
+	if len(unsetFields) > 0 {
+		update["$unset"] = unsetFields
 	}
 
-	return blog, nil
+	return update
 }
 
+/*
+*
+
+	Accepts: context, slug
+
+	Reports whether no blog uses the slug, now or as one of its
+	retired slugs, which stay reserved for their redirects.
+*/
 func (r *MongoBlogRepository) ValidateSlug(ctx context.Context, slug string) (bool, error) {
 	var blog *Blog
 
-	filter := bson.M{"slug": slug}
+	filter := bson.M{
+		"$or": []bson.M{
+			{"slug": slug},
+			{"previousSlugs": slug},
+		},
+	}
 
 	err := r.collection.FindOne(ctx, filter).Decode(&blog)
-
-	fmt.Println("the error: ", err)
 	if err == mongo.ErrNoDocuments {
 		return true, nil
 	}
 
-	if !blog.ID.IsZero() && err == nil {
+	if err == nil && !blog.ID.IsZero() {
 		return false, nil
 	}
 
 	return false, err
 }
 
+/*
+*
+
+	Accepts: context, slug
+
+	Looks up the published blog that retired the slug, returning
+	nil when no blog used to have it.
+*/
+func (r *MongoBlogRepository) GetSlugRedirect(ctx context.Context, slug string) (*BlogSlug, error) {
+	var blog *BlogSlug
+
+	filter := bson.M{
+		"previousSlugs": slug,
+		"published":     true,
+	}
+
+	if err := r.collection.FindOne(ctx, filter).Decode(&blog); err != nil {
+		if err != mongo.ErrNoDocuments {
+			return blog, err
+		}
+	}
+
+	return blog, nil
+}
+
 func (r *MongoBlogRepository) CreateBlog(ctx context.Context, input *CreateBlogInput) (*Blog, error) {
 	// Extract and validate the author ID from the context
 	authorID, ok := ctx.Value(ck.UserIDKey).(string)
@@ -738,11 +927,21 @@ func (r *MongoBlogRepository) CreateBlog(ctx context.Context, input *CreateBlogI
 	blog := &Blog{
 		Author:        hexAuthorID,
 		Published:     input.Published,
+		PublishAt:     input.PublishAt,
 		Categories:    input.Categories,
 		Text:          input.Text,
+		Markdown:      input.Markdown,
+		Format:        input.Format,
+		SearchText:    input.SearchText,
+		Excerpt:       input.Excerpt,
+		WordCount:     input.WordCount,
+		ReadingTime:   input.ReadingTime,
+		TOC:           input.TOC,
 		Title:         input.Title,
 		ImageLocation: input.ImageLocation,
 		ImageKey:      input.ImageKey,
+		ImageVariants: input.ImageVariants,
+		ImageSrcset:   input.ImageSrcset,
 		Slug:          input.Slug,
 		CreatedAt:     now,
 		UpdatedAt:     now,
@@ -812,3 +1011,578 @@ func (r *MongoBlogRepository) DeleteBlog(ctx context.Context, id, author bson.Ob
 
 	return int(result.DeletedCount), nil
 }
+
+/*
+*
+
+	Accepts: context, now
+
+	Atomically claims and publishes a single scheduled blog whose
+	publishAt time has passed. The filter only matches unpublished
+	documents so when several replicas run the publisher a blog is
+	only ever flipped by one of them. The blog is dated to when it
+	went live, listings, feeds and pagination order by createdAt so
+	a blog drafted weeks ago still appears first. Returns nil once no
+	scheduled blogs are due.
+*/
+func (r *MongoBlogRepository) PublishNextScheduledBlog(ctx context.Context, now time.Time) (*BlogMinimum, error) {
+	var blog *BlogMinimum
+
+	filter := bson.M{
+		"published": false,
+		"publishAt": bson.M{"$lte": now},
+	}
+
+	update := bson.M{
+		"$set":   bson.M{"published": true, "createdAt": now, "updatedAt": now},
+		"$unset": bson.M{"publishAt": ""},
+	}
+
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.M{"publishAt": 1}).
+		SetReturnDocument(options.After)
+
+	if err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&blog); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return blog, err
+	}
+
+	return blog, nil
+}
+
+/*
+*
+
+	Accepts: context
+
+	Groups the categories of every published blog as NormalizeCategory
+	leaves them and returns each with its post count and the date of its most
+	recent post, most used categories first.
+*/
+func (r *MongoBlogRepository) GetCategories(ctx context.Context) ([]CategorySummary, error) {
+	groups := []categoryGroup{}
+
+	// grouped as stored, the spellings are merged in go
+	pipeline := mongo.Pipeline{
+		{
+			{Key: "$match", Value: bson.M{"published": true}},
+		},
+
+		{
+			{Key: "$unwind", Value: "$categories"},
+		},
+
+		{
+			{
+				Key: "$group", Value: bson.M{
+					"_id":        "$categories",
+					"posts":      bson.M{"$addToSet": "$_id"},
+					"latestPost": bson.M{"$max": "$createdAt"},
+				},
+			},
+		},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return []CategorySummary{}, err
+	}
+
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &groups); err != nil {
+		return []CategorySummary{}, err
+	}
+
+	return mergeCategoryGroups(groups), nil
+}
+
+/*
+*
+
+	Accepts: context, author (user ObjectID), from, to
+
+	Replaces every category in from with to across the author's
+	blogs. Categories are matched as NormalizeCategory leaves them,
+	lowercase with their whitespace collapsed, and each affected
+	blog's categories are normalized with duplicates removed,
+	keeping their order.
+*/
+func (r *MongoBlogRepository) ReplaceCategories(ctx context.Context, author bson.ObjectID, from []string, to string) (int, error) {
+	blogs, err := r.getBlogCategories(ctx, bson.M{"author": author})
+	if err != nil {
+		return 0, err
+	}
+
+	affected := 0
+	for _, blog := range blogs {
+		categories, found := replaceCategories(blog.Categories, from, to)
+		if !found {
+			continue
+		}
+
+		if err := r.setCategories(ctx, blog.ID, categories); err != nil {
+			return affected, err
+		}
+
+		affected++
+	}
+
+	return affected, nil
+}
+
+/*
+*
+
+	Accepts: context
+
+	Normalizes the stored categories of every blog saved before
+	categories were normalized on write, so listings matching them
+	with a collation also find categories with extra whitespace.
+	Returns the number of blogs changed.
+*/
+func (r *MongoBlogRepository) NormalizeStoredCategories(ctx context.Context) (int, error) {
+	blogs, err := r.getBlogCategories(ctx, bson.M{"categories": bson.M{"$exists": true}})
+	if err != nil {
+		return 0, err
+	}
+
+	normalized := 0
+	for _, blog := range blogs {
+		categories := NormalizeCategories(blog.Categories)
+		if slices.Equal(categories, blog.Categories) {
+			continue
+		}
+
+		if err := r.setCategories(ctx, blog.ID, categories); err != nil {
+			return normalized, err
+		}
+
+		normalized++
+	}
+
+	return normalized, nil
+}
+
+// getBlogCategories looks up the stored categories of the matched blogs
+func (r *MongoBlogRepository) getBlogCategories(ctx context.Context, filter bson.M) ([]blogCategories, error) {
+	blogs := []blogCategories{}
+
+	opts := options.Find().SetProjection(bson.M{"categories": 1})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return blogs, err
+	}
+
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &blogs); err != nil {
+		return blogs, err
+	}
+
+	return blogs, nil
+}
+
+func (r *MongoBlogRepository) setCategories(ctx context.Context, id bson.ObjectID, categories []string) error {
+	_, err := r.collection.UpdateByID(ctx, id, bson.M{"$set": bson.M{"categories": categories}})
+	return err
+}
+
+/*
+*
+
+	Accepts: context, FeedQuery
+
+	Looks up the latest published blogs with their authors for
+	syndication feeds, optionally filtered by the same comma
+	separated categories as GetBlogsByCategory and by author.
+*/
+func (r *MongoBlogRepository) GetBlogFeed(ctx context.Context, q *FeedQuery) ([]BlogWithAuthor, error) {
+	blogs := []BlogWithAuthor{}
+
+	filter := bson.M{"published": true}
+
+	if q.Category != "" {
+		filter["categories"] = categoryFilter(q.Category)
+	}
+
+	if !q.Author.IsZero() {
+		filter["author"] = q.Author
+	}
+
+	pipeline := mongo.Pipeline{
+		{
+			{Key: "$match", Value: filter},
+		},
+
+		{
+			{Key: "$sort", Value: bson.M{"createdAt": -1}},
+		},
+
+		{
+			{Key: "$limit", Value: q.Limit},
+		},
+
+		{
+			{
+				Key: "$lookup", Value: bson.M{
+					"from":         "users",
+					"localField":   "author",
+					"foreignField": "_id",
+					"as":           "author",
+				},
+			},
+		},
+
+		{
+			{Key: "$unwind", Value: "$author"},
+		},
+	}
+
+	opts := options.Aggregate().SetCollation(caseInsensitive)
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline, opts)
+	if err != nil {
+		return blogs, err
+	}
+
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &blogs); err != nil {
+		return blogs, err
+	}
+
+	return blogs, nil
+}
+
+/*
+*
+
+	Accepts: context
+
+	Looks up the slug and timestamps of every published blog,
+	newest first, without the rest of the document.
+*/
+func (r *MongoBlogRepository) GetPublishedSlugs(ctx context.Context) ([]BlogSlug, error) {
+	slugs := []BlogSlug{}
+
+	filter := bson.M{"published": true}
+
+	opts := options.Find().
+		SetSort(bson.M{"createdAt": -1}).
+		SetProjection(bson.M{"slug": 1, "createdAt": 1, "updatedAt": 1})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return slugs, err
+	}
+
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &slugs); err != nil {
+		return slugs, err
+	}
+
+	return slugs, nil
+}
+
+/*
+*
+
+	Accepts: context, keys (storage keys)
+
+	Looks up every blog, published or not, using one of the keys
+	as its featured image or in an image of its text.
+*/
+func (r *MongoBlogRepository) GetImageReferences(ctx context.Context, keys []string) ([]ImageReference, error) {
+	references := []ImageReference{}
+
+	if len(keys) == 0 {
+		return references, nil
+	}
+
+	opts := options.Find().
+		SetSort(bson.M{"createdAt": -1}).
+		SetProjection(bson.M{"title": 1, "slug": 1, "published": 1})
+
+	cursor, err := r.collection.Find(ctx, ImageReferenceFilter(keys), opts)
+	if err != nil {
+		return references, err
+	}
+
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &references); err != nil {
+		return references, err
+	}
+
+	return references, nil
+}
+
+/*
+*
+
+	Accepts: context
+
+	Groups published blogs by author and returns each author's
+	username with their post count and most recent update.
+*/
+func (r *MongoBlogRepository) GetAuthors(ctx context.Context) ([]AuthorSummary, error) {
+	authors := []AuthorSummary{}
+
+	pipeline := mongo.Pipeline{
+		{
+			{Key: "$match", Value: bson.M{"published": true}},
+		},
+
+		{
+			{
+				Key: "$group", Value: bson.M{
+					"_id":         "$author",
+					"count":       bson.M{"$sum": 1},
+					"lastUpdated": bson.M{"$max": "$updatedAt"},
+				},
+			},
+		},
+
+		{
+			{
+				Key: "$lookup", Value: bson.M{
+					"from":         "users",
+					"localField":   "_id",
+					"foreignField": "_id",
+					"as":           "user",
+				},
+			},
+		},
+
+		{
+			{Key: "$unwind", Value: "$user"},
+		},
+
+		{
+			{
+				Key: "$project", Value: bson.M{
+					"_id":         1,
+					"username":    "$user.username",
+					"count":       1,
+					"lastUpdated": 1,
+				},
+			},
+		},
+
+		{
+			{Key: "$sort", Value: bson.M{"username": 1}},
+		},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return authors, err
+	}
+
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &authors); err != nil {
+		return authors, err
+	}
+
+	return authors, nil
+}
+
+/*
+*
+
+	Accepts: context
+
+	Returns the title, slug, categories, popularity and author
+	username of every published blog for the suggestion index.
+*/
+func (r *MongoBlogRepository) GetSuggestionSources(ctx context.Context) ([]SuggestionSource, error) {
+	sources := []SuggestionSource{}
+
+	pipeline := mongo.Pipeline{
+		{
+			{Key: "$match", Value: bson.M{"published": true}},
+		},
+
+		{
+			{
+				Key: "$lookup", Value: bson.M{
+					"from":         "users",
+					"localField":   "author",
+					"foreignField": "_id",
+					"as":           "user",
+				},
+			},
+		},
+
+		{
+			{
+				Key: "$unwind", Value: bson.M{
+					"path":                       "$user",
+					"preserveNullAndEmptyArrays": true,
+				},
+			},
+		},
+
+		{
+			{
+				Key: "$project", Value: bson.M{
+					"title":      1,
+					"slug":       1,
+					"categories": 1,
+					"views":      1,
+					"rating":     1,
+					"author":     "$user.username",
+				},
+			},
+		},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return sources, err
+	}
+
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &sources); err != nil {
+		return sources, err
+	}
+
+	return sources, nil
+}
+
+/*
+*
+
+	Accepts: context
+
+	Returns every published blog with its categories and search
+	text, without the html, for the related blogs index.
+*/
+func (r *MongoBlogRepository) GetRelatedSources(ctx context.Context) ([]RelatedSource, error) {
+	sources := []RelatedSource{}
+
+	filter := bson.M{"published": true}
+
+	opts := options.Find().SetProjection(bson.M{
+		"title":                 1,
+		"slug":                  1,
+		"views":                 1,
+		"rating":                1,
+		"featuredImageLocation": 1,
+		"featuredImageVariants": 1,
+		"featuredImageSrcset":   1,
+		"excerpt":               1,
+		"wordCount":             1,
+		"readingTime":           1,
+		"createdAt":             1,
+		"categories":            1,
+		"searchText":            1,
+	})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return sources, err
+	}
+
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &sources); err != nil {
+		return sources, err
+	}
+
+	return sources, nil
+}
+
+/*
+*
+
+	Accepts: context
+
+	Returns the id and html text of every blog, published
+	or not, for recomputing the fields derived from it.
+*/
+func (r *MongoBlogRepository) GetBlogTexts(ctx context.Context) ([]Blog, error) {
+	blogs := []Blog{}
+
+	opts := options.Find().SetProjection(bson.M{"text": 1})
+
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return blogs, err
+	}
+
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &blogs); err != nil {
+		return blogs, err
+	}
+
+	return blogs, nil
+}
+
+/*
+*
+
+	Accepts: context
+
+	Returns the author, featured image and html text of every
+	blog, published or not, for finding the images in use.
+*/
+func (r *MongoBlogRepository) GetImageUsage(ctx context.Context) ([]Blog, error) {
+	blogs := []Blog{}
+
+	opts := options.Find().SetProjection(bson.M{
+		"author":                1,
+		"featuredImageKey":      1,
+		"featuredImageVariants": 1,
+		"text":                  1,
+	})
+
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return blogs, err
+	}
+
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &blogs); err != nil {
+		return blogs, err
+	}
+
+	return blogs, nil
+}
+
+/*
+*
+
+	Accepts: context, blog id, input
+
+	Stores the text and the fields derived from it, search text,
+	excerpt, word count, reading time and table of contents,
+	without touching the blog's updatedAt.
+*/
+func (r *MongoBlogRepository) SetDerivedFields(ctx context.Context, id bson.ObjectID, input *BaseBlogInput) error {
+	filter := bson.M{"_id": id}
+
+	update := bson.M{
+		"$set": bson.M{
+			"text":        input.Text,
+			"searchText":  input.SearchText,
+			"excerpt":     input.Excerpt,
+			"wordCount":   input.WordCount,
+			"readingTime": input.ReadingTime,
+			"toc":         input.TOC,
+		},
+	}
+
+	_, err := r.collection.UpdateOne(ctx, filter, update)
+
+	return err
+}
@@ -1,9 +1,21 @@
 package blog
 
 import (
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
+// caseInsensitive matches strings regardless of case so
+// categories stored before normalization are still found
+var caseInsensitive = &options.Collation{Locale: "en", Strength: 2}
+
 func splitAndTrim(input string) []string {
 	inputSlice := strings.Split(input, ",")
 
@@ -13,3 +25,158 @@ func splitAndTrim(input string) []string {
 
 	return inputSlice
 }
+
+// categoryFilter matches blogs containing all of the
+// comma separated categories
+func categoryFilter(category string) bson.M {
+	return bson.M{"$all": NormalizeCategories(splitAndTrim(category))}
+}
+
+// NormalizeCategory lowercases a category and collapses its whitespace
+func NormalizeCategory(category string) string {
+	return strings.ToLower(strings.Join(strings.Fields(category), " "))
+}
+
+// NormalizeCategories normalizes each category, dropping
+// empty values and duplicates while keeping their order
+func NormalizeCategories(categories []string) []string {
+	normalized := []string{}
+	seen := make(map[string]struct{}, len(categories))
+
+	for _, category := range categories {
+		category = NormalizeCategory(category)
+		if category == "" {
+			continue
+		}
+
+		if _, ok := seen[category]; ok {
+			continue
+		}
+
+		seen[category] = struct{}{}
+		normalized = append(normalized, category)
+	}
+
+	return normalized
+}
+
+// blogCategories is a blog's stored categories, read to be normalized
+// in go since the pipeline's $toLower only lowercases ascii
+type blogCategories struct {
+	ID         bson.ObjectID `bson:"_id"`
+	Categories []string      `bson:"categories"`
+}
+
+// categoryGroup is a category as stored, with the blogs listing it
+type categoryGroup struct {
+	Category   string          `bson:"_id"`
+	Posts      []bson.ObjectID `bson:"posts"`
+	LatestPost time.Time       `bson:"latestPost"`
+}
+
+/*
+mergeCategoryGroups combines the stored spellings of each category
+into a summary of the form NormalizeCategory leaves it in, a blog
+listing several spellings counting once. Most used categories first.
+*/
+func mergeCategoryGroups(groups []categoryGroup) []CategorySummary {
+	summaries := []CategorySummary{}
+	posts := map[string]map[bson.ObjectID]struct{}{}
+	indexes := map[string]int{}
+
+	for _, group := range groups {
+		name := NormalizeCategory(group.Category)
+		if name == "" {
+			continue
+		}
+
+		i, found := indexes[name]
+		if !found {
+			i = len(summaries)
+			indexes[name] = i
+			posts[name] = map[bson.ObjectID]struct{}{}
+			summaries = append(summaries, CategorySummary{Name: name})
+		}
+
+		for _, post := range group.Posts {
+			posts[name][post] = struct{}{}
+		}
+
+		summaries[i].Count = len(posts[name])
+		if group.LatestPost.After(summaries[i].LatestPost) {
+			summaries[i].LatestPost = group.LatestPost
+		}
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Count != summaries[j].Count {
+			return summaries[i].Count > summaries[j].Count
+		}
+		return summaries[i].Name < summaries[j].Name
+	})
+
+	return summaries
+}
+
+// replaceCategories normalizes the categories with each of from
+// replaced by to, reporting whether any of from was listed
+func replaceCategories(categories, from []string, to string) ([]string, bool) {
+	replaced := NormalizeCategories(categories)
+	found := false
+
+	for i, category := range replaced {
+		if slices.Contains(from, category) {
+			replaced[i] = to
+			found = true
+		}
+	}
+
+	return NormalizeCategories(replaced), found
+}
+
+/*
+ImageReferenceFilter matches blogs using any of the storage keys as
+their featured image, one of its variants, or in a url of their text.
+A key only matches a whole url path so a.png doesn't match banana.png.
+*/
+func ImageReferenceFilter(keys []string) bson.M {
+	or := bson.A{
+		bson.M{"featuredImageKey": bson.M{"$in": keys}},
+		bson.M{"featuredImageVariants.key": bson.M{"$in": keys}},
+	}
+
+	for _, key := range keys {
+		pattern := imageURLPattern(key)
+
+		or = append(or,
+			bson.M{"featuredImageLocation": bson.M{"$regex": pattern}},
+			bson.M{"text": bson.M{"$regex": pattern}},
+		)
+	}
+
+	return bson.M{"$or": or}
+}
+
+// TextImageReferenceFilter matches documents using any of the storage
+// keys in a url of their text, revisions only restore the text
+func TextImageReferenceFilter(keys []string) bson.M {
+	or := bson.A{}
+
+	for _, key := range keys {
+		or = append(or, bson.M{"text": bson.M{"$regex": imageURLPattern(key)}})
+	}
+
+	return bson.M{"$or": or}
+}
+
+// imageURLPattern matches a url whose path ends with the key,
+// escaped or not
+func imageURLPattern(key string) string {
+	forms := []string{regexp.QuoteMeta(key)}
+
+	if escaped := (&url.URL{Path: key}).EscapedPath(); escaped != key {
+		forms = append(forms, regexp.QuoteMeta(escaped))
+	}
+
+	return `/(` + strings.Join(forms, "|") + `)($|["'?#\s)<])`
+}
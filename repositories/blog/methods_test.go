@@ -0,0 +1,133 @@
+package blog
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestImageReferenceFilter(t *testing.T) {
+	filter := ImageReferenceFilter([]string{"media/users/1/my photo.png"})
+
+	or := filter["$or"].(bson.A)
+	text := or[len(or)-1].(bson.M)["text"].(bson.M)["$regex"].(string)
+
+	pattern := regexp.MustCompile(text)
+
+	tests := []struct {
+		Text string
+		Want bool
+	}{
+		{Text: `<img src="https://b.s3.us-east-1.amazonaws.com/media/users/1/my%20photo.png">`, Want: true},
+		{Text: `<img src="https://b.s3.amazonaws.com/media/users/1/my photo.png?v=2">`, Want: true},
+		{Text: `<img src="https://b.s3.amazonaws.com/media/users/1/my%20photo.png.webp">`, Want: false},
+		{Text: `<img src="https://b.s3.amazonaws.com/media/users/11/my%20photo.png">`, Want: false},
+		{Text: `<p>media/users/1/my photo.png</p>`, Want: false},
+	}
+
+	for _, test := range tests {
+		if got := pattern.MatchString(test.Text); got != test.Want {
+			t.Errorf("%s: wanted %v, got %v", test.Text, test.Want, got)
+		}
+	}
+}
+
+func TestTextImageReferenceFilter(t *testing.T) {
+	filter := TextImageReferenceFilter([]string{"featured_images/users/1/a.png", "media/users/1/b.png"})
+
+	or := filter["$or"].(bson.A)
+	if len(or) != 2 {
+		t.Fatalf("wanted a text match per key, got %v", or)
+	}
+
+	for _, clause := range or {
+		if _, found := clause.(bson.M)["text"]; !found || len(clause.(bson.M)) != 1 {
+			t.Errorf("wanted only the text matched, got %v", clause)
+		}
+	}
+}
+
+func TestBlogUpdateKeepsSchedule(t *testing.T) {
+	publishAt := time.Now().Add(24 * time.Hour)
+
+	tests := []struct {
+		Name      string
+		Input     UpdateBlogInput
+		WantSet   bool
+		WantUnset bool
+	}{
+		{
+			Name:  "edit without publishAt",
+			Input: UpdateBlogInput{BaseBlogInput: BaseBlogInput{Title: "Renamed", Text: "<p>edited</p>"}},
+		},
+		{
+			Name:    "reschedule",
+			Input:   UpdateBlogInput{BaseBlogInput: BaseBlogInput{PublishAt: &publishAt}},
+			WantSet: true,
+		},
+		{
+			Name:      "publish",
+			Input:     UpdateBlogInput{BaseBlogInput: BaseBlogInput{Published: true}},
+			WantUnset: true,
+		},
+		{
+			Name:      "clear",
+			Input:     UpdateBlogInput{ClearPublishAt: true},
+			WantUnset: true,
+		},
+	}
+
+	for _, test := range tests {
+		update := blogUpdate(&test.Input)
+
+		if _, ok := update["$set"].(bson.M)["updatedAt"].(time.Time); !ok {
+			t.Errorf("%s: wanted updatedAt set", test.Name)
+		}
+
+		_, set := update["$set"].(bson.M)["publishAt"]
+
+		unset := false
+		if fields, ok := update["$unset"].(bson.M); ok {
+			_, unset = fields["publishAt"]
+		}
+
+		if set != test.WantSet || unset != test.WantUnset {
+			t.Errorf("%s: wanted publishAt set %v and unset %v, got %v and %v", test.Name, test.WantSet, test.WantUnset, set, unset)
+		}
+	}
+}
+
+func TestCategoryNormalization(t *testing.T) {
+	// $toLower only lowercases ascii, the categories are normalized in go
+	from := NormalizeCategories([]string{"ÄRGER  im\tBüro"})
+
+	categories, found := replaceCategories([]string{"Ärger im Büro", "ÉTÉ", "été"}, from, "arbeit")
+	if !found || slices.Compare(categories, []string{"arbeit", "été"}) != 0 {
+		t.Errorf("wanted the unicode category replaced, got %v %v", categories, found)
+	}
+
+	if _, found := replaceCategories([]string{"Sommer"}, from, "arbeit"); found {
+		t.Error("wanted a blog without the category left alone")
+	}
+
+	latest := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	shared := bson.NewObjectID()
+
+	summaries := mergeCategoryGroups([]categoryGroup{
+		{Category: "Go", Posts: []bson.ObjectID{bson.NewObjectID()}},
+		{Category: "ÉTÉ", Posts: []bson.ObjectID{shared}},
+		{Category: "été\u00a0", Posts: []bson.ObjectID{shared, bson.NewObjectID()}, LatestPost: latest},
+		{Category: " ", Posts: []bson.ObjectID{bson.NewObjectID()}},
+	})
+
+	if len(summaries) != 2 || summaries[0].Name != "été" || summaries[0].Count != 2 || !summaries[0].LatestPost.Equal(latest) {
+		t.Errorf("wanted the spellings of été merged, got %+v", summaries)
+	}
+
+	if summaries[1].Name != "go" || summaries[1].Count != 1 {
+		t.Errorf("wanted go counted once, got %+v", summaries[1])
+	}
+}
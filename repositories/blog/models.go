@@ -9,94 +9,310 @@ import (
 )
 
 type BlogQuery struct {
-	Offset int
+	Offset        int
+	Cursor        string // nextCursor or prevCursor of a previous page, takes precedence over Offset
+	Limit         int    // page size, DEFAULT_PAGE_SIZE when zero
+	Sort          string // one of the SORT_ values, newest or relevance when empty
+	From          *time.Time
+	To            *time.Time // exclusive
+	Categories    []string
+	CategoryMatch string // CATEGORY_MATCH_ALL or CATEGORY_MATCH_ANY
+}
+
+type FeedQuery struct {
+	Category string
+	Author   bson.ObjectID
+	Limit    int
+}
+
+// BlogPage describes where a page of blogs sits in its listing
+type BlogPage struct {
+	HasMore    bool   `json:"hasMore"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	PrevCursor string `json:"prevCursor,omitempty"`
 }
 
 type BlogIndexResponse struct {
-	Blogs   []BlogMinimum `json:"blogs"`
-	HasMore bool          `json:"hasMore"`
+	Blogs []BlogMinimum `json:"blogs"`
+	BlogPage
+}
+
+// BlogSearchResult is a blog matching a search along
+// with a highlighted snippet of where it matched
+type BlogSearchResult struct {
+	BlogMinimum `bson:",inline"`
+	SearchText  string  `bson:"searchText" json:"-"`
+	Score       float64 `bson:"score" json:"score"`
+	Snippet     string  `bson:"-" json:"snippet"`
+}
+
+type BlogSearchResponse struct {
+	Blogs []BlogSearchResult `json:"blogs"`
+	BlogPage
 }
 
 // update this since it's not atually a SingleBlogResponse anymore
 type SingleBlogResponse struct {
-	Blog     *BlogWithAuthor `json:"blog"`
-	Previous *BlogMinimum    `json:"previous"`
-	Next     *BlogMinimum    `json:"next"`
+	Blog            *BlogWithAuthor   `json:"blog"`
+	Previous        *BlogMinimum      `json:"previous"`
+	Next            *BlogMinimum      `json:"next"`
+	Series          *SeriesNavigation `json:"series,omitempty"`
+	TableOfContents []TOCEntry        `json:"tableOfContents"`
+}
+
+// ImageVariant is one size of a featured image, the original included
+type ImageVariant struct {
+	Name   string `bson:"name" json:"name"`
+	URL    string `bson:"url" json:"url"`
+	Key    string `bson:"key" json:"-"`
+	Width  int    `bson:"width" json:"width"`
+	Height int    `bson:"height" json:"height"`
+}
+
+// TOCEntry is a heading of the blog, linked to by its anchor id
+type TOCEntry struct {
+	Level int    `bson:"level" json:"level"`
+	ID    string `bson:"id" json:"id"`
+	Text  string `bson:"text" json:"text"`
+}
+
+// SeriesNavigation places a blog within the series it belongs to.
+// Position is 1 based and Previous/Next are the neighbouring parts.
+type SeriesNavigation struct {
+	ID       bson.ObjectID `json:"_id"`
+	Title    string        `json:"title"`
+	Position int           `json:"position"`
+	Total    int           `json:"total"`
+	Previous *BlogMinimum  `json:"previous"`
+	Next     *BlogMinimum  `json:"next"`
 }
 
 type BlogUpdateResponse struct {
 	Blog *Blog `json:"blog"`
 }
 
+// LikeResponse is the blog after a like or unlike along with
+// whether the visitor likes it now and had liked it before
+type LikeResponse struct {
+	Blog         *Blog `json:"blog"`
+	Liked        bool  `json:"liked"`
+	AlreadyLiked bool  `json:"alreadyLiked"`
+}
+
 type GenericUpdateResponse struct {
 	Affected int `json:"affected"`
 }
 
+type BlogEditResponse struct {
+	Blog   *Blog  `json:"blog"`
+	Format string `json:"format"`
+	Source string `json:"source"`
+}
+
+type CategorySummary struct {
+	Name       string    `bson:"name" json:"name"`
+	Count      int       `bson:"count" json:"count"`
+	LatestPost time.Time `bson:"latestPost" json:"latestPost"`
+}
+
+// BlogSlug is the minimum needed to link to a blog
+type BlogSlug struct {
+	ID        bson.ObjectID `bson:"_id" json:"_id"`
+	Slug      string        `bson:"slug" json:"slug"`
+	CreatedAt time.Time     `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time     `bson:"updatedAt" json:"updatedAt"`
+}
+
+// ImageReference is a blog using an uploaded image
+type ImageReference struct {
+	ID        bson.ObjectID `bson:"_id" json:"_id"`
+	Title     string        `bson:"title" json:"title"`
+	Slug      string        `bson:"slug" json:"slug"`
+	Published bool          `bson:"published" json:"published"`
+	Revision  bool          `bson:"-" json:"revision,omitempty"` // only an earlier revision of the blog uses the image
+}
+
+type AuthorSummary struct {
+	ID          bson.ObjectID `bson:"_id" json:"_id"`
+	Username    string        `bson:"username" json:"username"`
+	Count       int           `bson:"count" json:"count"`
+	LastUpdated time.Time     `bson:"lastUpdated" json:"lastUpdated"`
+}
+
+type CategoryIndexResponse struct {
+	Categories []CategorySummary `json:"categories"`
+}
+
+// Category rename POST payload
+type CategoryRenamePost struct {
+	Name string `json:"name"`
+}
+
+// Category merge POST payload
+type CategoryMergePost struct {
+	From []string `json:"from"`
+	Into string   `json:"into"`
+}
+
+// SuggestionSource is a published blog as seen by the suggestion index
+type SuggestionSource struct {
+	ID         bson.ObjectID `bson:"_id"`
+	Title      string        `bson:"title"`
+	Slug       string        `bson:"slug"`
+	Categories []string      `bson:"categories"`
+	Views      int           `bson:"views"`
+	Rating     int           `bson:"rating"`
+	Author     string        `bson:"author"`
+}
+
+// RelatedSource is a published blog as seen by the related blogs index
+type RelatedSource struct {
+	BlogMinimum `bson:",inline"`
+	Categories  []string `bson:"categories"`
+	SearchText  string   `bson:"searchText"`
+}
+
+type RelatedBlog struct {
+	BlogMinimum
+	Score float64 `json:"score"`
+}
+
+type RelatedResponse struct {
+	Blogs []RelatedBlog `json:"blogs"`
+}
+
+type PostSuggestion struct {
+	Title string `json:"title"`
+	Slug  string `json:"slug"`
+}
+
+type CategorySuggestion struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type AuthorSuggestion struct {
+	Username string `json:"username"`
+	Count    int    `json:"count"`
+}
+
+type SuggestionResponse struct {
+	Posts      []PostSuggestion     `json:"posts"`
+	Categories []CategorySuggestion `json:"categories"`
+	Authors    []AuthorSuggestion   `json:"authors"`
+}
+
 type SlugValidationResponse struct {
-	IsAvailable bool `json:"isAvailable"`
+	IsAvailable bool   `json:"isAvailable"`
+	Slug        string `json:"slug"`       // the slug the input is saved as
+	Reserved    bool   `json:"reserved"`   // clashes with a route
+	Suggestion  string `json:"suggestion"` // the slug, or the first free numbered variant
+}
+
+// SlugRedirectResponse is served with a 301 when a retired slug is requested
+type SlugRedirectResponse struct {
+	Slug string `json:"slug"`
 }
 
 type Blog struct {
-	Categories    []string      `bson:"categories" json:"categories"`
-	Rating        int           `bson:"rating" json:"rating"`
-	Views         int           `bson:"views" json:"views"`
-	ID            bson.ObjectID `bson:"_id,omitempty" json:"_id"`
-	Author        bson.ObjectID `bson:"author" json:"author"`
-	Title         string        `bson:"title" json:"title"`
-	ImageLocation string        `bson:"featuredImageLocation" json:"featuredImageLocation"`
-	ImageTag      string        `bson:"featuredImageTag" json:"featuredImageTag"`
-	ImageKey      string        `bson:"featuredImageKey" json:"featuredImageKey"`
-	Text          string        `bson:"text" json:"text"`
-	Published     bool          `bson:"published" json:"published"`
-	Slug          string        `bson:"slug" json:"slug"`
+	Categories    []string       `bson:"categories" json:"categories"`
+	Rating        int            `bson:"rating" json:"rating"`
+	Views         int            `bson:"views" json:"views"`
+	ID            bson.ObjectID  `bson:"_id,omitempty" json:"_id"`
+	Author        bson.ObjectID  `bson:"author" json:"author"`
+	Title         string         `bson:"title" json:"title"`
+	ImageLocation string         `bson:"featuredImageLocation" json:"featuredImageLocation"`
+	ImageTag      string         `bson:"featuredImageTag" json:"featuredImageTag"`
+	ImageKey      string         `bson:"featuredImageKey" json:"featuredImageKey"`
+	ImageVariants []ImageVariant `bson:"featuredImageVariants,omitempty" json:"featuredImageVariants,omitempty"`
+	ImageSrcset   string         `bson:"featuredImageSrcset,omitempty" json:"featuredImageSrcset,omitempty"`
+	Text          string         `bson:"text" json:"text"`
+	Markdown      string         `bson:"markdown,omitempty" json:"markdown,omitempty"`
+	Format        string         `bson:"format,omitempty" json:"format,omitempty"`
+	SearchText    string         `bson:"searchText,omitempty" json:"-"` // text without markup, used by the search index
+	Excerpt       string         `bson:"excerpt,omitempty" json:"excerpt"`
+	WordCount     int            `bson:"wordCount,omitempty" json:"wordCount"`
+	ReadingTime   int            `bson:"readingTime,omitempty" json:"readingTime"` // minutes
+	TOC           []TOCEntry     `bson:"toc,omitempty" json:"-"`                   // served as the response's tableOfContents
+	Published     bool           `bson:"published" json:"published"`
+	PublishAt     *time.Time     `bson:"publishAt,omitempty" json:"publishAt"`
+	Slug          string         `bson:"slug" json:"slug"`
+	PreviousSlugs []string       `bson:"previousSlugs,omitempty" json:"previousSlugs,omitempty"` // retired slugs, redirected to Slug
 	// SanitizedHTML string        `bson:"sanitizedHTML" json:"sanitizedHTML"` not using atm
 	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
 	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"`
 }
 
 type BlogWithAuthor struct {
-	Categories    []string      `bson:"categories" json:"categories"`
-	Rating        int           `bson:"rating" json:"rating"`
-	Views         int           `bson:"views" json:"views"`
-	ID            bson.ObjectID `bson:"_id,omitempty" json:"_id"`
-	Author        ur.User       `bson:"author" json:"author"`
-	Title         string        `bson:"title" json:"title"`
-	ImageLocation string        `bson:"featuredImageLocation" json:"featuredImageLocation"`
-	ImageTag      string        `bson:"featuredImageTag" json:"featuredImageTag"`
-	ImageKey      string        `bson:"featuredImageKey" json:"featuredImageKey"`
-	Text          string        `bson:"text" json:"text"`
-	Published     bool          `bson:"published" json:"published"`
-	Slug          string        `bson:"slug" json:"slug"`
+	Categories    []string       `bson:"categories" json:"categories"`
+	Rating        int            `bson:"rating" json:"rating"`
+	Views         int            `bson:"views" json:"views"`
+	ID            bson.ObjectID  `bson:"_id,omitempty" json:"_id"`
+	Author        ur.User        `bson:"author" json:"author"`
+	Title         string         `bson:"title" json:"title"`
+	ImageLocation string         `bson:"featuredImageLocation" json:"featuredImageLocation"`
+	ImageTag      string         `bson:"featuredImageTag" json:"featuredImageTag"`
+	ImageKey      string         `bson:"featuredImageKey" json:"featuredImageKey"`
+	ImageVariants []ImageVariant `bson:"featuredImageVariants,omitempty" json:"featuredImageVariants,omitempty"`
+	ImageSrcset   string         `bson:"featuredImageSrcset,omitempty" json:"featuredImageSrcset,omitempty"`
+	Text          string         `bson:"text" json:"text"`
+	Markdown      string         `bson:"markdown,omitempty" json:"markdown,omitempty"`
+	Format        string         `bson:"format,omitempty" json:"format,omitempty"`
+	Excerpt       string         `bson:"excerpt,omitempty" json:"excerpt"`
+	WordCount     int            `bson:"wordCount,omitempty" json:"wordCount"`
+	ReadingTime   int            `bson:"readingTime,omitempty" json:"readingTime"` // minutes
+	TOC           []TOCEntry     `bson:"toc,omitempty" json:"-"`                   // served as the response's tableOfContents
+	Published     bool           `bson:"published" json:"published"`
+	PublishAt     *time.Time     `bson:"publishAt,omitempty" json:"publishAt"`
+	Slug          string         `bson:"slug" json:"slug"`
 	// SanitizedHTML string        `bson:"sanitizedHTML" json:"sanitizedHTML"` not using atm
 	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
 	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"`
 }
 
 type BlogMinimum struct {
-	ID            bson.ObjectID `bson:"_id,omitempty" json:"_id"`
-	Views         int           `bson:"views" json:"views"`
-	Title         string        `bson:"title" json:"title"`
-	ImageLocation string        `bson:"featuredImageLocation" json:"featuredImageLocation"`
-	Slug          string        `bson:"slug" json:"slug"`
-	Rating        int           `bson:"rating" json:"rating"`
-	CreatedAt     time.Time     `bson:"createdAt" json:"createdAt"`
+	ID            bson.ObjectID  `bson:"_id,omitempty" json:"_id"`
+	Views         int            `bson:"views" json:"views"`
+	Title         string         `bson:"title" json:"title"`
+	ImageLocation string         `bson:"featuredImageLocation" json:"featuredImageLocation"`
+	ImageVariants []ImageVariant `bson:"featuredImageVariants,omitempty" json:"featuredImageVariants,omitempty"`
+	ImageSrcset   string         `bson:"featuredImageSrcset,omitempty" json:"featuredImageSrcset,omitempty"`
+	Slug          string         `bson:"slug" json:"slug"`
+	Rating        int            `bson:"rating" json:"rating"`
+	Excerpt       string         `bson:"excerpt,omitempty" json:"excerpt"`
+	WordCount     int            `bson:"wordCount,omitempty" json:"wordCount"`
+	ReadingTime   int            `bson:"readingTime,omitempty" json:"readingTime"` // minutes
+	PublishAt     *time.Time     `bson:"publishAt,omitempty" json:"publishAt,omitempty"`
+	CreatedAt     time.Time      `bson:"createdAt" json:"createdAt"`
 }
 
 type BaseBlogInput struct {
 	Categories    []string              `bson:"categories" form:"categories"`
 	Text          string                `bson:"text" form:"text"`
+	Markdown      string                `bson:"markdown" form:"markdown"` // rendered into Text when provided
+	Format        string                `bson:"format"`
+	SearchText    string                `bson:"searchText"`
+	Excerpt       string                `bson:"excerpt"`
+	WordCount     int                   `bson:"wordCount"`
+	ReadingTime   int                   `bson:"readingTime"`
+	TOC           []TOCEntry            `bson:"toc"`
 	Published     bool                  `bson:"published" form:"published"`
+	PublishAt     *time.Time            `bson:"publishAt" form:"publishAt"` // RFC 3339, keeps the blog unpublished until then
 	Title         string                `bson:"title" form:"title"`
 	Image         *multipart.FileHeader `bson:"-" form:"image"`     // ignored bson -> ignored in the mongo upsert
 	ImageBytes    []byte                `bson:"-" form:"imageData"` // ignored bson -> ignored in the mongo upsert
 	ImageLocation string                `bson:"featuredImageLocation"`
 	ImageKey      string                `bson:"featuredImageKey"`
+	ImageVariants []ImageVariant        `bson:"featuredImageVariants"`
+	ImageSrcset   string                `bson:"featuredImageSrcset"`
 	Slug          string                `bson:"slug" form:"slug"`
 }
 
 type UpdateBlogInput struct {
-	BaseBlogInput `bson:",inline"`
-	ID            string `bson:"_id" form:"id"`
+	BaseBlogInput  `bson:",inline"`
+	ID             string   `bson:"_id" form:"id"`
+	ClearPublishAt bool     `bson:"-" form:"clearPublishAt"` // cancels a schedule, an edit without publishAt keeps it
+	PreviousSlugs  []string `bson:"-"`                       // set by the service when Slug changes
 }
 
 type CreateBlogInput struct {
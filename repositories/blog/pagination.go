@@ -0,0 +1,360 @@
+package blog
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	DEFAULT_PAGE_SIZE = 10
+	MAX_PAGE_SIZE     = 50
+)
+
+const (
+	SORT_NEWEST      = "newest"
+	SORT_OLDEST      = "oldest"
+	SORT_MOST_VIEWED = "most-viewed"
+	SORT_MOST_LIKED  = "most-liked"
+	// search results only, ordered by text score
+	SORT_RELEVANCE = "relevance"
+)
+
+const (
+	CATEGORY_MATCH_ALL = "all"
+	CATEGORY_MATCH_ANY = "any"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrSearchSort    = errors.New("relevance sort only applies to search")
+)
+
+// sortField is a field a listing is ordered by, 1 or -1
+type sortField struct {
+	name  string
+	order int
+}
+
+// every sort ends on createdAt then _id so the keyset is unique
+var sortFields = map[string][]sortField{
+	SORT_NEWEST:      {{"createdAt", -1}, {"_id", -1}},
+	SORT_OLDEST:      {{"createdAt", 1}, {"_id", 1}},
+	SORT_MOST_VIEWED: {{"views", -1}, {"createdAt", -1}, {"_id", -1}},
+	SORT_MOST_LIKED:  {{"rating", -1}, {"createdAt", -1}, {"_id", -1}},
+	SORT_RELEVANCE:   {{"score", -1}, {"createdAt", -1}, {"_id", -1}},
+}
+
+// ValidSort reports if the sort can be used, relevance only applies to search
+func ValidSort(sort string, search bool) bool {
+	_, ok := sortFields[sort]
+	return ok && (search || sort != SORT_RELEVANCE)
+}
+
+// pageSize returns the requested page size or the default
+func (q *BlogQuery) pageSize() int {
+	if q.Limit <= 0 {
+		return DEFAULT_PAGE_SIZE
+	}
+
+	return min(q.Limit, MAX_PAGE_SIZE)
+}
+
+// sortOrder returns the requested sort or the listing's default
+func (q *BlogQuery) sortOrder(search bool) string {
+	if q.Sort != "" {
+		return q.Sort
+	}
+
+	if search {
+		return SORT_RELEVANCE
+	}
+
+	return SORT_NEWEST
+}
+
+/*
+listingFilter adds the query's date range and categories to a
+listing's base filter. It reports if categories are matched so
+the caller can apply the case-insensitive collation. The collation
+doesn't collapse whitespace, categories are normalized on write and
+older ones by NormalizeStoredCategories.
+*/
+func listingFilter(filter bson.M, q *BlogQuery) (bson.M, bool) {
+	if q.From != nil || q.To != nil {
+		createdAt := bson.M{}
+
+		if q.From != nil {
+			createdAt["$gte"] = q.From
+		}
+
+		if q.To != nil {
+			createdAt["$lt"] = q.To
+		}
+
+		filter["createdAt"] = createdAt
+	}
+
+	categories := NormalizeCategories(q.Categories)
+	if len(categories) == 0 {
+		return filter, false
+	}
+
+	if q.CategoryMatch == CATEGORY_MATCH_ANY {
+		filter["categories"] = bson.M{"$in": categories}
+	} else {
+		filter["categories"] = bson.M{"$all": categories}
+	}
+
+	return filter, true
+}
+
+/*
+pageCursor is the sort key of the blog a page starts or ends at.
+Value holds the views, rating or text score the listing is first
+sorted by. Clients only ever see it encoded.
+*/
+type pageCursor struct {
+	Sort      string   `json:"o"`
+	Value     *float64 `json:"v,omitempty"`
+	CreatedAt int64    `json:"t"`
+	ID        string   `json:"id"`
+	Prev      bool     `json:"p,omitempty"`
+
+	objectID bson.ObjectID
+}
+
+func encodeCursor(c pageCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor returns nil when no cursor was provided. A cursor
+// from a listing with a different sort is rejected.
+func decodeCursor(value, sort string) (*pageCursor, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	c := new(pageCursor)
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if c.Sort != sort || (len(sortFields[sort]) > 2) != (c.Value != nil) {
+		return nil, ErrInvalidCursor
+	}
+
+	if c.objectID, err = bson.ObjectIDFromHex(c.ID); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+// values returns the cursor's value for each sort field
+func (c *pageCursor) values() []any {
+	values := []any{time.UnixMilli(c.CreatedAt), c.objectID}
+
+	if c.Value != nil {
+		values = append([]any{*c.Value}, values...)
+	}
+
+	return values
+}
+
+// keysetFilter matches the blogs after the cursor, or
+// before it when paging back
+func keysetFilter(c *pageCursor) bson.M {
+	fields := sortFields[c.Sort]
+	values := c.values()
+
+	conditions := []bson.M{}
+
+	for i, field := range fields {
+		op := "$lt"
+		if (field.order == 1) != c.Prev {
+			op = "$gt"
+		}
+
+		condition := bson.M{field.name: bson.M{op: values[i]}}
+
+		for j := 0; j < i; j++ {
+			condition[fields[j].name] = values[j]
+		}
+
+		conditions = append(conditions, condition)
+	}
+
+	return bson.M{"$or": conditions}
+}
+
+// pageSort orders the listing by its sort, reversed while
+// paging back so the blogs nearest the cursor come first
+func pageSort(sort string, c *pageCursor) bson.D {
+	reverse := c != nil && c.Prev
+
+	order := bson.D{}
+
+	for _, field := range sortFields[sort] {
+		direction := field.order
+		if reverse {
+			direction = -direction
+		}
+
+		order = append(order, bson.E{Key: field.name, Value: direction})
+	}
+
+	return order
+}
+
+/*
+buildPage trims the one extra blog fetched past the limit, which
+is how hasMore is known without counting, puts blogs read while
+paging back into display order and sets the cursors either side.
+*/
+func buildPage[T any](items []T, q *BlogQuery, c *pageCursor, key func(T) pageCursor) ([]T, BlogPage) {
+	var page BlogPage
+
+	limit := q.pageSize()
+
+	more := len(items) > limit
+	if more {
+		items = items[:limit]
+	}
+
+	if len(items) == 0 {
+		return items, page
+	}
+
+	paging := c != nil && c.Prev
+	if paging {
+		slices.Reverse(items)
+	}
+
+	first := key(items[0])
+	first.Prev = true
+	last := key(items[len(items)-1])
+
+	switch {
+	case paging:
+		// the blog the cursor came from follows this page
+		page.HasMore = true
+		page.NextCursor = encodeCursor(last)
+
+		if more {
+			page.PrevCursor = encodeCursor(first)
+		}
+	default:
+		page.HasMore = more
+
+		if more {
+			page.NextCursor = encodeCursor(last)
+		}
+
+		if c != nil || q.Offset > 0 {
+			page.PrevCursor = encodeCursor(first)
+		}
+	}
+
+	return items, page
+}
+
+// blogCursor returns the key function for blogs in a listing sorted by sort
+func blogCursor(sort string) func(BlogMinimum) pageCursor {
+	return func(blog BlogMinimum) pageCursor {
+		c := pageCursor{Sort: sort, CreatedAt: blog.CreatedAt.UnixMilli(), ID: blog.ID.Hex()}
+
+		switch sort {
+		case SORT_MOST_VIEWED:
+			c.Value = toValue(blog.Views)
+		case SORT_MOST_LIKED:
+			c.Value = toValue(blog.Rating)
+		}
+
+		return c
+	}
+}
+
+func searchCursor(sort string) func(BlogSearchResult) pageCursor {
+	key := blogCursor(sort)
+
+	return func(result BlogSearchResult) pageCursor {
+		c := key(result.BlogMinimum)
+
+		if sort == SORT_RELEVANCE {
+			score := result.Score
+			c.Value = &score
+		}
+
+		return c
+	}
+}
+
+func toValue(n int) *float64 {
+	value := float64(n)
+	return &value
+}
+
+/*
+findBlogPage looks up a page of blogs matching the filter along
+with the query's date range and categories. A cursor in the query
+pages by keyset, otherwise the offset is skipped so older clients
+keep working.
+*/
+func (r *MongoBlogRepository) findBlogPage(ctx context.Context, filter bson.M, q *BlogQuery) ([]BlogMinimum, BlogPage, error) {
+	blogs := []BlogMinimum{}
+	sort := q.sortOrder(false)
+
+	if !ValidSort(sort, false) {
+		return blogs, BlogPage{}, ErrSearchSort
+	}
+
+	c, err := decodeCursor(q.Cursor, sort)
+	if err != nil {
+		return blogs, BlogPage{}, err
+	}
+
+	filter, byCategory := listingFilter(filter, q)
+
+	opts := options.Find().
+		SetSort(pageSort(sort, c)).
+		SetLimit(int64(q.pageSize() + 1))
+
+	if c != nil {
+		filter = bson.M{"$and": []bson.M{filter, keysetFilter(c)}}
+	} else {
+		opts.SetSkip(int64(q.Offset))
+	}
+
+	// categories stored before normalization may not be lowercase
+	if byCategory {
+		opts.SetCollation(caseInsensitive)
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return blogs, BlogPage{}, err
+	}
+
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &blogs); err != nil {
+		return blogs, BlogPage{}, err
+	}
+
+	blogs, page := buildPage(blogs, q, c, blogCursor(sort))
+
+	return blogs, page, nil
+}
@@ -0,0 +1,172 @@
+package blog
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func testBlogs(n int) []BlogMinimum {
+	blogs := make([]BlogMinimum, n)
+	created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := range blogs {
+		blogs[i] = BlogMinimum{ID: bson.NewObjectID(), CreatedAt: created.Add(-time.Duration(i) * time.Hour)}
+	}
+
+	return blogs
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	blog := testBlogs(1)[0]
+
+	c, err := decodeCursor(encodeCursor(blogCursor(SORT_NEWEST)(blog)), SORT_NEWEST)
+	if err != nil {
+		t.Fatalf("failed to decode cursor: %v", err)
+	}
+
+	if c.objectID != blog.ID || c.CreatedAt != blog.CreatedAt.UnixMilli() || c.Prev {
+		t.Errorf("cursor did not round trip: %+v", c)
+	}
+
+	if c, err := decodeCursor("", SORT_NEWEST); c != nil || err != nil {
+		t.Errorf("expected no cursor, got %+v, %v", c, err)
+	}
+
+	invalid := []string{
+		"not base64!",
+		"bm90IGpzb24",
+		encodeCursor(pageCursor{Sort: SORT_NEWEST, ID: "nope"}),
+		// a cursor from a listing with another sort
+		encodeCursor(blogCursor(SORT_MOST_VIEWED)(blog)),
+	}
+
+	for _, value := range invalid {
+		if _, err := decodeCursor(value, SORT_NEWEST); err != ErrInvalidCursor {
+			t.Errorf("expected %q to be rejected, got %v", value, err)
+		}
+	}
+}
+
+func TestBuildPage(t *testing.T) {
+	// a full page plus the extra blog fetched to detect hasMore
+	key := blogCursor(SORT_NEWEST)
+	blogs, page := buildPage(testBlogs(DEFAULT_PAGE_SIZE+1), &BlogQuery{}, nil, key)
+
+	if len(blogs) != DEFAULT_PAGE_SIZE || !page.HasMore || page.NextCursor == "" || page.PrevCursor != "" {
+		t.Fatalf("unexpected first page: %d blogs, %+v", len(blogs), page)
+	}
+
+	next, _ := decodeCursor(page.NextCursor, SORT_NEWEST)
+	if next.objectID != blogs[DEFAULT_PAGE_SIZE-1].ID || next.Prev {
+		t.Errorf("next cursor should point at the last blog: %+v", next)
+	}
+
+	// last page reached by cursor
+	blogs, page = buildPage(testBlogs(3), &BlogQuery{}, next, key)
+
+	if page.HasMore || page.NextCursor != "" || page.PrevCursor == "" {
+		t.Errorf("unexpected last page: %+v", page)
+	}
+
+	prev, _ := decodeCursor(page.PrevCursor, SORT_NEWEST)
+	if !prev.Prev || prev.objectID != blogs[0].ID {
+		t.Errorf("prev cursor should point back from the first blog: %+v", prev)
+	}
+
+	// paging back reads oldest first, the page must come out newest first
+	reversed := testBlogs(DEFAULT_PAGE_SIZE + 1)
+	for i, j := 0, len(reversed)-1; i < j; i, j = i+1, j-1 {
+		reversed[i], reversed[j] = reversed[j], reversed[i]
+	}
+
+	blogs, page = buildPage(reversed, &BlogQuery{}, prev, key)
+
+	if !blogs[0].CreatedAt.After(blogs[len(blogs)-1].CreatedAt) {
+		t.Error("expected the page back to be newest first")
+	}
+
+	if !page.HasMore || page.NextCursor == "" || page.PrevCursor == "" {
+		t.Errorf("unexpected page back: %+v", page)
+	}
+
+	// offset pages keep working and can page back by cursor
+	_, page = buildPage(testBlogs(2), &BlogQuery{Offset: 10}, nil, key)
+
+	if page.HasMore || page.PrevCursor == "" {
+		t.Errorf("unexpected offset page: %+v", page)
+	}
+
+	// the page size caps at the maximum
+	blogs, page = buildPage(testBlogs(MAX_PAGE_SIZE+5), &BlogQuery{Limit: 500}, nil, key)
+
+	if len(blogs) != MAX_PAGE_SIZE || !page.HasMore {
+		t.Errorf("expected %d blogs, got %d", MAX_PAGE_SIZE, len(blogs))
+	}
+}
+
+func TestKeysetFilter(t *testing.T) {
+	score := 1.5
+	c := &pageCursor{Sort: SORT_RELEVANCE, Value: &score, CreatedAt: 1000, objectID: bson.NewObjectID(), Prev: true}
+
+	conditions := keysetFilter(c)["$or"].([]bson.M)
+
+	if len(conditions) != 3 {
+		t.Fatalf("expected score, createdAt and _id conditions, got %v", conditions)
+	}
+
+	if _, ok := conditions[0]["score"].(bson.M)["$gt"]; !ok {
+		t.Errorf("expected paging back to compare with $gt, got %v", conditions[0])
+	}
+
+	if conditions[2]["score"] != score || conditions[2]["_id"].(bson.M)["$gt"] != c.objectID {
+		t.Errorf("expected the tie breaker on _id, got %v", conditions[2])
+	}
+
+	// oldest first pages forward with $gt
+	c = &pageCursor{Sort: SORT_OLDEST, CreatedAt: 1000, objectID: bson.NewObjectID()}
+	conditions = keysetFilter(c)["$or"].([]bson.M)
+
+	if _, ok := conditions[0]["createdAt"].(bson.M)["$gt"]; !ok || len(conditions) != 2 {
+		t.Errorf("expected oldest first to page forward with $gt, got %v", conditions)
+	}
+
+	sort := pageSort(SORT_MOST_LIKED, c)
+	if sort[0].Key != "rating" || sort[0].Value != -1 || sort[2].Key != "_id" {
+		t.Errorf("unexpected most liked sort: %v", sort)
+	}
+}
+
+func TestListingFilter(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	filter, byCategory := listingFilter(bson.M{"published": true}, &BlogQuery{
+		From:          &from,
+		Categories:    []string{" Go ", "web  dev", "go"},
+		CategoryMatch: CATEGORY_MATCH_ANY,
+	})
+
+	if !byCategory {
+		t.Error("expected the category collation to be requested")
+	}
+
+	if filter["createdAt"].(bson.M)["$gte"] != &from {
+		t.Errorf("expected the from date, got %v", filter["createdAt"])
+	}
+
+	categories := filter["categories"].(bson.M)["$in"].([]string)
+	if len(categories) != 2 || categories[0] != "go" || categories[1] != "web dev" {
+		t.Errorf("expected normalized categories, got %v", categories)
+	}
+
+	filter, byCategory = listingFilter(bson.M{}, &BlogQuery{Categories: []string{"go"}})
+
+	if _, ok := filter["categories"].(bson.M)["$all"]; !ok || !byCategory {
+		t.Errorf("expected all categories to be matched by default, got %v", filter)
+	}
+
+	if filter, byCategory = listingFilter(bson.M{}, &BlogQuery{}); len(filter) != 0 || byCategory {
+		t.Errorf("expected no filters, got %v", filter)
+	}
+}
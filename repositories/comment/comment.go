@@ -0,0 +1,213 @@
+package comment
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type CommentRepository interface {
+	CreateComment(ctx context.Context, comment *Comment) (*Comment, error)
+	GetCommentByID(ctx context.Context, id bson.ObjectID) (*Comment, error)
+	GetApprovedThreads(ctx context.Context, blog bson.ObjectID, offset int) ([]Comment, bool, error)
+	GetApprovedReplies(ctx context.Context, threads []bson.ObjectID) ([]Comment, error)
+	GetCommentsByStatus(ctx context.Context, blogAuthor bson.ObjectID, status string, offset int) ([]Comment, bool, error)
+	UpdateCommentStatus(ctx context.Context, id, blogAuthor bson.ObjectID, status string) (*Comment, error)
+	DeleteCommentsByBlog(ctx context.Context, blog bson.ObjectID) (int, error)
+}
+
+type MongoCommentRepository struct {
+	collection *mongo.Collection
+}
+
+func NewCommentRepository(db *mongo.Database) CommentRepository {
+	return &MongoCommentRepository{
+		collection: db.Collection("comments"),
+	}
+}
+
+func (r *MongoCommentRepository) CreateComment(ctx context.Context, comment *Comment) (*Comment, error) {
+	result, err := r.collection.InsertOne(ctx, comment)
+	if err != nil {
+		return nil, err
+	}
+
+	insertedID, ok := result.InsertedID.(bson.ObjectID)
+	if !ok {
+		return nil, fmt.Errorf("failed to convert inserted id to ObjectID")
+	}
+
+	return r.GetCommentByID(ctx, insertedID)
+}
+
+func (r *MongoCommentRepository) GetCommentByID(ctx context.Context, id bson.ObjectID) (*Comment, error) {
+	var comment *Comment
+
+	filter := bson.M{"_id": id}
+
+	if err := r.collection.FindOne(ctx, filter).Decode(&comment); err != nil {
+		return comment, err
+	}
+
+	return comment, nil
+}
+
+/*
+*
+
+	Accepts: context, blog (document ObjectID), offset
+
+	Looks up 10 approved top level comments of the blog, oldest
+	first, and returns them with a bool indicating if there are
+	any additional comments available after the provided offset.
+*/
+func (r *MongoCommentRepository) GetApprovedThreads(ctx context.Context, blog bson.ObjectID, offset int) ([]Comment, bool, error) {
+	limit := 10
+	comments := []Comment{}
+
+	filter := bson.M{
+		"blog":   blog,
+		"status": STATUS_APPROVED,
+		"parent": bson.M{"$exists": false},
+	}
+
+	// fetch one extra document to learn if there are more
+	opts := options.Find().
+		SetSort(bson.M{"createdAt": 1}).
+		SetLimit(int64(limit + 1)).
+		SetSkip(int64(offset))
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return comments, false, err
+	}
+
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &comments); err != nil {
+		return comments, false, err
+	}
+
+	hasMore := len(comments) > limit
+	if hasMore {
+		comments = comments[:limit]
+	}
+
+	return comments, hasMore, nil
+}
+
+/*
+*
+
+	Accepts: context, threads (top level comment ObjectIDs)
+
+	Looks up every approved reply within the provided threads,
+	oldest first.
+*/
+func (r *MongoCommentRepository) GetApprovedReplies(ctx context.Context, threads []bson.ObjectID) ([]Comment, error) {
+	comments := []Comment{}
+
+	filter := bson.M{
+		"thread": bson.M{"$in": threads},
+		"status": STATUS_APPROVED,
+	}
+
+	opts := options.Find().SetSort(bson.M{"createdAt": 1})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return comments, err
+	}
+
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &comments); err != nil {
+		return comments, err
+	}
+
+	return comments, nil
+}
+
+/*
+*
+
+	Accepts: context, blogAuthor (user ObjectID), status, offset
+
+	Looks up 10 comments with the provided status left on the
+	author's blogs, oldest first, for the moderation queue.
+*/
+func (r *MongoCommentRepository) GetCommentsByStatus(ctx context.Context, blogAuthor bson.ObjectID, status string, offset int) ([]Comment, bool, error) {
+	limit := 10
+	comments := []Comment{}
+
+	filter := bson.M{
+		"blogAuthor": blogAuthor,
+		"status":     status,
+	}
+
+	opts := options.Find().
+		SetSort(bson.M{"createdAt": 1}).
+		SetLimit(int64(limit + 1)).
+		SetSkip(int64(offset))
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return comments, false, err
+	}
+
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &comments); err != nil {
+		return comments, false, err
+	}
+
+	hasMore := len(comments) > limit
+	if hasMore {
+		comments = comments[:limit]
+	}
+
+	return comments, hasMore, nil
+}
+
+func (r *MongoCommentRepository) UpdateCommentStatus(ctx context.Context, id, blogAuthor bson.ObjectID, status string) (*Comment, error) {
+	var comment *Comment
+
+	filter := bson.M{
+		"_id":        id,
+		"blogAuthor": blogAuthor,
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"status":      status,
+			"moderatedAt": time.Now(),
+		},
+	}
+
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		filter,
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&comment)
+	if err != nil {
+		return comment, err
+	}
+
+	return comment, nil
+}
+
+func (r *MongoCommentRepository) DeleteCommentsByBlog(ctx context.Context, blog bson.ObjectID) (int, error) {
+	filter := bson.M{"blog": blog}
+
+	result, err := r.collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(result.DeletedCount), nil
+}
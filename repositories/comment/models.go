@@ -0,0 +1,58 @@
+package comment
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	STATUS_PENDING  = "pending"
+	STATUS_APPROVED = "approved"
+	STATUS_REJECTED = "rejected"
+)
+
+// Comment POST payload
+type CommentPost struct {
+	Name   string `json:"name"`
+	Body   string `json:"body"`
+	Parent string `json:"parent"`
+}
+
+// Comment moderation POST payload
+type CommentModerationPost struct {
+	Status string `json:"status"`
+}
+
+type Comment struct {
+	ID          bson.ObjectID  `bson:"_id,omitempty" json:"_id"`
+	Blog        bson.ObjectID  `bson:"blog" json:"blog"`
+	BlogAuthor  bson.ObjectID  `bson:"blogAuthor" json:"-"`
+	Parent      *bson.ObjectID `bson:"parent,omitempty" json:"parent"`
+	Thread      *bson.ObjectID `bson:"thread,omitempty" json:"thread"` // top level comment of a reply chain
+	Name        string         `bson:"name" json:"name"`
+	Body        string         `bson:"body" json:"body"`
+	Status      string         `bson:"status" json:"status"`
+	CreatedAt   time.Time      `bson:"createdAt" json:"createdAt"`
+	ModeratedAt *time.Time     `bson:"moderatedAt,omitempty" json:"moderatedAt,omitempty"`
+}
+
+// CommentThread is a top level comment and all of its replies
+type CommentThread struct {
+	Comment `bson:",inline"`
+	Replies []Comment `json:"replies"`
+}
+
+type CommentIndexResponse struct {
+	Comments []CommentThread `json:"comments"`
+	HasMore  bool            `json:"hasMore"`
+}
+
+type CommentModerationResponse struct {
+	Comments []Comment `json:"comments"`
+	HasMore  bool      `json:"hasMore"`
+}
+
+type CommentResponse struct {
+	Comment *Comment `json:"comment"`
+}
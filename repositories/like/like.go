@@ -0,0 +1,149 @@
+package like
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type LikeRepository interface {
+	CreateLike(ctx context.Context, blog bson.ObjectID, visitor string) (bool, error)
+	DeleteLike(ctx context.Context, blog bson.ObjectID, visitor string) (bool, error)
+	HasLiked(ctx context.Context, blog bson.ObjectID, visitor string) (bool, error)
+	DeleteLikesByBlog(ctx context.Context, blog bson.ObjectID) (int, error)
+	GetLikesSince(ctx context.Context, since time.Time) ([]Like, error)
+	EnsureIndexes(ctx context.Context) error
+}
+
+type MongoLikeRepository struct {
+	collection *mongo.Collection
+}
+
+func NewLikeRepository(db *mongo.Database) LikeRepository {
+	return &MongoLikeRepository{
+		collection: db.Collection("bloglikes"),
+	}
+}
+
+/*
+*
+
+	Accepts: context
+
+	Creates the unique blog and visitor index the like methods rely
+	on to count each visitor once, and the index recent likes are
+	looked up by. Existing indexes are left as is.
+*/
+func (r *MongoLikeRepository) EnsureIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "blog", Value: 1},
+				{Key: "visitor", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "createdAt", Value: -1}},
+		},
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
+
+	return err
+}
+
+/*
+*
+
+	Accepts: context, blog (document ObjectID), visitor
+
+	Records the visitor's like of the blog and returns false
+	without an error when the visitor had already liked it.
+*/
+func (r *MongoLikeRepository) CreateLike(ctx context.Context, blog bson.ObjectID, visitor string) (bool, error) {
+	like := Like{
+		Blog:      blog,
+		Visitor:   visitor,
+		CreatedAt: time.Now(),
+	}
+
+	if _, err := r.collection.InsertOne(ctx, like); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+/*
+*
+
+	Accepts: context, blog (document ObjectID), visitor
+
+	Removes the visitor's like of the blog and returns false
+	when there was no like to remove.
+*/
+func (r *MongoLikeRepository) DeleteLike(ctx context.Context, blog bson.ObjectID, visitor string) (bool, error) {
+	filter := bson.M{"blog": blog, "visitor": visitor}
+
+	result, err := r.collection.DeleteOne(ctx, filter)
+	if err != nil {
+		return false, err
+	}
+
+	return result.DeletedCount > 0, nil
+}
+
+func (r *MongoLikeRepository) HasLiked(ctx context.Context, blog bson.ObjectID, visitor string) (bool, error) {
+	filter := bson.M{"blog": blog, "visitor": visitor}
+
+	count, err := r.collection.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (r *MongoLikeRepository) DeleteLikesByBlog(ctx context.Context, blog bson.ObjectID) (int, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"blog": blog})
+	if err != nil {
+		return 0, err
+	}
+
+	return int(result.DeletedCount), nil
+}
+
+/*
+*
+
+	Accepts: context, since
+
+	Looks up the blog and time of every like made since then.
+*/
+func (r *MongoLikeRepository) GetLikesSince(ctx context.Context, since time.Time) ([]Like, error) {
+	likes := []Like{}
+
+	filter := bson.M{"createdAt": bson.M{"$gte": since}}
+	opts := options.Find().SetProjection(bson.M{"blog": 1, "createdAt": 1})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return likes, err
+	}
+
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &likes); err != nil {
+		return likes, err
+	}
+
+	return likes, nil
+}
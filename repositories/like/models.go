@@ -0,0 +1,15 @@
+package like
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Like records that a visitor liked a blog, a visitor likes a blog at most once
+type Like struct {
+	ID        bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+	Blog      bson.ObjectID `bson:"blog" json:"blog"`
+	Visitor   string        `bson:"visitor" json:"-"` // user, cookie or fingerprint identity, see the visitor middleware
+	CreatedAt time.Time     `bson:"createdAt" json:"createdAt"`
+}
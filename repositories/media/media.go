@@ -0,0 +1,203 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const PAGE_SIZE = 24
+
+type MediaRepository interface {
+	SaveMedia(ctx context.Context, media *Media) (*Media, error)
+	GetMedia(ctx context.Context, id, uploader bson.ObjectID) (*Media, error)
+	GetMediaByUploader(ctx context.Context, uploader bson.ObjectID, offset int) ([]Media, bool, error)
+	DeleteMedia(ctx context.Context, id bson.ObjectID) (bool, error)
+	DeleteMediaByKey(ctx context.Context, key string) (bool, error)
+	GetKeysByKind(ctx context.Context, kind string) ([]string, error)
+	EnsureIndexes(ctx context.Context) error
+}
+
+type MongoMediaRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMediaRepository(db *mongo.Database) MediaRepository {
+	return &MongoMediaRepository{
+		collection: db.Collection("media"),
+	}
+}
+
+/*
+*
+
+	Accepts: context
+
+	Creates the unique key index uploads are recorded by and the
+	index an uploader's library is listed by.
+*/
+func (r *MongoMediaRepository) EnsureIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "key", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "uploader", Value: 1},
+				{Key: "createdAt", Value: -1},
+			},
+		},
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
+
+	return err
+}
+
+/*
+*
+
+	Accepts: context, media
+
+	Records an upload by its key. An upload replacing the object at
+	an existing key replaces the record and keeps its id.
+*/
+func (r *MongoMediaRepository) SaveMedia(ctx context.Context, media *Media) (*Media, error) {
+	if media.CreatedAt.IsZero() {
+		media.CreatedAt = time.Now()
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"url":         media.URL,
+			"size":        media.Size,
+			"contentType": media.ContentType,
+			"width":       media.Width,
+			"height":      media.Height,
+			"kind":        media.Kind,
+			"variants":    media.Variants,
+			"uploader":    media.Uploader,
+			"createdAt":   media.CreatedAt,
+		},
+	}
+
+	opts := options.FindOneAndUpdate().
+		SetUpsert(true).
+		SetReturnDocument(options.After)
+
+	saved := new(Media)
+
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{"key": media.Key}, update, opts).Decode(saved)
+	if err != nil {
+		return nil, err
+	}
+
+	return saved, nil
+}
+
+func (r *MongoMediaRepository) GetMedia(ctx context.Context, id, uploader bson.ObjectID) (*Media, error) {
+	media := new(Media)
+
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "uploader": uploader}).Decode(media)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return media, nil
+}
+
+/*
+*
+
+	Accepts: context, uploader (user ObjectID), offset
+
+	Looks up the uploader's media, newest first, and returns a bool
+	indicating if there is more after the provided offset.
+*/
+func (r *MongoMediaRepository) GetMediaByUploader(ctx context.Context, uploader bson.ObjectID, offset int) ([]Media, bool, error) {
+	media := []Media{}
+
+	// fetch one extra document to learn if there are more
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(PAGE_SIZE + 1)).
+		SetSkip(int64(offset))
+
+	cursor, err := r.collection.Find(ctx, bson.M{"uploader": uploader}, opts)
+	if err != nil {
+		return media, false, err
+	}
+
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &media); err != nil {
+		return media, false, err
+	}
+
+	hasMore := len(media) > PAGE_SIZE
+	if hasMore {
+		media = media[:PAGE_SIZE]
+	}
+
+	return media, hasMore, nil
+}
+
+func (r *MongoMediaRepository) DeleteMedia(ctx context.Context, id bson.ObjectID) (bool, error) {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return false, err
+	}
+
+	return result.DeletedCount > 0, nil
+}
+
+func (r *MongoMediaRepository) DeleteMediaByKey(ctx context.Context, key string) (bool, error) {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"key": key})
+	if err != nil {
+		return false, err
+	}
+
+	return result.DeletedCount > 0, nil
+}
+
+/*
+*
+
+	Accepts: context, kind
+
+	Returns the keys of all media of the kind along with the
+	keys of their resized variants.
+*/
+func (r *MongoMediaRepository) GetKeysByKind(ctx context.Context, kind string) ([]string, error) {
+	keys := []string{}
+
+	opts := options.Find().SetProjection(bson.M{"key": 1, "variants": 1})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"kind": kind}, opts)
+	if err != nil {
+		return keys, err
+	}
+
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var media Media
+		if err := cursor.Decode(&media); err != nil {
+			return keys, err
+		}
+
+		keys = append(keys, media.Key)
+		keys = append(keys, media.Variants...)
+	}
+
+	return keys, cursor.Err()
+}
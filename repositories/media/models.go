@@ -0,0 +1,54 @@
+package media
+
+import (
+	br "blog-api/repositories/blog"
+	"mime/multipart"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	KIND_FEATURED = "featured"
+	KIND_PROFILE  = "profile"
+	KIND_LIBRARY  = "library"
+	KIND_BODY     = "body"
+)
+
+// Media records an uploaded object, the key is unique so uploading to
+// the same key again replaces the record
+type Media struct {
+	ID          bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+	Key         string        `bson:"key" json:"key"`
+	URL         string        `bson:"url" json:"url"`
+	Size        int64         `bson:"size" json:"size"`
+	ContentType string        `bson:"contentType" json:"contentType"`
+	Width       int           `bson:"width,omitempty" json:"width,omitempty"`
+	Height      int           `bson:"height,omitempty" json:"height,omitempty"`
+	Kind        string        `bson:"kind" json:"kind"`
+	Variants    []string      `bson:"variants,omitempty" json:"-"` // keys of resized copies removed with the media
+	Uploader    bson.ObjectID `bson:"uploader" json:"uploader"`
+	CreatedAt   time.Time     `bson:"createdAt" json:"createdAt"`
+}
+
+// Media library POST payload
+type MediaUploadInput struct {
+	Image      *multipart.FileHeader `form:"image"`
+	ImageBytes []byte                `form:"imageData"`
+}
+
+type MediaIndexResponse struct {
+	Media   []Media `json:"media"`
+	HasMore bool    `json:"hasMore"`
+}
+
+type MediaResponse struct {
+	Media *Media `json:"media"`
+}
+
+// MediaDeleteResponse lists the blogs using media that can't be deleted
+type MediaDeleteResponse struct {
+	Affected   int                 `json:"affected"`
+	References []br.ImageReference `json:"references,omitempty"`
+	Error      string              `json:"error,omitempty"`
+}
@@ -0,0 +1,67 @@
+package revision
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Revision is an immutable snapshot of a blog post taken
+// right before the post is overwritten by an update
+type Revision struct {
+	ID            bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+	Blog          bson.ObjectID `bson:"blog" json:"blog"`
+	Author        bson.ObjectID `bson:"author" json:"author"`
+	Categories    []string      `bson:"categories" json:"categories"`
+	Title         string        `bson:"title" json:"title"`
+	Text          string        `bson:"text" json:"text"`
+	Markdown      string        `bson:"markdown,omitempty" json:"markdown,omitempty"`
+	Slug          string        `bson:"slug" json:"slug"`
+	ImageLocation string        `bson:"featuredImageLocation" json:"featuredImageLocation"`
+	ImageKey      string        `bson:"featuredImageKey" json:"featuredImageKey"`
+	Published     bool          `bson:"published" json:"published"`
+	CreatedAt     time.Time     `bson:"createdAt" json:"createdAt"`
+}
+
+// RevisionMinimum omits the post body for revision listings
+type RevisionMinimum struct {
+	ID         bson.ObjectID `bson:"_id,omitempty" json:"_id"`
+	Blog       bson.ObjectID `bson:"blog" json:"blog"`
+	Categories []string      `bson:"categories" json:"categories"`
+	Title      string        `bson:"title" json:"title"`
+	Published  bool          `bson:"published" json:"published"`
+	CreatedAt  time.Time     `bson:"createdAt" json:"createdAt"`
+}
+
+type RevisionListResponse struct {
+	Revisions []RevisionMinimum `json:"revisions"`
+}
+
+type RevisionResponse struct {
+	Revision *Revision `json:"revision"`
+}
+
+// DiffLine is a single block of a revision's text and
+// whether it was kept, added or removed between revisions
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+type FieldChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type CategoryChange struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+type RevisionDiffResponse struct {
+	From       bson.ObjectID   `json:"from"`
+	To         bson.ObjectID   `json:"to"`
+	Title      *FieldChange    `json:"title"`
+	Categories *CategoryChange `json:"categories"`
+	Text       []DiffLine      `json:"text"`
+}
@@ -0,0 +1,184 @@
+package revision
+
+import (
+	br "blog-api/repositories/blog"
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type RevisionRepository interface {
+	CreateRevision(ctx context.Context, revision *Revision) (*Revision, error)
+	GetRevisionsByBlog(ctx context.Context, blog, author bson.ObjectID) ([]RevisionMinimum, error)
+	GetRevisionByID(ctx context.Context, id, blog, author bson.ObjectID) (*Revision, error)
+	GetImageUsage(ctx context.Context) ([]Revision, error)
+	GetImageReferences(ctx context.Context, keys []string) ([]Revision, error)
+	DeleteRevisionsByBlog(ctx context.Context, blog bson.ObjectID) (int64, error)
+}
+
+type MongoRevisionRepository struct {
+	collection *mongo.Collection
+}
+
+func NewRevisionRepository(db *mongo.Database) RevisionRepository {
+	return &MongoRevisionRepository{
+		collection: db.Collection("blogrevisions"),
+	}
+}
+
+/*
+*
+
+	Accepts: context, revision
+
+	Inserts a new snapshot. Revisions are never updated once
+	written so this is the only write the repository exposes.
+*/
+func (r *MongoRevisionRepository) CreateRevision(ctx context.Context, revision *Revision) (*Revision, error) {
+	revision.ID = bson.NilObjectID
+
+	result, err := r.collection.InsertOne(ctx, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	insertedID, ok := result.InsertedID.(bson.ObjectID)
+	if !ok {
+		return nil, fmt.Errorf("failed to convert inserted id to ObjectID")
+	}
+
+	revision.ID = insertedID
+
+	return revision, nil
+}
+
+/*
+*
+
+	Accepts: context, blog (document ObjectID), author (user ObjectID)
+
+	Looks up every revision of the provided blog owned by the author,
+	newest first, without the post body.
+*/
+func (r *MongoRevisionRepository) GetRevisionsByBlog(ctx context.Context, blog, author bson.ObjectID) ([]RevisionMinimum, error) {
+	revisions := []RevisionMinimum{}
+
+	filter := bson.M{
+		"blog":   blog,
+		"author": author,
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetProjection(bson.M{"text": 0})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return revisions, err
+	}
+
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &revisions); err != nil {
+		return revisions, err
+	}
+
+	return revisions, nil
+}
+
+func (r *MongoRevisionRepository) GetRevisionByID(ctx context.Context, id, blog, author bson.ObjectID) (*Revision, error) {
+	var revision *Revision
+
+	filter := bson.M{
+		"_id":    id,
+		"blog":   blog,
+		"author": author,
+	}
+
+	if err := r.collection.FindOne(ctx, filter).Decode(&revision); err != nil {
+		return revision, err
+	}
+
+	return revision, nil
+}
+
+/*
+*
+
+	Accepts: context
+
+	Looks up the text of every revision, the images of which a
+	restore would bring back.
+*/
+func (r *MongoRevisionRepository) GetImageUsage(ctx context.Context) ([]Revision, error) {
+	revisions := []Revision{}
+
+	opts := options.Find().SetProjection(bson.M{"text": 1})
+
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return revisions, err
+	}
+
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &revisions); err != nil {
+		return revisions, err
+	}
+
+	return revisions, nil
+}
+
+/*
+*
+
+	Accepts: context, storage keys
+
+	Looks up the revisions using any of the keys in their text, newest
+	first, without the post body. A revision's featured image isn't
+	restored so it doesn't count as a use.
+*/
+func (r *MongoRevisionRepository) GetImageReferences(ctx context.Context, keys []string) ([]Revision, error) {
+	revisions := []Revision{}
+
+	if len(keys) == 0 {
+		return revisions, nil
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetProjection(bson.M{"blog": 1, "title": 1, "slug": 1, "published": 1})
+
+	cursor, err := r.collection.Find(ctx, br.TextImageReferenceFilter(keys), opts)
+	if err != nil {
+		return revisions, err
+	}
+
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &revisions); err != nil {
+		return revisions, err
+	}
+
+	return revisions, nil
+}
+
+/*
+*
+
+	Accepts: context, blog (document ObjectID)
+
+	Removes every revision of a deleted blog, they can no longer
+	be restored.
+*/
+func (r *MongoRevisionRepository) DeleteRevisionsByBlog(ctx context.Context, blog bson.ObjectID) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"blog": blog})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
@@ -0,0 +1,26 @@
+package series
+
+import (
+	br "blog-api/repositories/blog"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// OrderBlogs returns the blogs in the order of the series posts,
+// dropping any post without a matching blog
+func OrderBlogs(posts []bson.ObjectID, blogs []br.BlogMinimum) []br.BlogMinimum {
+	ordered := []br.BlogMinimum{}
+
+	byID := make(map[bson.ObjectID]br.BlogMinimum, len(blogs))
+	for _, blog := range blogs {
+		byID[blog.ID] = blog
+	}
+
+	for _, id := range posts {
+		if blog, ok := byID[id]; ok {
+			ordered = append(ordered, blog)
+		}
+	}
+
+	return ordered
+}
@@ -0,0 +1,36 @@
+package series
+
+import (
+	br "blog-api/repositories/blog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Series is an author's ordered collection of blog posts
+type Series struct {
+	ID          bson.ObjectID   `bson:"_id,omitempty" json:"_id"`
+	Author      bson.ObjectID   `bson:"author" json:"author"`
+	Title       string          `bson:"title" json:"title"`
+	Description string          `bson:"description" json:"description"`
+	Posts       []bson.ObjectID `bson:"posts" json:"posts"`
+	CreatedAt   time.Time       `bson:"createdAt" json:"createdAt"`
+	UpdatedAt   time.Time       `bson:"updatedAt" json:"updatedAt"`
+}
+
+// Series POST payload
+type SeriesInput struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Posts       []string `json:"posts"`
+}
+
+type SeriesIndexResponse struct {
+	Series []Series `json:"series"`
+}
+
+// SeriesResponse carries the series with its posts in series order
+type SeriesResponse struct {
+	Series *Series          `json:"series"`
+	Posts  []br.BlogMinimum `json:"posts"`
+}
@@ -0,0 +1,182 @@
+package series
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type SeriesRepository interface {
+	GetSeriesIndex(ctx context.Context, filter bson.M) ([]Series, error)
+	GetSeriesByID(ctx context.Context, id bson.ObjectID) (*Series, error)
+	GetSeriesByPost(ctx context.Context, post bson.ObjectID) (*Series, error)
+	HasPostsInOtherSeries(ctx context.Context, posts []bson.ObjectID, exclude bson.ObjectID) (bool, error)
+	CreateSeries(ctx context.Context, series *Series) (*Series, error)
+	UpdateSeries(ctx context.Context, id, author bson.ObjectID, updates bson.M) (*Series, error)
+	DeleteSeries(ctx context.Context, id, author bson.ObjectID) (int, error)
+	RemovePost(ctx context.Context, post bson.ObjectID) error
+}
+
+type MongoSeriesRepository struct {
+	collection *mongo.Collection
+}
+
+func NewSeriesRepository(db *mongo.Database) SeriesRepository {
+	return &MongoSeriesRepository{
+		collection: db.Collection("blogseries"),
+	}
+}
+
+/*
+*
+
+	Accepts: context, filter
+
+	Looks up every series matching the filter, most
+	recently updated first.
+*/
+func (r *MongoSeriesRepository) GetSeriesIndex(ctx context.Context, filter bson.M) ([]Series, error) {
+	series := []Series{}
+
+	opts := options.Find().SetSort(bson.M{"updatedAt": -1})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return series, err
+	}
+
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &series); err != nil {
+		return series, err
+	}
+
+	return series, nil
+}
+
+func (r *MongoSeriesRepository) GetSeriesByID(ctx context.Context, id bson.ObjectID) (*Series, error) {
+	var series *Series
+
+	filter := bson.M{"_id": id}
+
+	if err := r.collection.FindOne(ctx, filter).Decode(&series); err != nil {
+		return series, err
+	}
+
+	return series, nil
+}
+
+/*
+*
+
+	Accepts: context, post (blog ObjectID)
+
+	Looks up the series containing the provided post.
+	Returns nil if the post is not part of a series.
+*/
+func (r *MongoSeriesRepository) GetSeriesByPost(ctx context.Context, post bson.ObjectID) (*Series, error) {
+	var series *Series
+
+	filter := bson.M{"posts": post}
+
+	if err := r.collection.FindOne(ctx, filter).Decode(&series); err != nil {
+		if err != mongo.ErrNoDocuments {
+			return series, err
+		}
+	}
+
+	return series, nil
+}
+
+/*
+*
+
+	Accepts: context, posts, exclude (series ObjectID)
+
+	Reports if any of the provided posts already belong to a series
+	other than the excluded one, since a post can only be part of a
+	single series.
+*/
+func (r *MongoSeriesRepository) HasPostsInOtherSeries(ctx context.Context, posts []bson.ObjectID, exclude bson.ObjectID) (bool, error) {
+	filter := bson.M{
+		"posts": bson.M{"$in": posts},
+		"_id":   bson.M{"$ne": exclude},
+	}
+
+	count, err := r.collection.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (r *MongoSeriesRepository) CreateSeries(ctx context.Context, series *Series) (*Series, error) {
+	now := time.Now()
+
+	series.CreatedAt = now
+	series.UpdatedAt = now
+
+	result, err := r.collection.InsertOne(ctx, series)
+	if err != nil {
+		return nil, err
+	}
+
+	insertedID, ok := result.InsertedID.(bson.ObjectID)
+	if !ok {
+		return nil, fmt.Errorf("failed to convert inserted id to ObjectID")
+	}
+
+	return r.GetSeriesByID(ctx, insertedID)
+}
+
+func (r *MongoSeriesRepository) UpdateSeries(ctx context.Context, id, author bson.ObjectID, updates bson.M) (*Series, error) {
+	var series *Series
+
+	filter := bson.M{
+		"_id":    id,
+		"author": author,
+	}
+
+	updates["updatedAt"] = time.Now()
+
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		filter,
+		bson.M{"$set": updates},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&series)
+	if err != nil {
+		return series, err
+	}
+
+	return series, nil
+}
+
+func (r *MongoSeriesRepository) DeleteSeries(ctx context.Context, id, author bson.ObjectID) (int, error) {
+	filter := bson.M{
+		"_id":    id,
+		"author": author,
+	}
+
+	result, err := r.collection.DeleteOne(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(result.DeletedCount), nil
+}
+
+// RemovePost pulls a deleted post out of any series it was part of
+func (r *MongoSeriesRepository) RemovePost(ctx context.Context, post bson.ObjectID) error {
+	filter := bson.M{"posts": post}
+	update := bson.M{"$pull": bson.M{"posts": post}}
+
+	_, err := r.collection.UpdateMany(ctx, filter, update)
+
+	return err
+}
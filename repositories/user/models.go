@@ -50,6 +50,12 @@ type UserWithID struct {
 	User `bson:",inline"`
 }
 
+// UserProfileImage is the stored file name of a user's profile image
+type UserProfileImage struct {
+	ID       bson.ObjectID `bson:"_id" json:"_id"`
+	ImageKey string        `bson:"profileImageKey" json:"-"`
+}
+
 // User with Password field
 type UserWithPassword struct {
 	UserWithID `bson:",inline"`
@@ -16,6 +16,7 @@ type UserRepository interface {
 	FindUser(ctx context.Context, payload UserLoginPost) (*UserWithPassword, error)
 	UpdateUserPassword(ctx context.Context, password string, user bson.ObjectID) (bool, error)
 	UpdateUser(ctx context.Context, authorId string, input *UserUpdatePost) (*User, error)
+	GetProfileImages(ctx context.Context) ([]UserProfileImage, error)
 }
 
 type MongoUserRepository struct {
@@ -118,3 +119,31 @@ func (r *MongoUserRepository) UpdateUser(ctx context.Context, authorId string, i
 
 	return user, nil
 }
+
+/*
+*
+
+	Accepts: context
+
+	Looks up the id and profile image key of every user with
+	a profile image.
+*/
+func (r *MongoUserRepository) GetProfileImages(ctx context.Context) ([]UserProfileImage, error) {
+	images := []UserProfileImage{}
+
+	filter := bson.M{"profileImageKey": bson.M{"$nin": bson.A{nil, ""}}}
+	opts := options.Find().SetProjection(bson.M{"profileImageKey": 1})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return images, err
+	}
+
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &images); err != nil {
+		return images, err
+	}
+
+	return images, nil
+}
@@ -0,0 +1,300 @@
+package analytics
+
+import (
+	r "blog-api/repositories/analytics"
+	br "blog-api/repositories/blog"
+	lr "blog-api/repositories/like"
+	su "blog-api/utilities/service"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	DEFAULT_RANGE_DAYS = 30
+	MAX_RANGE_DAYS     = 366
+	TOP_LIMIT          = 10
+)
+
+type AnalyticsService struct {
+	analyticsRepo r.AnalyticsRepository
+	blogRepo      br.BlogRepository
+	likeRepo      lr.LikeRepository
+	siteHost      string // views referred by the site itself count as direct
+
+	// trending blogs of each window, refreshed by RunTrendingRanker
+	trending atomic.Pointer[trendingRanking]
+}
+
+func NewAnalyticsService(
+	analyticsRepo r.AnalyticsRepository,
+	blogRepo br.BlogRepository,
+	likeRepo lr.LikeRepository,
+	siteURL string,
+) *AnalyticsService {
+	siteHost := ""
+	if parsed, err := url.Parse(siteURL); err == nil {
+		siteHost = normalizeHost(parsed.Hostname())
+	}
+
+	return &AnalyticsService{
+		analyticsRepo: analyticsRepo,
+		blogRepo:      blogRepo,
+		likeRepo:      likeRepo,
+		siteHost:      siteHost,
+	}
+}
+
+func (s *AnalyticsService) EnsureIndexes(ctx context.Context) error {
+	return s.analyticsRepo.EnsureIndexes(ctx)
+}
+
+/*
+RecordView counts a view of the blog in the background like the
+lifetime view counter. Views from bots are skipped and the visitor
+set by the visitor middleware is only stored as a hash with the day.
+*/
+func (s *AnalyticsService) RecordView(ctx context.Context, blog *br.BlogWithAuthor, userAgent, referrer string) {
+	if blog == nil || isBot(userAgent) {
+		return
+	}
+
+	visitor, ok := su.GetVisitorID(ctx)
+	if !ok {
+		return
+	}
+
+	slug := blog.Slug
+	day := startOfDay(time.Now())
+
+	view := &r.View{
+		Blog:     blog.ID,
+		Day:      day,
+		Visitor:  visitorHash(visitor, day),
+		Referrer: s.referrerDomain(referrer),
+	}
+
+	go func() {
+		recordCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		// the blog's author is embedded without an id, look it up to bucket by author
+		stored, err := s.blogRepo.GetBlogById(recordCtx, view.Blog)
+		if err != nil || stored == nil {
+			log.Printf("analytics: failed to lookup author of %s: %v", slug, err)
+			return
+		}
+
+		view.Author = stored.Author
+
+		if err := s.analyticsRepo.RecordView(recordCtx, view); err != nil {
+			log.Printf("analytics: failed to record view of %s: %v", slug, err)
+		}
+	}()
+}
+
+/*
+GetAnalytics returns the author's daily views between from and to,
+the last 30 days when unset, along with their most viewed posts and
+top referrers. When blogID is set the numbers only cover that blog,
+which has to be one of the author's.
+*/
+func (s *AnalyticsService) GetAnalytics(ctx context.Context, blogID string, from, to *time.Time) (r.AnalyticsResponse, error) {
+	var response r.AnalyticsResponse
+
+	userID, ok := su.GetAuthorID(ctx)
+	if !ok {
+		return response, fmt.Errorf("failed to access context values")
+	}
+
+	authorObjectID, err := bson.ObjectIDFromHex(userID)
+	if err != nil {
+		return response, err
+	}
+
+	q, err := analyticsRange(from, to, time.Now())
+	if err != nil {
+		return response, err
+	}
+
+	q.Author = authorObjectID
+
+	if blogID != "" {
+		blogObjectID, err := bson.ObjectIDFromHex(blogID)
+		if err != nil {
+			return response, err
+		}
+
+		blog, err := s.blogRepo.GetBlogByIdAndAuthor(ctx, blogObjectID, authorObjectID)
+		if err != nil {
+			return response, err
+		}
+
+		if blog == nil {
+			return response, fmt.Errorf("no blog %s by the author", blogID)
+		}
+
+		q.Blog = &blogObjectID
+	}
+
+	days, err := s.analyticsRepo.GetDailyViews(ctx, q)
+	if err != nil {
+		return response, err
+	}
+
+	posts, err := s.analyticsRepo.GetTopPosts(ctx, q, TOP_LIMIT)
+	if err != nil {
+		return response, err
+	}
+
+	if err := s.addPostDetails(ctx, posts); err != nil {
+		return response, err
+	}
+
+	referrers, err := s.analyticsRepo.GetTopReferrers(ctx, q, TOP_LIMIT)
+	if err != nil {
+		return response, err
+	}
+
+	response.From = q.From
+	response.To = q.To
+	response.Days = fillDays(days, q.From, q.To)
+	response.TopPosts = posts
+	response.TopReferrers = referrers
+
+	for _, day := range days {
+		response.Views += day.Views
+		response.Unique += day.Unique
+	}
+
+	return response, nil
+}
+
+// addPostDetails sets the title and slug of each post
+func (s *AnalyticsService) addPostDetails(ctx context.Context, posts []r.PostViews) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	ids := make([]bson.ObjectID, len(posts))
+	for i, post := range posts {
+		ids[i] = post.Blog
+	}
+
+	blogs, err := s.blogRepo.GetBlogsByIDs(ctx, ids, nil)
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[bson.ObjectID]br.BlogMinimum, len(blogs))
+	for _, blog := range blogs {
+		byID[blog.ID] = blog
+	}
+
+	for i := range posts {
+		blog := byID[posts[i].Blog]
+		posts[i].Title = blog.Title
+		posts[i].Slug = blog.Slug
+	}
+
+	return nil
+}
+
+// analyticsRange widens from and to to whole UTC days, defaulting
+// to the last DEFAULT_RANGE_DAYS days including today
+func analyticsRange(from, to *time.Time, now time.Time) (*r.AnalyticsQuery, error) {
+	q := new(r.AnalyticsQuery)
+
+	q.To = startOfDay(now).AddDate(0, 0, 1)
+	if to != nil {
+		q.To = startOfDay(*to)
+		if !q.To.Equal(to.UTC()) {
+			q.To = q.To.AddDate(0, 0, 1)
+		}
+	}
+
+	q.From = q.To.AddDate(0, 0, -DEFAULT_RANGE_DAYS)
+	if from != nil {
+		q.From = startOfDay(*from)
+	}
+
+	if !q.From.Before(q.To) {
+		return nil, fmt.Errorf("from must be before to")
+	}
+
+	if q.To.Sub(q.From) > MAX_RANGE_DAYS*24*time.Hour {
+		return nil, fmt.Errorf("the range can cover at most %d days", MAX_RANGE_DAYS)
+	}
+
+	return q, nil
+}
+
+// fillDays returns a day for every day from from until to,
+// with zero views for the days missing from days
+func fillDays(days []r.DailyViews, from, to time.Time) []r.DailyViews {
+	byDay := make(map[time.Time]r.DailyViews, len(days))
+	for _, day := range days {
+		byDay[day.Day.UTC()] = day
+	}
+
+	filled := []r.DailyViews{}
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		views, ok := byDay[day]
+		if !ok {
+			views = r.DailyViews{Day: day}
+		}
+
+		views.Day = day
+		filled = append(filled, views)
+	}
+
+	return filled
+}
+
+func startOfDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// visitorHash keeps unique views countable without storing the
+// visitor, the hash changes with the day so it can't follow them
+func visitorHash(visitor string, day time.Time) string {
+	sum := sha256.Sum256([]byte(day.Format(time.DateOnly) + "\n" + visitor))
+	return hex.EncodeToString(sum[:16])
+}
+
+// referrerDomain reduces a referring url to its domain, links from
+// the site itself and unparseable referrers count as direct views
+func (s *AnalyticsService) referrerDomain(referrer string) string {
+	referrer = strings.TrimSpace(referrer)
+	if referrer == "" {
+		return ""
+	}
+
+	if !strings.Contains(referrer, "://") {
+		referrer = "//" + referrer
+	}
+
+	parsed, err := url.Parse(referrer)
+	if err != nil {
+		return ""
+	}
+
+	host := normalizeHost(parsed.Hostname())
+	if host == s.siteHost || len(host) > 253 || !strings.Contains(host, ".") {
+		return ""
+	}
+
+	return host
+}
+
+func normalizeHost(host string) string {
+	return strings.TrimPrefix(strings.ToLower(host), "www.")
+}
@@ -0,0 +1,179 @@
+package analytics
+
+import (
+	r "blog-api/repositories/analytics"
+	lr "blog-api/repositories/like"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestIsBot(t *testing.T) {
+	bots := []string{
+		"",
+		"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+		"Mozilla/5.0 (compatible; bingbot/2.0; +http://www.bing.com/bingbot.htm)",
+		"facebookexternalhit/1.1",
+		"curl/8.4.0",
+		"Go-http-client/1.1",
+		"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) HeadlessChrome/120.0.0.0 Safari/537.36",
+	}
+
+	for _, agent := range bots {
+		if !isBot(agent) {
+			t.Errorf("expected %q to be a bot", agent)
+		}
+	}
+
+	readers := []string{
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1",
+		"Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0",
+	}
+
+	for _, agent := range readers {
+		if isBot(agent) {
+			t.Errorf("expected %q not to be a bot", agent)
+		}
+	}
+}
+
+func TestReferrerDomain(t *testing.T) {
+	service := NewAnalyticsService(nil, nil, nil, "https://www.example.com")
+
+	cases := map[string]string{
+		"":                                       "",
+		"https://news.ycombinator.com/item?id=1": "news.ycombinator.com",
+		"https://WWW.Google.com/":                "google.com",
+		"http://reddit.com:8080/r/golang":        "reddit.com",
+		"lobste.rs/s/abc":                        "lobste.rs",
+		"https://www.example.com/blog/other":     "",
+		"https://example.com/blog":               "",
+		"android-app://com.slack":                "com.slack",
+		"localhost":                              "",
+		"::not a url":                            "",
+	}
+
+	for referrer, expected := range cases {
+		if got := service.referrerDomain(referrer); got != expected {
+			t.Errorf("referrerDomain(%q) = %q, expected %q", referrer, got, expected)
+		}
+	}
+}
+
+func TestAnalyticsRange(t *testing.T) {
+	now := time.Date(2025, 3, 10, 15, 30, 0, 0, time.UTC)
+
+	q, err := analyticsRange(nil, nil, now)
+	if err != nil {
+		t.Fatalf("default range failed: %v", err)
+	}
+
+	if !q.To.Equal(time.Date(2025, 3, 11, 0, 0, 0, 0, time.UTC)) || !q.From.Equal(time.Date(2025, 2, 9, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected default range %v - %v", q.From, q.To)
+	}
+
+	// times are widened to whole days
+	from := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 3, 2, 9, 0, 0, 0, time.UTC)
+
+	q, err = analyticsRange(&from, &to, now)
+	if err != nil {
+		t.Fatalf("range failed: %v", err)
+	}
+
+	if !q.From.Equal(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)) || !q.To.Equal(time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected range %v - %v", q.From, q.To)
+	}
+
+	tooLong := now.AddDate(-2, 0, 0)
+	if _, err := analyticsRange(&tooLong, nil, now); err == nil {
+		t.Error("expected a range over the limit to fail")
+	}
+}
+
+func TestFillDays(t *testing.T) {
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 3)
+
+	days := fillDays([]r.DailyViews{{Day: from.AddDate(0, 0, 1), Views: 4, Unique: 2}}, from, to)
+
+	if len(days) != 3 {
+		t.Fatalf("expected 3 days, got %d", len(days))
+	}
+
+	if days[0].Views != 0 || days[1].Views != 4 || days[1].Unique != 2 || days[2].Views != 0 {
+		t.Errorf("unexpected days %+v", days)
+	}
+
+	if !days[2].Day.Equal(from.AddDate(0, 0, 2)) {
+		t.Errorf("unexpected last day %v", days[2].Day)
+	}
+}
+
+func TestVisitorHash(t *testing.T) {
+	day := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	if visitorHash("visitor:abc", day) != visitorHash("visitor:abc", day) {
+		t.Error("visitor hash is not stable within a day")
+	}
+
+	if visitorHash("visitor:abc", day) == visitorHash("visitor:abc", day.AddDate(0, 0, 1)) {
+		t.Error("visitor hash did not change with the day")
+	}
+}
+
+func TestTrendingScores(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	today := startOfDay(now)
+
+	burst := bson.NewObjectIDFromTimestamp(now.Add(-2 * time.Hour))
+	steady := bson.NewObjectIDFromTimestamp(now.Add(-time.Hour))
+	old := bson.NewObjectIDFromTimestamp(now)
+
+	views := []r.BlogDayViews{
+		{Blog: burst, Day: today, Unique: 40},
+		{Blog: steady, Day: today.AddDate(0, 0, -5), Unique: 60},
+		{Blog: old, Day: today.AddDate(0, 0, -40), Unique: 5000},
+	}
+
+	likes := []lr.Like{
+		{Blog: steady, CreatedAt: now.Add(-3 * time.Hour)},
+		{Blog: old, CreatedAt: now.AddDate(0, -3, 0)},
+	}
+
+	week := topTrending(trendingScores(views, likes, trendingWindows[TRENDING_WEEK], now), MAX_TRENDING)
+	if len(week) != 2 || week[0] != burst || week[1] != steady {
+		t.Errorf("unexpected weekly ranking %v", week)
+	}
+
+	day := topTrending(trendingScores(views, likes, trendingWindows[TRENDING_DAY], now), MAX_TRENDING)
+	if len(day) != 2 || day[0] != burst {
+		t.Errorf("unexpected daily ranking %v", day)
+	}
+
+	// activity outside of every window never trends
+	month := trendingScores(views, likes, trendingWindows[TRENDING_MONTH], now)
+	if month[old] != 0 {
+		t.Errorf("expected activity outside of the window to be ignored, got %v", month[old])
+	}
+
+	if top := topTrending(month, 1); len(top) != 1 {
+		t.Errorf("expected the ranking to be limited, got %v", top)
+	}
+}
+
+func TestDecay(t *testing.T) {
+	if got := decay(0, time.Hour); got != 1 {
+		t.Errorf("decay of new activity = %v", got)
+	}
+
+	if got := decay(2*time.Hour, time.Hour); got != 0.25 {
+		t.Errorf("decay after two half lives = %v", got)
+	}
+
+	if got := decay(-time.Hour, time.Hour); got != 1 {
+		t.Errorf("decay of future activity = %v", got)
+	}
+}
@@ -0,0 +1,51 @@
+package analytics
+
+import "strings"
+
+// user agent fragments of crawlers, link previews, monitors and
+// http libraries, matched case insensitively
+var botAgents = []string{
+	"bot",
+	"crawl",
+	"spider",
+	"slurp",
+	"archiver",
+	"facebookexternalhit",
+	"embedly",
+	"preview",
+	"monitor",
+	"uptime",
+	"pingdom",
+	"lighthouse",
+	"headless",
+	"phantomjs",
+	"curl",
+	"wget",
+	"python-requests",
+	"python-urllib",
+	"go-http-client",
+	"java/",
+	"okhttp",
+	"axios",
+	"node-fetch",
+	"httpclient",
+	"feedfetcher",
+	"rss",
+}
+
+// isBot reports whether a user agent belongs to an automated client,
+// requests without a user agent are treated as one
+func isBot(userAgent string) bool {
+	userAgent = strings.ToLower(strings.TrimSpace(userAgent))
+	if userAgent == "" {
+		return true
+	}
+
+	for _, fragment := range botAgents {
+		if strings.Contains(userAgent, fragment) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,236 @@
+package analytics
+
+import (
+	r "blog-api/repositories/analytics"
+	br "blog-api/repositories/blog"
+	lr "blog-api/repositories/like"
+	"context"
+	"log"
+	"math"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	TRENDING_DAY   = "day"
+	TRENDING_WEEK  = "week"
+	TRENDING_MONTH = "month"
+
+	DEFAULT_TRENDING     = 10
+	MAX_TRENDING         = 50
+	TRENDING_LIKE_WEIGHT = 5 // a like counts as much as this many unique views
+)
+
+// trendingWindow is how far back a ranking looks and how quickly
+// activity within it loses weight
+type trendingWindow struct {
+	length   time.Duration
+	halfLife time.Duration
+}
+
+var trendingWindows = map[string]trendingWindow{
+	TRENDING_DAY:   {length: 24 * time.Hour, halfLife: 6 * time.Hour},
+	TRENDING_WEEK:  {length: 7 * 24 * time.Hour, halfLife: 2 * 24 * time.Hour},
+	TRENDING_MONTH: {length: 30 * 24 * time.Hour, halfLife: 7 * 24 * time.Hour},
+}
+
+// trendingRanking is the most trending published blogs of each window
+type trendingRanking struct {
+	updatedAt time.Time
+	windows   map[string][]r.TrendingBlog
+}
+
+func ValidTrendingWindow(window string) bool {
+	_, ok := trendingWindows[window]
+	return ok
+}
+
+/*
+GetTrending returns up to limit of the published blogs trending within
+the window, as of the last time the ranking was refreshed. The list is
+empty until the first refresh completes.
+*/
+func (s *AnalyticsService) GetTrending(window string, limit int) r.TrendingResponse {
+	if limit < 1 || limit > MAX_TRENDING {
+		limit = DEFAULT_TRENDING
+	}
+
+	response := r.TrendingResponse{
+		Window: window,
+		Blogs:  []r.TrendingBlog{},
+	}
+
+	ranking := s.trending.Load()
+	if ranking == nil {
+		return response
+	}
+
+	blogs := ranking.windows[window]
+	if len(blogs) > limit {
+		blogs = blogs[:limit]
+	}
+
+	response.UpdatedAt = ranking.updatedAt
+	response.Blogs = append(response.Blogs, blogs...)
+
+	return response
+}
+
+/*
+RunTrendingRanker refreshes the trending rankings once on start and
+then on every interval until the provided context is cancelled, so
+requests only ever read the last ranking.
+*/
+func (s *AnalyticsService) RunTrendingRanker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		refreshCtx, cancel := context.WithTimeout(ctx, time.Minute)
+		if err := s.RefreshTrending(refreshCtx); err != nil && ctx.Err() == nil {
+			log.Printf("trending ranker: %v", err)
+		}
+		cancel()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+/*
+RefreshTrending scores every blog viewed or liked within the longest
+window. Unique views and likes are weighted by how long ago they
+happened, halving every half life of the window, so a burst of recent
+activity outranks a large but old view count.
+*/
+func (s *AnalyticsService) RefreshTrending(ctx context.Context) error {
+	now := time.Now()
+
+	var longest time.Duration
+	for _, window := range trendingWindows {
+		longest = max(longest, window.length)
+	}
+
+	views, err := s.analyticsRepo.GetViewsSince(ctx, now.Add(-longest))
+	if err != nil {
+		return err
+	}
+
+	likes, err := s.likeRepo.GetLikesSince(ctx, now.Add(-longest))
+	if err != nil {
+		return err
+	}
+
+	ranked := make(map[string][]bson.ObjectID, len(trendingWindows))
+	scores := make(map[string]map[bson.ObjectID]float64, len(trendingWindows))
+	ids := []bson.ObjectID{}
+
+	for name, window := range trendingWindows {
+		scores[name] = trendingScores(views, likes, window, now)
+		ranked[name] = topTrending(scores[name], MAX_TRENDING)
+		ids = append(ids, ranked[name]...)
+	}
+
+	blogs := []br.BlogMinimum{}
+	if len(ids) > 0 {
+		blogs, err = s.blogRepo.GetBlogsByIDs(ctx, ids, bson.M{"published": true})
+		if err != nil {
+			return err
+		}
+	}
+
+	byID := make(map[bson.ObjectID]br.BlogMinimum, len(blogs))
+	for _, blog := range blogs {
+		byID[blog.ID] = blog
+	}
+
+	ranking := &trendingRanking{
+		updatedAt: now,
+		windows:   make(map[string][]r.TrendingBlog, len(trendingWindows)),
+	}
+
+	for name, ids := range ranked {
+		trending := []r.TrendingBlog{}
+
+		for _, id := range ids {
+			// drafts and deleted blogs are left out
+			if blog, ok := byID[id]; ok {
+				trending = append(trending, r.TrendingBlog{BlogMinimum: blog, Score: scores[name][id]})
+			}
+		}
+
+		ranking.windows[name] = trending
+	}
+
+	s.trending.Store(ranking)
+
+	return nil
+}
+
+// trendingScores sums the decayed unique views and likes of each
+// blog within the window. Views are bucketed by day, so they are
+// aged from the middle of their day.
+func trendingScores(views []r.BlogDayViews, likes []lr.Like, window trendingWindow, now time.Time) map[bson.ObjectID]float64 {
+	start := now.Add(-window.length)
+	scores := map[bson.ObjectID]float64{}
+
+	for _, view := range views {
+		if !view.Day.Add(24 * time.Hour).After(start) {
+			continue
+		}
+
+		viewedAt := view.Day.Add(12 * time.Hour)
+		if viewedAt.After(now) {
+			viewedAt = now
+		}
+
+		scores[view.Blog] += float64(view.Unique) * decay(now.Sub(viewedAt), window.halfLife)
+	}
+
+	for _, like := range likes {
+		if like.CreatedAt.Before(start) {
+			continue
+		}
+
+		scores[like.Blog] += TRENDING_LIKE_WEIGHT * decay(now.Sub(like.CreatedAt), window.halfLife)
+	}
+
+	return scores
+}
+
+// decay halves the weight of activity every half life
+func decay(age, halfLife time.Duration) float64 {
+	return math.Pow(0.5, max(age, 0).Hours()/halfLife.Hours())
+}
+
+// topTrending returns the ids of the limit highest scores, highest first
+func topTrending(scores map[bson.ObjectID]float64, limit int) []bson.ObjectID {
+	ids := make([]bson.ObjectID, 0, len(scores))
+	for id, score := range scores {
+		if score > 0 {
+			ids = append(ids, id)
+		}
+	}
+
+	slices.SortFunc(ids, func(a, b bson.ObjectID) int {
+		if scores[a] != scores[b] {
+			if scores[a] > scores[b] {
+				return -1
+			}
+			return 1
+		}
+
+		return a.Timestamp().Compare(b.Timestamp())
+	})
+
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+
+	return ids
+}
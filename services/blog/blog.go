@@ -2,30 +2,66 @@ package blog
 
 import (
 	ck "blog-api/contextkeys"
+	"blog-api/objectstore"
 	r "blog-api/repositories/blog"
-	"blog-api/s3"
+	cr "blog-api/repositories/comment"
+	lr "blog-api/repositories/like"
+	rr "blog-api/repositories/revision"
+	sr "blog-api/repositories/series"
+	ms "blog-api/services/media"
 	"context"
 	"fmt"
-	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
 
-	"github.com/microcosm-cc/bluemonday"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 type BlogService struct {
-	blogRepo r.BlogRepository
+	blogRepo     r.BlogRepository
+	revisionRepo rr.RevisionRepository
+	seriesRepo   sr.SeriesRepository
+	commentRepo  cr.CommentRepository
+	likeRepo     lr.LikeRepository
+	mediaService *ms.MediaService
+	store        objectstore.ObjectStore
+
+	// prefix index of published titles, categories and authors
+	suggestions   atomic.Pointer[suggestionIndex]
+	suggestionsMu sync.Mutex
+
+	// tf-idf vectors of published blogs for related blogs
+	related   atomic.Pointer[relatedIndex]
+	relatedMu sync.Mutex
 }
 
-func NewBlogService(repo r.BlogRepository) *BlogService {
-	return &BlogService{blogRepo: repo}
+func NewBlogService(
+	repo r.BlogRepository,
+	revisionRepo rr.RevisionRepository,
+	seriesRepo sr.SeriesRepository,
+	commentRepo cr.CommentRepository,
+	likeRepo lr.LikeRepository,
+	mediaService *ms.MediaService,
+	store objectstore.ObjectStore,
+) *BlogService {
+	return &BlogService{
+		blogRepo:     repo,
+		revisionRepo: revisionRepo,
+		seriesRepo:   seriesRepo,
+		commentRepo:  commentRepo,
+		likeRepo:     likeRepo,
+		mediaService: mediaService,
+		store:        store,
+	}
 }
 
 func (s *BlogService) GetBlogIndex(ctx context.Context, q *r.BlogQuery) (r.BlogIndexResponse, error) {
-	blogs, hasMore, err := s.blogRepo.GetBlogIndex(ctx, q)
+	blogs, page, err := s.blogRepo.GetBlogIndex(ctx, q)
 
 	reponse := r.BlogIndexResponse{
-		Blogs:   blogs,
-		HasMore: hasMore,
+		Blogs:    blogs,
+		BlogPage: page,
 	}
 
 	return reponse, err
@@ -38,11 +74,11 @@ func (s *BlogService) GetBlogsByUser(ctx context.Context, q *r.BlogQuery, userID
 	if err != nil {
 		return response, err
 	}
-	blogs, hasMore, err := s.blogRepo.GetBlogsByUser(ctx, q, userObjectID)
+	blogs, page, err := s.blogRepo.GetBlogsByUser(ctx, q, userObjectID)
 
 	reponse := r.BlogIndexResponse{
-		Blogs:   blogs,
-		HasMore: hasMore,
+		Blogs:    blogs,
+		BlogPage: page,
 	}
 
 	return reponse, err
@@ -70,9 +106,16 @@ func (s *BlogService) GetBlogBySlug(ctx context.Context, slug string) (r.SingleB
 		return response, err
 	}
 
+	series, err := s.getSeriesNavigation(ctx, blog.ID)
+	if err != nil {
+		return response, err
+	}
+
 	response.Blog = blog
 	response.Next = nextBlog
 	response.Previous = previousBlog
+	response.Series = series
+	response.TableOfContents = tableOfContents(blog)
 
 	s.blogRepo.IncrementViewCount(blog.Slug)
 
@@ -100,6 +143,7 @@ func (s *BlogService) GetRandomBlog(ctx context.Context) (r.SingleBlogResponse,
 	response.Blog = blog
 	response.Next = nextBlog
 	response.Previous = previousBlog
+	response.TableOfContents = tableOfContents(blog)
 
 	s.blogRepo.IncrementViewCount(blog.Slug)
 
@@ -108,13 +152,13 @@ func (s *BlogService) GetRandomBlog(ctx context.Context) (r.SingleBlogResponse,
 
 func (s *BlogService) GetBlogsByCategory(ctx context.Context, category string, q *r.BlogQuery) (r.BlogIndexResponse, error) {
 	response := r.BlogIndexResponse{}
-	blogs, hasMore, err := s.blogRepo.GetBlogsByCategory(ctx, category, q)
+	blogs, page, err := s.blogRepo.GetBlogsByCategory(ctx, category, q)
 	if err != nil {
 		return response, err
 	}
 
 	response.Blogs = blogs
-	response.HasMore = hasMore
+	response.BlogPage = page
 
 	return response, err
 }
@@ -122,12 +166,12 @@ func (s *BlogService) GetBlogsByCategory(ctx context.Context, category string, q
 func (s *BlogService) GetDraftsByUser(ctx context.Context, q *r.BlogQuery) (r.BlogIndexResponse, error) {
 	response := r.BlogIndexResponse{}
 
-	blogs, hasMore, err := s.blogRepo.GetDraftsByUser(ctx, q)
+	blogs, page, err := s.blogRepo.GetDraftsByUser(ctx, q)
 	if err != nil {
 		return response, err
 	}
 
-	response.HasMore = hasMore
+	response.BlogPage = page
 	response.Blogs = blogs
 
 	return response, nil
@@ -154,95 +198,229 @@ func (s *BlogService) GetDraftByUser(ctx context.Context, slug string) (r.Single
 	response.Blog = blog
 	response.Next = nextBlog
 	response.Previous = previousBlog
+	response.TableOfContents = tableOfContents(blog)
 
 	return response, nil
 }
 
-func (s *BlogService) GetBlogsBySearchQuery(ctx context.Context, searchQuery string, q *r.BlogQuery) (r.BlogIndexResponse, error) {
-	response := r.BlogIndexResponse{}
+func (s *BlogService) UpdateBlog(ctx context.Context, input *r.UpdateBlogInput) (r.BlogUpdateResponse, error) {
+	var response r.BlogUpdateResponse
 
-	blogs, hasMore, err := s.blogRepo.GetBlogsBySearchQuery(ctx, searchQuery, q)
-	if err != nil {
+	// if a file was included process first
+	if input.Image != nil && input.Image.Size > 0 {
+		authorID, ok := ctx.Value(ck.UserIDKey).(string)
+		if !ok {
+			return response, fmt.Errorf("user id missing in context")
+		}
+
+		// store the image and its resized variants
+		if err := s.uploadFeaturedImage(ctx, &input.BaseBlogInput, authorID); err != nil {
+			return response, err
+		}
+	}
+
+	// render markdown or sanitize input text html
+	if err := prepareText(&input.BaseBlogInput); err != nil {
 		return response, err
 	}
 
-	response.HasMore = hasMore
-	response.Blogs = blogs
+	applySchedule(&input.BaseBlogInput)
 
-	return response, nil
-}
+	if len(input.Categories) > 0 {
+		input.Categories = r.NormalizeCategories(input.Categories)
+	}
 
-func (s *BlogService) LikeBlog(ctx context.Context, id string) (r.BlogUpdateResponse, error) {
-	var response r.BlogUpdateResponse
+	if err := s.prepareSlugChange(ctx, input); err != nil {
+		return response, err
+	}
+
+	// snapshot the current document before it is overwritten
+	if err := s.snapshotBlog(ctx, input.ID); err != nil {
+		return response, err
+	}
 
-	blog, err := s.blogRepo.LikeBlog(ctx, id)
+	blog, err := s.blogRepo.UpdateBlog(ctx, input)
 	if err != nil {
 		return response, err
 	}
 
+	s.refreshCachesAsync()
+
 	response.Blog = blog
 
 	return response, nil
 }
 
-func (s *BlogService) UpdateBlog(ctx context.Context, input *r.UpdateBlogInput) (r.BlogUpdateResponse, error) {
-	var response r.BlogUpdateResponse
+/*
+prepareSlugChange checks a new slug is free and moves the blog's
+current slug into its slug history, so links to it can redirect.
+A blog may take back one of its own retired slugs.
+*/
+func (s *BlogService) prepareSlugChange(ctx context.Context, input *r.UpdateBlogInput) error {
+	if input.Slug == "" {
+		return nil
+	}
 
-	// if a file was included process first
-	if input.Image != nil && input.Image.Size > 0 {
-		authorID, ok := ctx.Value(ck.UserIDKey).(string)
-		if !ok {
-			return response, fmt.Errorf("user id missing in context")
-		}
+	input.Slug = generateSlug(input.Slug)
+	if input.Slug == "" {
+		return fmt.Errorf("a slug needs at least one letter or digit")
+	}
+
+	blog, err := s.getAuthorBlog(ctx, input.ID)
+	if err != nil {
+		return err
+	}
+
+	if input.Slug == blog.Slug {
+		input.Slug = ""
+		return nil
+	}
 
-		url, err := s3.UploadToS3(input.Image, input.ImageBytes, authorID)
+	if !slices.Contains(blog.PreviousSlugs, input.Slug) {
+		isAvailable, err := s.slugAvailable(ctx, input.Slug)
 		if err != nil {
-			return response, err
+			return err
 		}
 
-		// set url and filename
-		input.ImageLocation = url
-		input.ImageKey = input.Image.Filename
+		if !isAvailable {
+			return fmt.Errorf("the slug %s is already taken or reserved", input.Slug)
+		}
 	}
 
-	// sanitize input text html
-	if input.Text != "" {
-		p := bluemonday.UGCPolicy()
+	previousSlugs := slices.DeleteFunc(slices.Clone(blog.PreviousSlugs), func(slug string) bool {
+		return slug == input.Slug
+	})
 
-		p.AllowAttrs("class", "data-language", "spellcheck").OnElements("pre", "span", "code")
+	input.PreviousSlugs = append(previousSlugs, blog.Slug)
 
-		sanitized := p.Sanitize(input.Text)
+	return nil
+}
 
-		input.Text = sanitized
+// GetSlugRedirect returns the current slug of the published
+// blog that retired the slug, or an empty string
+func (s *BlogService) GetSlugRedirect(ctx context.Context, slug string) (string, error) {
+	blog, err := s.blogRepo.GetSlugRedirect(ctx, slug)
+	if err != nil || blog == nil {
+		return "", err
 	}
 
-	blog, err := s.blogRepo.UpdateBlog(ctx, input)
+	return blog.Slug, nil
+}
+
+/*
+GetBlogForEdit returns the author's blog along with the source
+the editor should load: the markdown for markdown posts and the
+html for everything else.
+*/
+func (s *BlogService) GetBlogForEdit(ctx context.Context, blogID string) (r.BlogEditResponse, error) {
+	var response r.BlogEditResponse
+
+	blog, err := s.getAuthorBlog(ctx, blogID)
 	if err != nil {
 		return response, err
 	}
 
 	response.Blog = blog
+	response.Format = FORMAT_HTML
+	response.Source = blog.Text
+
+	if blog.Format == FORMAT_MARKDOWN {
+		response.Format = FORMAT_MARKDOWN
+		response.Source = blog.Markdown
+	}
 
 	return response, nil
 }
 
+/*
+ValidateSlug previews the slug a title or slug is saved with and
+whether it is free. When it is taken or reserved by a route, the
+suggestion is the numbered variant a generated slug would use.
+*/
 func (s *BlogService) ValidateSlug(ctx context.Context, slug string) (r.SlugValidationResponse, error) {
 	var response r.SlugValidationResponse
 
-	isAvailable, err := s.blogRepo.ValidateSlug(ctx, slug)
-	fmt.Println("is available: ", isAvailable, err)
+	response.Slug = generateSlug(slug)
+	if response.Slug == "" {
+		return response, fmt.Errorf("a slug needs at least one letter or digit")
+	}
+
+	isAvailable, err := s.slugAvailable(ctx, response.Slug)
 	if err != nil {
 		return response, err
 	}
 
 	response.IsAvailable = isAvailable
+	response.Reserved = isReservedSlug(response.Slug)
+
+	response.Suggestion, err = s.uniqueSlug(ctx, response.Slug)
 
 	return response, err
 }
 
-func (s *BlogService) CreateBlog(ctx context.Context, input *r.CreateBlogInput) (r.BlogUpdateResponse, error) {
+// slugAvailable reports whether no blog or route uses the slug
+func (s *BlogService) slugAvailable(ctx context.Context, slug string) (bool, error) {
+	if slug == "" || isReservedSlug(slug) {
+		return false, nil
+	}
+
+	return s.blogRepo.ValidateSlug(ctx, slug)
+}
+
+// uniqueSlug returns the slug, or the slug with the lowest
+// numbered suffix, which is available
+func (s *BlogService) uniqueSlug(ctx context.Context, slug string) (string, error) {
 	safetyNet := 50
+
+	if slug == "" {
+		slug = FALLBACK_SLUG
+	}
+
+	candidate := slug
+
+	for i := 1; ; i++ {
+		isAvailable, err := s.slugAvailable(ctx, candidate)
+		if err != nil {
+			return "", err
+		}
+
+		if isAvailable {
+			return candidate, nil
+		}
+
+		// likelihood of this happening is slim, but just in case
+		if i == safetyNet {
+			return "", fmt.Errorf("could not generate a unique slug after %d attempts", safetyNet)
+		}
+
+		candidate = fmt.Sprintf("%s-%d", slug, i)
+	}
+}
+
+func (s *BlogService) CreateBlog(ctx context.Context, input *r.CreateBlogInput) (r.BlogUpdateResponse, error) {
 	var response r.BlogUpdateResponse
+
+	// settle the slug before anything is uploaded
+	if input.GenerateSlug {
+		slug, err := s.uniqueSlug(ctx, generateSlug(input.Title))
+		if err != nil {
+			return response, err
+		}
+
+		input.Slug = slug
+	} else if input.Slug != "" {
+		input.Slug = generateSlug(input.Slug)
+
+		isAvailable, err := s.slugAvailable(ctx, input.Slug)
+		if err != nil {
+			return response, err
+		}
+
+		if !isAvailable {
+			return response, fmt.Errorf("the slug %s is already taken or reserved", input.Slug)
+		}
+	}
+
 	// if a file was included process first
 	if input.Image != nil {
 		authorID, ok := ctx.Value(ck.UserIDKey).(string)
@@ -250,53 +428,21 @@ func (s *BlogService) CreateBlog(ctx context.Context, input *r.CreateBlogInput)
 			return response, fmt.Errorf("user id missing in context")
 		}
 
-		url, err := s3.UploadToS3(input.Image, input.ImageBytes, authorID)
-		if err != nil {
+		// store the image and its resized variants
+		if err := s.uploadFeaturedImage(ctx, &input.BaseBlogInput, authorID); err != nil {
 			return response, err
 		}
-
-		// set url and filename
-		input.ImageLocation = url
-		input.ImageKey = input.Image.Filename
 	}
 
-	if input.GenerateSlug {
-		originalSlug := generateSlug(input.Title)
-		fmt.Println(originalSlug)
-		slug := originalSlug
-		i := 1
-
-		for {
-			validationResponse, err := s.ValidateSlug(ctx, slug)
-			if err != nil {
-				fmt.Println("the error in service", err)
-				return response, err
-			}
-
-			if validationResponse.IsAvailable {
-				input.Slug = slug
-				break
-			}
-
-			// likelihood of this happening is slim, but just in case
-			if i == safetyNet {
-				return response, fmt.Errorf("could not generate a unique slug after %d attempts", safetyNet)
-			}
-
-			slug = fmt.Sprintf("%s-%d", originalSlug, i)
-			i++
-		}
+	// render markdown or sanitize input text html
+	if err := prepareText(&input.BaseBlogInput); err != nil {
+		return response, err
 	}
 
-	// sanitize input text html
-	if input.Text != "" {
-		p := bluemonday.UGCPolicy()
-
-		p.AllowAttrs("class", "data-language", "spellcheck").OnElements("pre", "span", "code")
-
-		sanitized := p.Sanitize(input.Text)
+	applySchedule(&input.BaseBlogInput)
 
-		input.Text = sanitized
+	if len(input.Categories) > 0 {
+		input.Categories = r.NormalizeCategories(input.Categories)
 	}
 
 	blog, err := s.blogRepo.CreateBlog(ctx, input)
@@ -304,6 +450,8 @@ func (s *BlogService) CreateBlog(ctx context.Context, input *r.CreateBlogInput)
 		return response, err
 	}
 
+	s.refreshCachesAsync()
+
 	response.Blog = blog
 
 	return response, nil
@@ -338,14 +486,11 @@ func (s *BlogService) DeleteImage(ctx context.Context, blogID string) (*r.Generi
 		return response, fmt.Errorf("the provded blog ID contains no featured image key")
 	}
 
-	err = s3.DeleteFromS3(imageKey)
-	if err != nil {
-		return response, err
-	}
-
 	updates := bson.M{
 		"featuredImageKey":      "",
 		"featuredImageLocation": "",
+		"featuredImageVariants": []r.ImageVariant{},
+		"featuredImageSrcset":   "",
 	}
 
 	additionalFilters := bson.M{
@@ -357,6 +502,12 @@ func (s *BlogService) DeleteImage(ctx context.Context, blogID string) (*r.Generi
 		return response, err
 	}
 
+	// the original and every resized variant, once the blog no longer
+	// uses them, along with their media record
+	if _, err := s.mediaService.DeleteUnreferenced(ctx, featuredImageKeys(blog)); err != nil {
+		return response, err
+	}
+
 	response.Affected = docsAffected
 	return response, nil
 }
@@ -384,30 +535,41 @@ func (s *BlogService) DeleteBlog(ctx context.Context, blogID string) (*r.Generic
 		return response, err
 	}
 
-	resources := os.Getenv("AWS_BUCKET")
-	imageSources, err := extraImageSourcesFromHTML(blog.Text, resources)
+	imageKeys, err := bodyImageKeys(s.store, blog.Text)
 	if err != nil {
 		return response, err
 	}
 
-	if len(imageSources) > 0 {
-		// delete images from s3
-		for _, src := range imageSources {
-			key := extractKeyFromImageSource(src, resources+".s3.amazonaws.com/")
-
-			if key != "" {
-				err := s3.DeleteFromS3(key)
-				if err != nil {
-					return response, err
-				}
-			}
-		}
-	}
 	affected, err := s.blogRepo.DeleteBlog(ctx, blogObjectID, authorObjectID)
 	if err != nil {
 		return response, err
 	}
 
+	// revisions of a deleted blog can't be restored, and would keep
+	// its images in use
+	if _, err := s.revisionRepo.DeleteRevisionsByBlog(ctx, blogObjectID); err != nil {
+		return response, err
+	}
+
+	// images are shared between blogs, only remove those no other blog uses
+	if _, err := s.mediaService.DeleteUnreferenced(ctx, imageKeys); err != nil {
+		return response, err
+	}
+
+	if err := s.seriesRepo.RemovePost(ctx, blogObjectID); err != nil {
+		return response, err
+	}
+
+	if _, err := s.commentRepo.DeleteCommentsByBlog(ctx, blogObjectID); err != nil {
+		return response, err
+	}
+
+	if _, err := s.likeRepo.DeleteLikesByBlog(ctx, blogObjectID); err != nil {
+		return response, err
+	}
+
+	s.refreshCachesAsync()
+
 	response.Affected = affected
 
 	return response, err
@@ -0,0 +1,29 @@
+package blog
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RefreshCaches rebuilds the in-memory suggestion and related blogs indexes
+func (s *BlogService) RefreshCaches(ctx context.Context) error {
+	if err := s.RefreshSuggestions(ctx); err != nil {
+		return err
+	}
+
+	return s.RefreshRelated(ctx)
+}
+
+// refreshCachesAsync rebuilds the in-memory indexes after a
+// write without holding up the request that made it
+func (s *BlogService) refreshCachesAsync() {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := s.RefreshCaches(ctx); err != nil {
+			log.Printf("caches: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,74 @@
+package blog
+
+import (
+	r "blog-api/repositories/blog"
+	su "blog-api/utilities/service"
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func (s *BlogService) GetCategories(ctx context.Context) (r.CategoryIndexResponse, error) {
+	var response r.CategoryIndexResponse
+
+	categories, err := s.blogRepo.GetCategories(ctx)
+	if err != nil {
+		return response, err
+	}
+
+	response.Categories = categories
+
+	return response, nil
+}
+
+// RenameCategory renames a category across all of the author's blogs
+func (s *BlogService) RenameCategory(ctx context.Context, category, name string) (*r.GenericUpdateResponse, error) {
+	return s.MergeCategories(ctx, &r.CategoryMergePost{
+		From: []string{category},
+		Into: name,
+	})
+}
+
+/*
+MergeCategories replaces each of the from categories with the into
+category across all of the author's blogs. A rename is a merge of a
+single category.
+*/
+func (s *BlogService) MergeCategories(ctx context.Context, input *r.CategoryMergePost) (*r.GenericUpdateResponse, error) {
+	response := new(r.GenericUpdateResponse)
+
+	from := r.NormalizeCategories(input.From)
+	into := r.NormalizeCategory(input.Into)
+
+	if len(from) == 0 || into == "" {
+		return response, fmt.Errorf("a source and target category are required")
+	}
+
+	userID, ok := su.GetAuthorID(ctx)
+	if !ok {
+		return response, fmt.Errorf("failed to access context values")
+	}
+
+	authorObjectID, err := bson.ObjectIDFromHex(userID)
+	if err != nil {
+		return response, err
+	}
+
+	affected, err := s.blogRepo.ReplaceCategories(ctx, authorObjectID, from, into)
+	if err != nil {
+		return response, err
+	}
+
+	s.refreshCachesAsync()
+
+	response.Affected = affected
+
+	return response, nil
+}
+
+// NormalizeStoredCategories normalizes the categories of blogs saved
+// before categories were normalized on write
+func (s *BlogService) NormalizeStoredCategories(ctx context.Context) (int, error) {
+	return s.blogRepo.NormalizeStoredCategories(ctx)
+}
@@ -0,0 +1,178 @@
+package blog
+
+import (
+	"blog-api/images"
+	"blog-api/objectstore"
+	r "blog-api/repositories/blog"
+	mr "blog-api/repositories/media"
+	ms "blog-api/services/media"
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const ORIGINAL_VARIANT = "original"
+
+/*
+uploadFeaturedImage stores the input's featured image and a resized
+variant for each of images.VARIANTS narrower than it, setting the
+image fields of the input and recording the upload in the author's
+media. Files that can't be decoded as an image are stored as
+uploaded without variants.
+*/
+func (s *BlogService) uploadFeaturedImage(ctx context.Context, input *r.BaseBlogInput, authorID string) error {
+	if err := uploadFeaturedImage(ctx, s.store, input, authorID); err != nil {
+		return err
+	}
+
+	uploader, err := bson.ObjectIDFromHex(authorID)
+	if err != nil {
+		return err
+	}
+
+	media := ms.NewMedia(input.ImageKey, input.ImageLocation, input.ImageBytes, mr.KIND_FEATURED, uploader)
+
+	for _, variant := range input.ImageVariants {
+		if variant.Key != input.ImageKey {
+			media.Variants = append(media.Variants, variant.Key)
+		}
+	}
+
+	return s.mediaService.RecordUpload(ctx, media)
+}
+
+func uploadFeaturedImage(ctx context.Context, store objectstore.ObjectStore, input *r.BaseBlogInput, authorID string) error {
+	key := objectstore.BuildKey(objectstore.FEATURED_IMAGES, authorID, input.Image.Filename)
+
+	url, err := store.Put(ctx, key, input.ImageBytes)
+	if err != nil {
+		return err
+	}
+
+	input.ImageLocation = url
+	input.ImageKey = key
+	input.ImageVariants = []r.ImageVariant{}
+	input.ImageSrcset = ""
+
+	img, _, err := images.Decode(input.ImageBytes)
+	if err != nil {
+		log.Printf("featured image %s stored without variants: %v", key, err)
+		return nil
+	}
+
+	variants, err := images.Resize(img, images.VARIANTS)
+	if err != nil {
+		return fmt.Errorf("failed to resize featured image: %w", err)
+	}
+
+	for _, variant := range variants {
+		variantKey := variantKey(key, variant.Name, variant.Extension)
+
+		variantURL, err := store.Put(ctx, variantKey, variant.Data)
+		if err != nil {
+			return err
+		}
+
+		input.ImageVariants = append(input.ImageVariants, r.ImageVariant{
+			Name:   variant.Name,
+			URL:    variantURL,
+			Key:    variantKey,
+			Width:  variant.Width,
+			Height: variant.Height,
+		})
+	}
+
+	input.ImageVariants = append(input.ImageVariants, r.ImageVariant{
+		Name:   ORIGINAL_VARIANT,
+		URL:    url,
+		Key:    key,
+		Width:  img.Bounds().Dx(),
+		Height: img.Bounds().Dy(),
+	})
+
+	input.ImageSrcset = srcset(input.ImageVariants)
+
+	return nil
+}
+
+// variantKey places a variant beside the original, photo.png
+// becomes photo-thumbnail.jpg
+func variantKey(key, name, extension string) string {
+	return strings.TrimSuffix(key, path.Ext(key)) + "-" + name + extension
+}
+
+// srcset lists the variants by width for an img srcset attribute
+func srcset(variants []r.ImageVariant) string {
+	candidates := make([]string, 0, len(variants))
+
+	for _, variant := range variants {
+		candidates = append(candidates, fmt.Sprintf("%s %dw", variant.URL, variant.Width))
+	}
+
+	return strings.Join(candidates, ", ")
+}
+
+/*
+featuredImageKeys returns the storage keys of a blog's featured image
+and all of its variants. Blogs saved before variants stored only the
+file name of the image, their key is rebuilt from the upload path.
+*/
+func featuredImageKeys(blog *r.Blog) []string {
+	keys := []string{}
+
+	for _, variant := range blog.ImageVariants {
+		keys = append(keys, variant.Key)
+	}
+
+	if len(keys) > 0 {
+		return keys
+	}
+
+	if blog.ImageKey == "" {
+		return keys
+	}
+
+	if !strings.Contains(blog.ImageKey, "/") {
+		return append(keys, objectstore.BuildKey(objectstore.FEATURED_IMAGES, blog.Author.Hex(), blog.ImageKey))
+	}
+
+	return append(keys, blog.ImageKey)
+}
+
+// bodyImageKeys returns the storage keys of the store's images
+// in the html text
+func bodyImageKeys(store objectstore.ObjectStore, text string) ([]string, error) {
+	keys := []string{}
+
+	// every source, the store recognizes the urls of its own objects
+	imageSources, err := extraImageSourcesFromHTML(text, "")
+	if err != nil {
+		return keys, err
+	}
+
+	for _, src := range imageSources {
+		if key, ok := store.KeyFromURL(src); ok {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+/*
+ReferencedKeys returns the storage keys of every image the blog uses,
+its featured image and variants and the images of its text. The blog
+needs its author, featured image fields and text.
+*/
+func ReferencedKeys(store objectstore.ObjectStore, blog *r.Blog) ([]string, error) {
+	keys, err := bodyImageKeys(store, blog.Text)
+	if err != nil {
+		return keys, err
+	}
+
+	return append(keys, featuredImageKeys(blog)...), nil
+}
@@ -0,0 +1,151 @@
+package blog
+
+import (
+	"blog-api/objectstore"
+	r "blog-api/repositories/blog"
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestVariantKey(t *testing.T) {
+	got := variantKey("featured_images/users/1/photo.png", "thumbnail", ".jpg")
+	want := "featured_images/users/1/photo-thumbnail.jpg"
+
+	if got != want {
+		t.Errorf("wanted %s, got %s", want, got)
+	}
+}
+
+func TestSrcset(t *testing.T) {
+	variants := []r.ImageVariant{
+		{Name: "thumbnail", URL: "https://cdn/a-thumbnail.jpg", Width: 320},
+		{Name: "original", URL: "https://cdn/a.png", Width: 1000},
+	}
+
+	got := srcset(variants)
+	want := "https://cdn/a-thumbnail.jpg 320w, https://cdn/a.png 1000w"
+
+	if got != want {
+		t.Errorf("wanted %s, got %s", want, got)
+	}
+}
+
+func TestFeaturedImageKeys(t *testing.T) {
+	author := bson.NewObjectID()
+
+	tests := []struct {
+		Name string
+		Blog r.Blog
+		Want []string
+	}{
+		{
+			Name: "variants",
+			Blog: r.Blog{
+				ImageKey: "featured_images/users/1/a.png",
+				ImageVariants: []r.ImageVariant{
+					{Key: "featured_images/users/1/a-thumbnail.jpg"},
+					{Key: "featured_images/users/1/a.png"},
+				},
+			},
+			Want: []string{"featured_images/users/1/a-thumbnail.jpg", "featured_images/users/1/a.png"},
+		},
+		{
+			Name: "legacy file name",
+			Blog: r.Blog{Author: author, ImageKey: "a.png"},
+			Want: []string{"featured_images/users/" + author.Hex() + "/a.png"},
+		},
+		{
+			Name: "no image",
+			Blog: r.Blog{},
+			Want: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		got := featuredImageKeys(&test.Blog)
+
+		if len(got) != len(test.Want) {
+			t.Errorf("%s: wanted %v, got %v", test.Name, test.Want, got)
+			continue
+		}
+
+		for i := range got {
+			if got[i] != test.Want[i] {
+				t.Errorf("%s: wanted %v, got %v", test.Name, test.Want, got)
+			}
+		}
+	}
+}
+
+func TestReferencedKeys(t *testing.T) {
+	store := objectstore.NewS3Store(context.Background(), "bucket", "us-east-1")
+
+	author := bson.NewObjectID()
+
+	blog := &r.Blog{
+		Author:   author,
+		ImageKey: "cover.png",
+		Text: `<p><img src="https://bucket.s3.amazonaws.com/canvas_1.png"></p>` +
+			`<img src="https://bucket.s3.us-east-1.amazonaws.com/media/users/1/my%20photo.png">` +
+			`<img src="https://elsewhere.com/outside.png">`,
+	}
+
+	keys, err := ReferencedKeys(store, blog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"canvas_1.png",
+		"media/users/1/my photo.png",
+		"featured_images/users/" + author.Hex() + "/cover.png",
+	}
+
+	if len(keys) != len(want) {
+		t.Fatalf("wanted %v, got %v", want, keys)
+	}
+
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Errorf("wanted %v, got %v", want, keys)
+		}
+	}
+}
+
+func TestUploadFeaturedImageVariants(t *testing.T) {
+	store := objectstore.NewMemoryStore("http://localhost:8080/files")
+
+	img := image.NewRGBA(image.Rect(0, 0, 800, 400))
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+
+	input := &r.BaseBlogInput{
+		Image:      &multipart.FileHeader{Filename: "my cover.png"},
+		ImageBytes: buf.Bytes(),
+	}
+
+	if err := uploadFeaturedImage(context.Background(), store, input, "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if input.ImageKey != "featured_images/users/1/my-cover.png" || input.ImageLocation != store.URL(input.ImageKey) {
+		t.Errorf("unexpected image %s at %s", input.ImageKey, input.ImageLocation)
+	}
+
+	// thumbnail and medium, large would upscale
+	if len(input.ImageVariants) != 3 {
+		t.Fatalf("wanted 2 variants and the original, got %+v", input.ImageVariants)
+	}
+
+	for _, variant := range input.ImageVariants {
+		if _, found := store.Get(variant.Key); !found {
+			t.Errorf("wanted %s stored", variant.Key)
+		}
+	}
+}
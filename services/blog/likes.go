@@ -0,0 +1,133 @@
+package blog
+
+import (
+	r "blog-api/repositories/blog"
+	su "blog-api/utilities/service"
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+/*
+LikeBlog records the visitor's like of a published blog and raises
+its rating. A visitor who already liked the blog gets the blog back
+unchanged with AlreadyLiked set. The response blog is nil when there
+is no published blog with the id.
+*/
+func (s *BlogService) LikeBlog(ctx context.Context, id string) (r.LikeResponse, error) {
+	var response r.LikeResponse
+
+	blogObjectID, visitor, err := likeTarget(ctx, id)
+	if err != nil {
+		return response, err
+	}
+
+	blog, err := s.blogRepo.GetBlogById(ctx, blogObjectID)
+	if err != nil || blog == nil || !blog.Published {
+		return response, err
+	}
+
+	created, err := s.likeRepo.CreateLike(ctx, blogObjectID, visitor)
+	if err != nil {
+		return response, err
+	}
+
+	response.Liked = true
+	response.AlreadyLiked = !created
+
+	if !created {
+		response.Blog = blog
+		return response, nil
+	}
+
+	blog, err = s.blogRepo.UpdateRating(ctx, blogObjectID, 1)
+	if err != nil {
+		return response, err
+	}
+
+	response.Blog = blog
+
+	return response, nil
+}
+
+/*
+UnlikeBlog removes the visitor's like of a published blog and lowers
+its rating. Unliking a blog the visitor never liked leaves the rating
+as is. The response blog is nil when there is no published blog with
+the id.
+*/
+func (s *BlogService) UnlikeBlog(ctx context.Context, id string) (r.LikeResponse, error) {
+	var response r.LikeResponse
+
+	blogObjectID, visitor, err := likeTarget(ctx, id)
+	if err != nil {
+		return response, err
+	}
+
+	blog, err := s.blogRepo.GetBlogById(ctx, blogObjectID)
+	if err != nil || blog == nil || !blog.Published {
+		return response, err
+	}
+
+	deleted, err := s.likeRepo.DeleteLike(ctx, blogObjectID, visitor)
+	if err != nil {
+		return response, err
+	}
+
+	response.AlreadyLiked = deleted
+
+	if !deleted {
+		response.Blog = blog
+		return response, nil
+	}
+
+	blog, err = s.blogRepo.UpdateRating(ctx, blogObjectID, -1)
+	if err != nil {
+		return response, err
+	}
+
+	response.Blog = blog
+
+	return response, nil
+}
+
+// GetLikeStatus reports whether the visitor has liked a published blog
+func (s *BlogService) GetLikeStatus(ctx context.Context, id string) (r.LikeResponse, error) {
+	var response r.LikeResponse
+
+	blogObjectID, visitor, err := likeTarget(ctx, id)
+	if err != nil {
+		return response, err
+	}
+
+	blog, err := s.blogRepo.GetBlogById(ctx, blogObjectID)
+	if err != nil || blog == nil || !blog.Published {
+		return response, err
+	}
+
+	liked, err := s.likeRepo.HasLiked(ctx, blogObjectID, visitor)
+	if err != nil {
+		return response, err
+	}
+
+	response.Blog = blog
+	response.Liked = liked
+	response.AlreadyLiked = liked
+
+	return response, nil
+}
+
+func likeTarget(ctx context.Context, id string) (bson.ObjectID, string, error) {
+	visitor, ok := su.GetVisitorID(ctx)
+	if !ok {
+		return bson.ObjectID{}, "", fmt.Errorf("failed to identify visitor")
+	}
+
+	blogObjectID, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return bson.ObjectID{}, "", err
+	}
+
+	return blogObjectID, visitor, nil
+}
@@ -0,0 +1,133 @@
+package blog
+
+import (
+	r "blog-api/repositories/blog"
+	"bytes"
+	"html"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer"
+	gmhtml "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/util"
+)
+
+const (
+	FORMAT_HTML     = "html"
+	FORMAT_MARKDOWN = "markdown"
+
+	// language used by the editor for code blocks without one
+	DEFAULT_CODE_LANGUAGE = "plain"
+)
+
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithRendererOptions(
+		// raw html is allowed through since the
+		// output is always sanitized afterwards
+		gmhtml.WithUnsafe(),
+		renderer.WithNodeRenderers(
+			util.Prioritized(&editorCodeRenderer{}, 100),
+		),
+	),
+)
+
+/*
+renderMarkdown converts markdown source into sanitized html. Code
+is rendered in the same shape the Quill editor produces so markdown
+and html posts display the same way.
+*/
+func renderMarkdown(source string) (string, error) {
+	var buf bytes.Buffer
+
+	if err := markdown.Convert([]byte(source), &buf); err != nil {
+		return "", err
+	}
+
+	return sanitizeHTML(buf.String()), nil
+}
+
+// prepareText fills the input's html text, rendering it from the
+// markdown source when one was submitted, and the fields derived from it
+func prepareText(input *r.BaseBlogInput) error {
+	if input.Markdown != "" {
+		rendered, err := renderMarkdown(input.Markdown)
+		if err != nil {
+			return err
+		}
+
+		input.Text = rendered
+		input.Format = FORMAT_MARKDOWN
+	} else if input.Text != "" {
+		input.Text = sanitizeHTML(input.Text)
+		input.Format = FORMAT_HTML
+	}
+
+	deriveText(input)
+
+	return nil
+}
+
+// editorCodeRenderer renders code blocks and inline code as
+// <pre class="ql-syntax" data-language> and <code class="inline-code">
+type editorCodeRenderer struct{}
+
+func (r *editorCodeRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, r.renderCodeBlock)
+	reg.Register(ast.KindCodeBlock, r.renderCodeBlock)
+	reg.Register(ast.KindCodeSpan, r.renderCodeSpan)
+}
+
+func (r *editorCodeRenderer) renderCodeBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		_, _ = w.WriteString("</pre>\n")
+		return ast.WalkContinue, nil
+	}
+
+	language := DEFAULT_CODE_LANGUAGE
+	if fenced, ok := node.(*ast.FencedCodeBlock); ok {
+		if l := fenced.Language(source); len(l) > 0 {
+			language = string(l)
+		}
+	}
+
+	_, _ = w.WriteString(`<pre class="ql-syntax" data-language="` + html.EscapeString(language) + `" spellcheck="false">`)
+
+	lines := node.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		_, _ = w.WriteString(html.EscapeString(string(line.Value(source))))
+	}
+
+	return ast.WalkContinue, nil
+}
+
+func (r *editorCodeRenderer) renderCodeSpan(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		_, _ = w.WriteString("</code>")
+		return ast.WalkContinue, nil
+	}
+
+	_, _ = w.WriteString(`<code class="inline-code">`)
+
+	for c := node.FirstChild(); c != nil; c = c.NextSibling() {
+		var value []byte
+
+		switch t := c.(type) {
+		case *ast.Text:
+			value = t.Segment.Value(source)
+		case *ast.String:
+			value = t.Value
+		}
+
+		// line endings inside inline code render as spaces
+		if bytes.HasSuffix(value, []byte("\n")) {
+			value = append(value[:len(value)-1:len(value)-1], ' ')
+		}
+
+		_, _ = w.WriteString(html.EscapeString(string(value)))
+	}
+
+	return ast.WalkSkipChildren, nil
+}
@@ -1,13 +1,31 @@
 package blog
 
 import (
+	"regexp"
 	"strings"
 
+	rr "blog-api/repositories/revision"
+
+	"github.com/microcosm-cc/bluemonday"
 	"golang.org/x/net/html"
 )
 
-func generateSlug(title string) string {
-	return strings.ToLower(strings.Join(strings.Split(title, " "), "-"))
+const (
+	DIFF_EQUAL  = "equal"
+	DIFF_INSERT = "insert"
+	DIFF_DELETE = "delete"
+)
+
+var blockBoundary = regexp.MustCompile(`(?i)(</(?:p|pre|h[1-6]|li|ul|ol|blockquote|div)>|<br\s*/?>|<img[^>]*>)`)
+
+func sanitizeHTML(text string) string {
+	p := bluemonday.UGCPolicy()
+
+	p.AllowAttrs("class", "data-language", "spellcheck").OnElements("pre", "span", "code")
+	// heading anchors linked to from the table of contents
+	p.AllowAttrs("id").Matching(anchorID).OnElements("h1", "h2", "h3", "h4")
+
+	return p.Sanitize(text)
 }
 
 func extraImageSourcesFromHTML(text string, bucket string) ([]string, error) {
@@ -45,12 +63,98 @@ func extraImageSourcesFromHTML(text string, bucket string) ([]string, error) {
 	return imageSources, nil
 }
 
-func extractKeyFromImageSource(source, bucket string) string {
-	result := strings.Split(source, bucket)
+// splitBlocks breaks post html into block level chunks so
+// revisions of single-line editor output can be diffed
+func splitBlocks(text string) []string {
+	var blocks []string
+
+	marked := blockBoundary.ReplaceAllString(text, "$1\n")
+
+	for _, block := range strings.Split(marked, "\n") {
+		block = strings.TrimSpace(block)
+		if block != "" {
+			blocks = append(blocks, block)
+		}
+	}
+
+	return blocks
+}
+
+// diffBlocks produces a longest common subsequence diff
+// between two slices of blocks
+func diffBlocks(from, to []string) []rr.DiffLine {
+	diff := []rr.DiffLine{}
+
+	// lengths[i][j] holds the lcs length of from[i:] and to[j:]
+	lengths := make([][]int, len(from)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(to)+1)
+	}
+
+	for i := len(from) - 1; i >= 0; i-- {
+		for j := len(to) - 1; j >= 0; j-- {
+			if from[i] == to[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
 
-	if len(result) != 2 {
-		return ""
+	i, j := 0, 0
+	for i < len(from) && j < len(to) {
+		switch {
+		case from[i] == to[j]:
+			diff = append(diff, rr.DiffLine{Op: DIFF_EQUAL, Text: from[i]})
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			diff = append(diff, rr.DiffLine{Op: DIFF_DELETE, Text: from[i]})
+			i++
+		default:
+			diff = append(diff, rr.DiffLine{Op: DIFF_INSERT, Text: to[j]})
+			j++
+		}
+	}
+
+	for ; i < len(from); i++ {
+		diff = append(diff, rr.DiffLine{Op: DIFF_DELETE, Text: from[i]})
+	}
+
+	for ; j < len(to); j++ {
+		diff = append(diff, rr.DiffLine{Op: DIFF_INSERT, Text: to[j]})
+	}
+
+	return diff
+}
+
+// diffCategories reports the categories added to and
+// removed from the from slice
+func diffCategories(from, to []string) *rr.CategoryChange {
+	change := &rr.CategoryChange{
+		Added:   []string{},
+		Removed: []string{},
+	}
+
+	fromSet := make(map[string]struct{}, len(from))
+	for _, category := range from {
+		fromSet[category] = struct{}{}
+	}
+
+	toSet := make(map[string]struct{}, len(to))
+	for _, category := range to {
+		toSet[category] = struct{}{}
+
+		if _, ok := fromSet[category]; !ok {
+			change.Added = append(change.Added, category)
+		}
+	}
+
+	for _, category := range from {
+		if _, ok := toSet[category]; !ok {
+			change.Removed = append(change.Removed, category)
+		}
 	}
 
-	return result[1]
+	return change
 }
@@ -1,9 +1,15 @@
 package blog
 
 import (
+	ck "blog-api/contextkeys"
+	r "blog-api/repositories/blog"
+	"context"
 	"fmt"
 	"os"
+	"strings"
 	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 type ImageExtractionTest struct {
@@ -32,27 +38,386 @@ func TestImageSourceExtraction(t *testing.T) {
 
 }
 
-type ImageKeyExtractionTest struct {
-	Input string
-	Want  string
+type BlockDiffTest struct {
+	From        string
+	To          string
+	WantInserts int
+	WantDeletes int
 }
 
-func TestExtractKeyFromImageSource(t *testing.T) {
-	bucketName := os.Getenv("AWS_BUCKET")
+func TestDiffBlocks(t *testing.T) {
+	tests := []BlockDiffTest{
+		{
+			From:        "<p>one</p><p>two</p><p>three</p>",
+			To:          "<p>one</p><p>two</p><p>three</p>",
+			WantInserts: 0,
+			WantDeletes: 0,
+		},
+		{
+			From:        "<p>one</p><p>two</p><p>three</p>",
+			To:          "<p>one</p><p>2</p><p>three</p><p>four</p>",
+			WantInserts: 2,
+			WantDeletes: 1,
+		},
+		{
+			From:        "",
+			To:          "<h2>title</h2><pre class=\"ql-syntax\">code</pre>",
+			WantInserts: 2,
+			WantDeletes: 0,
+		},
+	}
 
-	tests := []ImageKeyExtractionTest{
+	for _, test := range tests {
+		diff := diffBlocks(splitBlocks(test.From), splitBlocks(test.To))
+
+		inserts, deletes := 0, 0
+		for _, line := range diff {
+			switch line.Op {
+			case DIFF_INSERT:
+				inserts++
+			case DIFF_DELETE:
+				deletes++
+			}
+		}
+
+		if inserts != test.WantInserts || deletes != test.WantDeletes {
+			t.Errorf("invalid diff of %q and %q: wanted %d inserts and %d deletes, got %d and %d", test.From, test.To, test.WantInserts, test.WantDeletes, inserts, deletes)
+		}
+	}
+}
+
+type MarkdownRenderTest struct {
+	Input    string
+	Contains []string
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []MarkdownRenderTest{
+		{
+			Input: "# Title\n\nSome `inline` code.\n\n```go\nfmt.Println(\"<hi>\")\n```\n",
+			Contains: []string{
+				"<h1>Title</h1>",
+				"<code class=\"inline-code\">inline</code>",
+				"<pre class=\"ql-syntax\" data-language=\"go\" spellcheck=\"false\">fmt.Println(&#34;&lt;hi&gt;&#34;)\n</pre>",
+			},
+		},
 		{
-			Input: "https://dev-blog-resources.s3.amazonaws.com/canvas_1736739686719.png",
-			Want:  "canvas_1736739686719.png",
+			Input: "```\nplain text\n```\n\n<script>alert(1)</script>",
+			Contains: []string{
+				"<pre class=\"ql-syntax\" data-language=\"plain\" spellcheck=\"false\">plain text\n</pre>",
+			},
 		},
 	}
 
 	for _, test := range tests {
-		result := extractKeyFromImageSource(test.Input, bucketName+".s3.amazonaws.com/")
+		rendered, err := renderMarkdown(test.Input)
+		if err != nil {
+			t.Fatalf("failed to render markdown: %v", err)
+		}
 
-		if result != test.Want {
-			t.Errorf("error extracting image key: wanted %s, got %s", test.Want, result)
+		for _, want := range test.Contains {
+			if !strings.Contains(rendered, want) {
+				t.Errorf("rendered markdown missing %q, got %q", want, rendered)
+			}
 		}
+
+		if strings.Contains(rendered, "<script>") {
+			t.Errorf("rendered markdown was not sanitized: %q", rendered)
+		}
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	text := plainText(`<p>Use <b>go</b>fmt &amp; vet</p><p>often<br>always</p><script>alert(1)</script><pre class="ql-syntax">x := 1</pre>`)
+	want := "Use gofmt & vet often always x := 1"
+
+	if text != want {
+		t.Errorf("expected %q, got %q", want, text)
 	}
+}
+
+type SnippetTest struct {
+	Query    string
+	Text     string
+	Expected string
+}
+
+func TestHighlightSnippet(t *testing.T) {
+	long := strings.Repeat("filler words ", 10)
 
+	tests := []SnippetTest{
+		{
+			Query:    "Running tests",
+			Text:     "How I run my <test> suites when running go",
+			Expected: "How I <mark>run</mark> my &lt;<mark>test</mark>&gt; suites when <mark>running</mark> go",
+		},
+		{
+			Query:    `"table driven" -mocks`,
+			Text:     "Table driven tests beat mocks, table  driven",
+			Expected: "<mark>Table driven</mark> tests beat mocks, <mark>table  driven</mark>",
+		},
+		{
+			Query:    "needle",
+			Text:     long + "the needle is here " + long,
+			Expected: "…filler words filler words filler words filler words the <mark>needle</mark> is here " + strings.Repeat("filler words ", 9) + "filler…",
+		},
+		{
+			Query:    "title",
+			Text:     "no match in the body",
+			Expected: "no match in the body",
+		},
+	}
+
+	for _, test := range tests {
+		snippet := highlightSnippet(test.Text, searchMatcher(test.Query))
+		if snippet != test.Expected {
+			t.Errorf("query %q:\nexpected %q\ngot      %q", test.Query, test.Expected, snippet)
+		}
+	}
+}
+
+func TestSuggestionIndex(t *testing.T) {
+	index := buildSuggestionIndex([]r.SuggestionSource{
+		{Title: "Sorting in Go", Slug: "sorting-in-go", Categories: []string{"Go", "algorithms"}, Views: 10, Author: "jonah"},
+		{Title: "Go Generics", Slug: "go-generics", Categories: []string{"go"}, Views: 40, Rating: 2, Author: "gopher"},
+		{Title: "Graph Algorithms", Slug: "graph-algorithms", Categories: []string{"algorithms"}, Views: 5, Author: "jonah"},
+	})
+
+	suggestions := index.lookup("  GO ", 5)
+
+	if len(suggestions.Posts) != 2 || suggestions.Posts[0].Slug != "go-generics" || suggestions.Posts[1].Slug != "sorting-in-go" {
+		t.Errorf("expected go posts ranked by popularity, got %+v", suggestions.Posts)
+	}
+
+	if len(suggestions.Categories) != 1 || suggestions.Categories[0] != (r.CategorySuggestion{Name: "go", Count: 2}) {
+		t.Errorf("expected the go category once, got %+v", suggestions.Categories)
+	}
+
+	if len(suggestions.Authors) != 1 || suggestions.Authors[0].Username != "gopher" {
+		t.Errorf("expected the gopher author, got %+v", suggestions.Authors)
+	}
+
+	suggestions = index.lookup("sorting in g", 5)
+
+	if len(suggestions.Posts) != 1 || suggestions.Posts[0].Slug != "sorting-in-go" {
+		t.Errorf("expected a multi word prefix to match, got %+v", suggestions.Posts)
+	}
+
+	suggestions = index.lookup("algo", 1)
+
+	if len(suggestions.Posts) != 1 || suggestions.Posts[0].Slug != "graph-algorithms" {
+		t.Errorf("expected the limit to keep the top post, got %+v", suggestions.Posts)
+	}
+
+	if len(suggestions.Categories) != 1 || suggestions.Categories[0].Count != 2 {
+		t.Errorf("expected the algorithms category, got %+v", suggestions.Categories)
+	}
+
+	if suggestions = index.lookup("rust", 5); len(suggestions.Posts)+len(suggestions.Categories)+len(suggestions.Authors) != 0 {
+		t.Errorf("expected no suggestions, got %+v", suggestions)
+	}
+}
+
+func TestRelatedIndex(t *testing.T) {
+	source := func(slug, title, text string, categories ...string) r.RelatedSource {
+		return r.RelatedSource{
+			BlogMinimum: r.BlogMinimum{Slug: slug, Title: title},
+			Categories:  categories,
+			SearchText:  text,
+		}
+	}
+
+	index := buildRelatedIndex([]r.RelatedSource{
+		source("goroutines", "Goroutines and channels", "Concurrency in Go with goroutines and channels.", "go", "concurrency"),
+		source("worker-pools", "Worker pools", "Bounded concurrency using goroutines, channels and a wait group.", "go"),
+		source("sourdough", "Sourdough starter", "Feeding a starter with flour and water.", "baking"),
+		source("go-modules", "Go modules", "Versioning dependencies with go modules.", "Go"),
+	})
+
+	related := index.relatedTo(index.bySlug["goroutines"])
+
+	if len(related) != 2 {
+		t.Fatalf("expected the two go blogs, got %+v", related)
+	}
+
+	if related[0].Slug != "worker-pools" || related[1].Slug != "go-modules" {
+		t.Errorf("expected shared text to rank worker-pools first, got %s, %s", related[0].Slug, related[1].Slug)
+	}
+
+	if related[0].Score <= related[1].Score || related[0].Score > 1 {
+		t.Errorf("unexpected scores: %v, %v", related[0].Score, related[1].Score)
+	}
+
+	if related := index.relatedTo(index.bySlug["sourdough"]); len(related) != 0 {
+		t.Errorf("expected nothing related to sourdough, got %+v", related)
+	}
+}
+
+func TestAnchorHeadings(t *testing.T) {
+	text, toc := anchorHeadings(`<h2 class="title">Getting Started</h2><p>intro<br></p><h3>Install &amp; <b>Setup</b></h3><h2><br></h2><h2>Getting started!</h2><h5>Skipped</h5>`)
+
+	want := `<h2 class="title" id="getting-started">Getting Started</h2><p>intro<br></p>` +
+		`<h3 id="install-setup">Install &amp; <b>Setup</b></h3><h2><br></h2>` +
+		`<h2 id="getting-started-2">Getting started!</h2><h5>Skipped</h5>`
+
+	if text != want {
+		t.Errorf("expected %q\ngot      %q", want, text)
+	}
+
+	expected := []r.TOCEntry{
+		{Level: 2, ID: "getting-started", Text: "Getting Started"},
+		{Level: 3, ID: "install-setup", Text: "Install & Setup"},
+		{Level: 2, ID: "getting-started-2", Text: "Getting started!"},
+	}
+
+	if len(toc) != len(expected) {
+		t.Fatalf("expected %d headings, got %+v", len(expected), toc)
+	}
+
+	for i := range expected {
+		if toc[i] != expected[i] {
+			t.Errorf("heading %d: expected %+v, got %+v", i, expected[i], toc[i])
+		}
+	}
+
+	// anchors survive sanitizing so a later edit keeps them
+	if sanitized := sanitizeHTML(text); !strings.Contains(sanitized, `id="install-setup"`) {
+		t.Errorf("sanitizer dropped the heading anchor: %q", sanitized)
+	}
+}
+
+func TestDeriveText(t *testing.T) {
+	input := &r.BaseBlogInput{Text: "<h1>Title</h1><p>" + strings.Repeat("word ", 450) + "</p>"}
+	deriveText(input)
+
+	if input.WordCount != 451 || input.ReadingTime != 3 {
+		t.Errorf("expected 451 words and 3 minutes, got %d and %d", input.WordCount, input.ReadingTime)
+	}
+
+	if len(input.Excerpt) > EXCERPT_LENGTH+len("…") || !strings.HasPrefix(input.Excerpt, "Title word") || !strings.HasSuffix(input.Excerpt, "word…") {
+		t.Errorf("unexpected excerpt: %q", input.Excerpt)
+	}
+
+	if len(input.TOC) != 1 || !strings.HasPrefix(input.Text, `<h1 id="title">`) {
+		t.Errorf("expected the heading to be anchored: %q", input.Text[:20])
+	}
+
+	if excerpt("short text") != "short text" || readingTime(0) != 0 || readingTime(1) != 1 {
+		t.Error("unexpected excerpt or reading time for short text")
+	}
+}
+
+// slugRepository stubs the repository methods slug changes rely on
+type slugRepository struct {
+	r.BlogRepository
+	blog  *r.Blog
+	taken map[string]bool
+}
+
+func (s *slugRepository) GetBlogByIdAndAuthor(ctx context.Context, id, author bson.ObjectID) (*r.Blog, error) {
+	return s.blog, nil
+}
+
+func (s *slugRepository) ValidateSlug(ctx context.Context, slug string) (bool, error) {
+	return !s.taken[slug], nil
+}
+
+func TestPrepareSlugChange(t *testing.T) {
+	blog := &r.Blog{ID: bson.NewObjectID(), Slug: "current", PreviousSlugs: []string{"first", "second"}}
+	repo := &slugRepository{
+		blog:  blog,
+		taken: map[string]bool{"current": true, "first": true, "second": true, "other-post": true},
+	}
+	service := &BlogService{blogRepo: repo}
+	ctx := context.WithValue(context.Background(), ck.UserIDKey, bson.NewObjectID().Hex())
+
+	input := &r.UpdateBlogInput{ID: blog.ID.Hex(), BaseBlogInput: r.BaseBlogInput{Slug: "renamed"}}
+	if err := service.prepareSlugChange(ctx, input); err != nil {
+		t.Fatalf("failed to change slug: %v", err)
+	}
+
+	if strings.Join(input.PreviousSlugs, ",") != "first,second,current" {
+		t.Errorf("unexpected slug history %v", input.PreviousSlugs)
+	}
+
+	// a blog can take back its own retired slug
+	input = &r.UpdateBlogInput{ID: blog.ID.Hex(), BaseBlogInput: r.BaseBlogInput{Slug: "first"}}
+	if err := service.prepareSlugChange(ctx, input); err != nil {
+		t.Fatalf("failed to restore a retired slug: %v", err)
+	}
+
+	if strings.Join(input.PreviousSlugs, ",") != "second,current" {
+		t.Errorf("unexpected slug history %v", input.PreviousSlugs)
+	}
+
+	input = &r.UpdateBlogInput{ID: blog.ID.Hex(), BaseBlogInput: r.BaseBlogInput{Slug: "other-post"}}
+	if err := service.prepareSlugChange(ctx, input); err == nil {
+		t.Error("expected a slug used by another blog to be rejected")
+	}
+
+	// keeping the current slug is not a change
+	input = &r.UpdateBlogInput{ID: blog.ID.Hex(), BaseBlogInput: r.BaseBlogInput{Slug: "current"}}
+	if err := service.prepareSlugChange(ctx, input); err != nil || input.Slug != "" || input.PreviousSlugs != nil {
+		t.Errorf("expected the current slug to be left as is, got %q %v (%v)", input.Slug, input.PreviousSlugs, err)
+	}
+}
+
+func TestGenerateSlug(t *testing.T) {
+	cases := map[string]string{
+		"Sorting in Go":                      "sorting-in-go",
+		"What's new in Go 1.24?":             "whats-new-in-go-1-24",
+		"  Lots   of\tspaces  ":              "lots-of-spaces",
+		"Crème brûlée à la française":        "creme-brulee-a-la-francaise",
+		"Straße & Ørsted":                    "strasse-and-orsted",
+		"Привет, мир":                        "privet-mir",
+		"Καλημέρα κόσμε":                     "kalimera-kosme",
+		"Shipping 🚀 fast 🔥":                  "shipping-fast",
+		"日本語のブログ":                            "日本語のブログ",
+		"ﬁle — system":                       "file-system",
+		"--already-a-slug--":                 "already-a-slug",
+		"🚀🔥":                                 "",
+		"C++ / C# / F#: a comparison (2024)": "c-c-f-a-comparison-2024",
+	}
+
+	for title, expected := range cases {
+		if got := generateSlug(title); got != expected {
+			t.Errorf("generateSlug(%q) = %q, expected %q", title, got, expected)
+		}
+	}
+
+	long := generateSlug(strings.Repeat("word ", 30))
+	if len(long) > MAX_SLUG_LENGTH || strings.HasSuffix(long, "-") || !strings.HasSuffix(long, "word") {
+		t.Errorf("expected the slug to be cut at a word boundary, got %q", long)
+	}
+
+	if got := truncateSlug("abc-defgh", 6); got != "abc" {
+		t.Errorf("truncateSlug cut inside a word: %q", got)
+	}
+
+	if got := truncateSlug("abc-defgh", 3); got != "abc" {
+		t.Errorf("truncateSlug at a dash: %q", got)
+	}
+}
+
+func TestUniqueSlug(t *testing.T) {
+	repo := &slugRepository{taken: map[string]bool{"hello": true, "hello-1": true}}
+	service := &BlogService{blogRepo: repo}
+
+	cases := map[string]string{
+		"hello":  "hello-2",
+		"fresh":  "fresh",
+		"drafts": "drafts-1",
+		"":       FALLBACK_SLUG,
+	}
+
+	for slug, expected := range cases {
+		if got, err := service.uniqueSlug(context.Background(), slug); err != nil || got != expected {
+			t.Errorf("uniqueSlug(%q) = %q (%v), expected %q", slug, got, err, expected)
+		}
+	}
+
+	response, err := service.ValidateSlug(context.Background(), "Random")
+	if err != nil || response.Slug != "random" || response.IsAvailable || !response.Reserved || response.Suggestion != "random-1" {
+		t.Errorf("unexpected validation of a reserved slug: %+v (%v)", response, err)
+	}
 }
@@ -0,0 +1,68 @@
+package blog
+
+import (
+	r "blog-api/repositories/blog"
+	"context"
+	"log"
+	"time"
+)
+
+/*
+RunScheduledPublisher publishes blogs whose publishAt time has
+passed, checking once on start and then on every interval until
+the provided context is cancelled. Each blog is claimed with a
+single atomic update so it is safe to run on every replica.
+*/
+func (s *BlogService) RunScheduledPublisher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.publishDueBlogs(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *BlogService) publishDueBlogs(ctx context.Context) {
+	for {
+		publishCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		blog, err := s.blogRepo.PublishNextScheduledBlog(publishCtx, time.Now())
+		cancel()
+
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("scheduled publisher: %v", err)
+			}
+			return
+		}
+
+		if blog == nil {
+			return
+		}
+
+		log.Printf("scheduled publisher: published %s", blog.Slug)
+
+		s.refreshCachesAsync()
+	}
+}
+
+// applySchedule keeps blogs scheduled for the future unpublished and
+// publishes blogs whose scheduled time has already passed
+func applySchedule(input *r.BaseBlogInput) {
+	if input.PublishAt == nil {
+		return
+	}
+
+	if input.PublishAt.After(time.Now()) {
+		input.Published = false
+		return
+	}
+
+	input.Published = true
+	input.PublishAt = nil
+}
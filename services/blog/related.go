@@ -0,0 +1,250 @@
+package blog
+
+import (
+	r "blog-api/repositories/blog"
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	DEFAULT_RELATED = 5
+	MAX_RELATED     = 10
+
+	// title words count this many times as often as body words
+	RELATED_TITLE_WEIGHT = 3
+	// share of the similarity coming from category overlap,
+	// the rest comes from the text
+	RELATED_CATEGORY_WEIGHT = 0.4
+)
+
+var relatedWord = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// common english words carry no meaning for similarity
+var relatedStopWords = map[string]bool{}
+
+func init() {
+	for _, word := range strings.Fields(`a about after all also an and any are as at be because been
+		but by can could did do does for from had has have he her his how i if in into is it its
+		just like more most my no not of on one only or our out over she so some than that the
+		their them then there these they this to too up us use used using was we were what when
+		where which while who will with would you your`) {
+		relatedStopWords[word] = true
+	}
+}
+
+type relatedDocument struct {
+	blog       r.BlogMinimum
+	categories map[string]bool
+	// tf-idf weights normalized to unit length
+	vector map[string]float64
+}
+
+/*
+relatedIndex holds a tf-idf vector for every published blog. The
+related blogs of a post are worked out the first time they are
+asked for and kept until a write replaces the whole index.
+*/
+type relatedIndex struct {
+	documents []relatedDocument
+	bySlug    map[string]int
+
+	mu      sync.Mutex
+	related map[int][]r.RelatedBlog
+}
+
+/*
+GetRelatedBlogs returns the published blogs most similar to the
+blog with the provided slug, combining category overlap with the
+cosine similarity of their title and text.
+*/
+func (s *BlogService) GetRelatedBlogs(ctx context.Context, slug string, limit int) (r.RelatedResponse, error) {
+	response := r.RelatedResponse{Blogs: []r.RelatedBlog{}}
+
+	if limit <= 0 {
+		limit = DEFAULT_RELATED
+	}
+
+	limit = min(limit, MAX_RELATED)
+
+	index := s.related.Load()
+	if index == nil {
+		if err := s.RefreshRelated(ctx); err != nil {
+			return response, err
+		}
+
+		index = s.related.Load()
+	}
+
+	document, ok := index.bySlug[slug]
+	if !ok {
+		return response, fmt.Errorf("no published blog with slug: %s", slug)
+	}
+
+	related := index.relatedTo(document)
+
+	response.Blogs = related[:min(limit, len(related))]
+
+	return response, nil
+}
+
+// RefreshRelated rebuilds the related blogs index from the published blogs
+func (s *BlogService) RefreshRelated(ctx context.Context) error {
+	// serialized so an older rebuild never replaces a newer one
+	s.relatedMu.Lock()
+	defer s.relatedMu.Unlock()
+
+	sources, err := s.blogRepo.GetRelatedSources(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.related.Store(buildRelatedIndex(sources))
+
+	return nil
+}
+
+func buildRelatedIndex(sources []r.RelatedSource) *relatedIndex {
+	index := &relatedIndex{
+		documents: make([]relatedDocument, len(sources)),
+		bySlug:    make(map[string]int, len(sources)),
+		related:   map[int][]r.RelatedBlog{},
+	}
+
+	frequencies := make([]map[string]int, len(sources))
+	documentFrequency := map[string]int{}
+
+	for i, source := range sources {
+		frequencies[i] = map[string]int{}
+
+		for _, term := range relatedTerms(source.Title) {
+			frequencies[i][term] += RELATED_TITLE_WEIGHT
+		}
+
+		for _, term := range relatedTerms(source.SearchText) {
+			frequencies[i][term]++
+		}
+
+		for term := range frequencies[i] {
+			documentFrequency[term]++
+		}
+
+		categories := map[string]bool{}
+		for _, category := range r.NormalizeCategories(source.Categories) {
+			categories[category] = true
+		}
+
+		index.documents[i] = relatedDocument{blog: source.BlogMinimum, categories: categories}
+		index.bySlug[source.Slug] = i
+	}
+
+	total := float64(len(sources))
+
+	for i, frequency := range frequencies {
+		vector := make(map[string]float64, len(frequency))
+		length := 0.0
+
+		for term, count := range frequency {
+			// smoothed so terms found in every blog still count a little
+			idf := math.Log((1+total)/(1+float64(documentFrequency[term]))) + 1
+			weight := float64(count) * idf
+
+			vector[term] = weight
+			length += weight * weight
+		}
+
+		length = math.Sqrt(length)
+		for term := range vector {
+			vector[term] /= length
+		}
+
+		index.documents[i].vector = vector
+	}
+
+	return index
+}
+
+// relatedTo returns up to MAX_RELATED blogs similar to the document, most similar first
+func (i *relatedIndex) relatedTo(document int) []r.RelatedBlog {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if related, ok := i.related[document]; ok {
+		return related
+	}
+
+	related := []r.RelatedBlog{}
+	source := i.documents[document]
+
+	for other, candidate := range i.documents {
+		if other == document {
+			continue
+		}
+
+		score := (1-RELATED_CATEGORY_WEIGHT)*cosine(source.vector, candidate.vector) +
+			RELATED_CATEGORY_WEIGHT*jaccard(source.categories, candidate.categories)
+
+		if score > 0 {
+			related = append(related, r.RelatedBlog{BlogMinimum: candidate.blog, Score: score})
+		}
+	}
+
+	sort.SliceStable(related, func(a, b int) bool {
+		if related[a].Score != related[b].Score {
+			return related[a].Score > related[b].Score
+		}
+
+		return related[a].CreatedAt.After(related[b].CreatedAt)
+	})
+
+	related = related[:min(MAX_RELATED, len(related))]
+	i.related[document] = related
+
+	return related
+}
+
+// relatedTerms lowercases the words of the text, leaving out stop words
+func relatedTerms(text string) []string {
+	var terms []string
+
+	for _, word := range relatedWord.FindAllString(strings.ToLower(text), -1) {
+		if len(word) > 1 && !relatedStopWords[word] {
+			terms = append(terms, word)
+		}
+	}
+
+	return terms
+}
+
+// cosine of two unit length vectors
+func cosine(a, b map[string]float64) float64 {
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+
+	sum := 0.0
+	for term, weight := range a {
+		sum += weight * b[term]
+	}
+
+	return sum
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for category := range a {
+		if b[category] {
+			shared++
+		}
+	}
+
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
@@ -0,0 +1,186 @@
+package blog
+
+import (
+	r "blog-api/repositories/blog"
+	rr "blog-api/repositories/revision"
+	su "blog-api/utilities/service"
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// snapshotBlog stores the current state of the author's blog
+// as a revision before an update overwrites it
+func (s *BlogService) snapshotBlog(ctx context.Context, blogID string) error {
+	blog, err := s.getAuthorBlog(ctx, blogID)
+	if err != nil {
+		return err
+	}
+
+	revision := &rr.Revision{
+		Blog:          blog.ID,
+		Author:        blog.Author,
+		Categories:    blog.Categories,
+		Title:         blog.Title,
+		Text:          blog.Text,
+		Markdown:      blog.Markdown,
+		Slug:          blog.Slug,
+		ImageLocation: blog.ImageLocation,
+		ImageKey:      blog.ImageKey,
+		Published:     blog.Published,
+		CreatedAt:     time.Now(),
+	}
+
+	_, err = s.revisionRepo.CreateRevision(ctx, revision)
+
+	return err
+}
+
+// getAuthorBlog looks up a blog by its hex id, scoped
+// to the author stored in the request context
+func (s *BlogService) getAuthorBlog(ctx context.Context, blogID string) (*r.Blog, error) {
+	blogObjectID, err := bson.ObjectIDFromHex(blogID)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, ok := su.GetAuthorID(ctx)
+	if !ok {
+		return nil, fmt.Errorf("failed to access context values")
+	}
+
+	authorObjectID, err := bson.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.blogRepo.GetBlogByIdAndAuthor(ctx, blogObjectID, authorObjectID)
+}
+
+func (s *BlogService) GetRevisions(ctx context.Context, blogID string) (rr.RevisionListResponse, error) {
+	var response rr.RevisionListResponse
+
+	blog, err := s.getAuthorBlog(ctx, blogID)
+	if err != nil {
+		return response, err
+	}
+
+	revisions, err := s.revisionRepo.GetRevisionsByBlog(ctx, blog.ID, blog.Author)
+	if err != nil {
+		return response, err
+	}
+
+	response.Revisions = revisions
+
+	return response, nil
+}
+
+func (s *BlogService) GetRevision(ctx context.Context, blogID, revisionID string) (rr.RevisionResponse, error) {
+	var response rr.RevisionResponse
+
+	revision, err := s.getRevision(ctx, blogID, revisionID)
+	if err != nil {
+		return response, err
+	}
+
+	response.Revision = revision
+
+	return response, nil
+}
+
+func (s *BlogService) getRevision(ctx context.Context, blogID, revisionID string) (*rr.Revision, error) {
+	revisionObjectID, err := bson.ObjectIDFromHex(revisionID)
+	if err != nil {
+		return nil, err
+	}
+
+	blog, err := s.getAuthorBlog(ctx, blogID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.revisionRepo.GetRevisionByID(ctx, revisionObjectID, blog.ID, blog.Author)
+}
+
+/*
+DiffRevisions compares two revisions of the same blog. The to
+value may be "current" to compare against the live document.
+*/
+func (s *BlogService) DiffRevisions(ctx context.Context, blogID, fromID, toID string) (rr.RevisionDiffResponse, error) {
+	var response rr.RevisionDiffResponse
+
+	from, err := s.getRevision(ctx, blogID, fromID)
+	if err != nil {
+		return response, err
+	}
+
+	var to *rr.Revision
+
+	if toID == "current" {
+		blog, err := s.getAuthorBlog(ctx, blogID)
+		if err != nil {
+			return response, err
+		}
+
+		to = &rr.Revision{
+			ID:         blog.ID,
+			Categories: blog.Categories,
+			Title:      blog.Title,
+			Text:       blog.Text,
+		}
+	} else {
+		to, err = s.getRevision(ctx, blogID, toID)
+		if err != nil {
+			return response, err
+		}
+	}
+
+	response.From = from.ID
+	response.To = to.ID
+
+	if from.Title != to.Title {
+		response.Title = &rr.FieldChange{From: from.Title, To: to.Title}
+	}
+
+	response.Categories = diffCategories(from.Categories, to.Categories)
+	response.Text = diffBlocks(splitBlocks(from.Text), splitBlocks(to.Text))
+
+	return response, nil
+}
+
+/*
+RestoreRevision makes the provided revision's title, text and
+categories the current version of the blog. The restore is a
+regular update so the text is sanitized and the version being
+replaced is itself kept as a revision.
+*/
+func (s *BlogService) RestoreRevision(ctx context.Context, blogID, revisionID string) (r.BlogUpdateResponse, error) {
+	var response r.BlogUpdateResponse
+
+	revision, err := s.getRevision(ctx, blogID, revisionID)
+	if err != nil {
+		return response, err
+	}
+
+	blog, err := s.getAuthorBlog(ctx, blogID)
+	if err != nil {
+		return response, err
+	}
+
+	input := &r.UpdateBlogInput{
+		ID: blogID,
+		BaseBlogInput: r.BaseBlogInput{
+			Categories: revision.Categories,
+			Text:       revision.Text,
+			Markdown:   revision.Markdown,
+			Title:      revision.Title,
+			// restoring content should not change visibility
+			Published: blog.Published,
+			PublishAt: blog.PublishAt,
+		},
+	}
+
+	return s.UpdateBlog(ctx, input)
+}
@@ -0,0 +1,244 @@
+package blog
+
+import (
+	r "blog-api/repositories/blog"
+	"context"
+	"html"
+	"log"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	xhtml "golang.org/x/net/html"
+)
+
+const (
+	// approximate length of a search result snippet in bytes
+	SNIPPET_LENGTH = 200
+	// how much text to keep ahead of the first match
+	SNIPPET_LEAD = 60
+)
+
+// elements whose boundaries separate words in the extracted text
+var textBoundaries = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "ul": true, "ol": true,
+	"pre": true, "blockquote": true, "img": true, "hr": true, "table": true,
+	"tr": true, "td": true, "th": true, "h1": true, "h2": true, "h3": true,
+	"h4": true, "h5": true, "h6": true,
+}
+
+var (
+	searchPhrase = regexp.MustCompile(`"([^"]*)"`)
+	searchSuffix = regexp.MustCompile(`(ing|ed|es|s)$`)
+)
+
+func (s *BlogService) GetBlogsBySearchQuery(ctx context.Context, searchQuery string, q *r.BlogQuery) (r.BlogSearchResponse, error) {
+	response := r.BlogSearchResponse{}
+
+	blogs, page, err := s.blogRepo.GetBlogsBySearchQuery(ctx, searchQuery, q)
+	if err != nil {
+		return response, err
+	}
+
+	matcher := searchMatcher(searchQuery)
+
+	for i := range blogs {
+		blogs[i].Snippet = highlightSnippet(blogs[i].SearchText, matcher)
+	}
+
+	response.BlogPage = page
+	response.Blogs = blogs
+
+	return response, nil
+}
+
+/*
+PrepareIndexes creates the listing, search and like indexes and fills
+in the search text of blogs saved before it was stored, so older
+blogs can be found by their body as well as their title.
+*/
+func (s *BlogService) PrepareIndexes(ctx context.Context) error {
+	if err := s.blogRepo.EnsureIndexes(ctx); err != nil {
+		return err
+	}
+
+	if err := s.likeRepo.EnsureIndexes(ctx); err != nil {
+		return err
+	}
+
+	blogs, err := s.blogRepo.GetBlogsWithoutSearchText(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, blog := range blogs {
+		if err := s.blogRepo.SetSearchText(ctx, blog.ID, plainText(blog.Text)); err != nil {
+			return err
+		}
+	}
+
+	if len(blogs) > 0 {
+		log.Printf("search: indexed %d existing blogs", len(blogs))
+	}
+
+	return nil
+}
+
+// plainText strips the markup from blog html, leaving
+// the words the search index and snippets work from
+func plainText(text string) string {
+	var builder strings.Builder
+
+	tokenizer := xhtml.NewTokenizer(strings.NewReader(text))
+	skip := 0
+
+	for {
+		switch tokenizer.Next() {
+		case xhtml.ErrorToken:
+			return strings.Join(strings.Fields(builder.String()), " ")
+		case xhtml.TextToken:
+			if skip == 0 {
+				builder.Write(tokenizer.Text())
+			}
+		case xhtml.StartTagToken, xhtml.EndTagToken, xhtml.SelfClosingTagToken:
+			name, _ := tokenizer.TagName()
+			tag := string(name)
+
+			if tag == "script" || tag == "style" {
+				if tokenizer.Token().Type == xhtml.StartTagToken {
+					skip++
+				} else if skip > 0 {
+					skip--
+				}
+			}
+
+			if textBoundaries[tag] {
+				builder.WriteString(" ")
+			}
+		}
+	}
+}
+
+/*
+searchMatcher builds a case-insensitive pattern matching the
+quoted phrases and words of a search query. Words match on a
+rough stem so "running" still highlights "run" and "runs",
+mirroring the stemming done by the text index. Negated terms
+are left out since they never appear in a result.
+*/
+func searchMatcher(searchQuery string) *regexp.Regexp {
+	var patterns []string
+
+	for _, match := range searchPhrase.FindAllStringSubmatch(searchQuery, -1) {
+		if words := strings.Fields(match[1]); len(words) > 0 {
+			for i, word := range words {
+				words[i] = regexp.QuoteMeta(word)
+			}
+
+			patterns = append(patterns, strings.Join(words, `\s+`))
+		}
+	}
+
+	for _, term := range strings.Fields(searchPhrase.ReplaceAllString(searchQuery, " ")) {
+		if strings.HasPrefix(term, "-") {
+			continue
+		}
+
+		term = strings.Trim(strings.ToLower(term), `"'.,;:!?()`)
+		if term == "" {
+			continue
+		}
+
+		if stem := searchSuffix.ReplaceAllString(term, ""); len(stem) >= 3 {
+			// running -> runn -> run
+			if n := len(stem); stem[n-1] == stem[n-2] && stem != term {
+				stem = stem[:n-1]
+			}
+
+			term = stem
+		}
+
+		patterns = append(patterns, regexp.QuoteMeta(term)+`[\p{L}\p{N}]*`)
+	}
+
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	return regexp.MustCompile(`(?i)(?:^|[^\p{L}\p{N}])(` + strings.Join(patterns, "|") + `)`)
+}
+
+/*
+highlightSnippet cuts a window of the text around its first
+match, on word boundaries, and wraps every match inside it in
+<mark>. Text that only matched on title or categories gets its
+opening words without highlights. The rest of the snippet is
+html escaped.
+*/
+func highlightSnippet(text string, matcher *regexp.Regexp) string {
+	var matches [][]int
+
+	if matcher != nil {
+		for _, match := range matcher.FindAllStringSubmatchIndex(text, -1) {
+			// only the captured term, not the boundary before it
+			matches = append(matches, match[2:4])
+		}
+	}
+
+	start := 0
+	if len(matches) > 0 && matches[0][0] > SNIPPET_LEAD {
+		start = matches[0][0] - SNIPPET_LEAD
+		if space := strings.IndexByte(text[start:matches[0][0]], ' '); space >= 0 {
+			start += space + 1
+		} else {
+			start = matches[0][0]
+		}
+	}
+
+	end := len(text)
+	if start+SNIPPET_LENGTH < end {
+		end = start + SNIPPET_LENGTH
+		if space := strings.LastIndexByte(text[start:end], ' '); space > 0 {
+			end = start + space
+		}
+
+		for end > start && !utf8.RuneStart(text[end]) {
+			end--
+		}
+
+		// never cut through the first match
+		if len(matches) > 0 && end < matches[0][1] {
+			end = matches[0][1]
+		}
+	}
+
+	var builder strings.Builder
+
+	if start > 0 {
+		builder.WriteString("…")
+	}
+
+	position := start
+
+	for _, match := range matches {
+		if match[0] < position {
+			continue
+		}
+
+		if match[1] > end {
+			break
+		}
+
+		builder.WriteString(html.EscapeString(text[position:match[0]]))
+		builder.WriteString("<mark>" + html.EscapeString(text[match[0]:match[1]]) + "</mark>")
+		position = match[1]
+	}
+
+	builder.WriteString(html.EscapeString(text[position:end]))
+
+	if end < len(text) {
+		builder.WriteString("…")
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,53 @@
+package blog
+
+import (
+	r "blog-api/repositories/blog"
+	sr "blog-api/repositories/series"
+	"context"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+/*
+getSeriesNavigation places a published blog within its series,
+counting only the published parts. Returns nil when the blog is
+not part of a series.
+*/
+func (s *BlogService) getSeriesNavigation(ctx context.Context, blogID bson.ObjectID) (*r.SeriesNavigation, error) {
+	series, err := s.seriesRepo.GetSeriesByPost(ctx, blogID)
+	if err != nil || series == nil {
+		return nil, err
+	}
+
+	blogs, err := s.blogRepo.GetBlogsByIDs(ctx, series.Posts, bson.M{"published": true})
+	if err != nil {
+		return nil, err
+	}
+
+	parts := sr.OrderBlogs(series.Posts, blogs)
+
+	for i, part := range parts {
+		if part.ID != blogID {
+			continue
+		}
+
+		navigation := &r.SeriesNavigation{
+			ID:       series.ID,
+			Title:    series.Title,
+			Position: i + 1,
+			Total:    len(parts),
+		}
+
+		if i > 0 {
+			navigation.Previous = &parts[i-1]
+		}
+
+		if i < len(parts)-1 {
+			navigation.Next = &parts[i+1]
+		}
+
+		return navigation, nil
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,146 @@
+package blog
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	MAX_SLUG_LENGTH = 80 // characters, longer slugs are cut at a word boundary
+	FALLBACK_SLUG   = "post"
+)
+
+// reservedSlugs name the literal routes under /blog, a blog
+// using one could not be reached by GET /blog/{slug}
+var reservedSlugs = map[string]bool{
+	"analytics":      true,
+	"categories":     true,
+	"category":       true,
+	"comments":       true,
+	"drafts":         true,
+	"edit":           true,
+	"featured-image": true,
+	"feed":           true,
+	"media":          true,
+	"random":         true,
+	"revisions":      true,
+	"search":         true,
+	"series":         true,
+	"suggestions":    true,
+	"trending":       true,
+	"user":           true,
+	"validate-slug":  true,
+}
+
+// transliterations spell letters which have no decomposition
+// into a latin base letter and a mark
+var transliterations = map[rune]string{
+	// latin
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d",
+	'þ': "th", 'ł': "l", 'ı': "i", 'ħ': "h", 'ŋ': "n", 'ŧ': "t",
+	'&': "and",
+
+	// greek
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z",
+	'η': "i", 'θ': "th", 'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m",
+	'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s",
+	'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps",
+	'ω': "o",
+
+	// cyrillic
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'ґ': "g", 'д': "d",
+	'е': "e", 'ё': "e", 'є': "ye", 'ж': "zh", 'з': "z", 'и': "i",
+	'і': "i", 'ї': "yi", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t",
+	'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh",
+	'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya",
+}
+
+/*
+generateSlug turns a title into a url slug. Accented letters lose
+their accents, greek and cyrillic are transliterated and letters of
+other scripts are kept. Apostrophes are dropped, any other run of
+punctuation, symbols or spaces becomes a single dash and the result
+is cut at a word boundary to MAX_SLUG_LENGTH.
+*/
+func generateSlug(title string) string {
+	var slug strings.Builder
+	separate := false
+
+	write := func(part string) {
+		if part == "" {
+			return
+		}
+
+		if separate && slug.Len() > 0 {
+			slug.WriteByte('-')
+		}
+
+		separate = false
+		slug.WriteString(part)
+	}
+
+	for _, r := range strings.ToLower(title) {
+		if isApostrophe(r) {
+			continue
+		}
+
+		if spelling, ok := transliterations[r]; ok {
+			write(spelling)
+			continue
+		}
+
+		// marks change the letter in other scripts, keep it whole
+		if unicode.IsLetter(r) && !unicode.In(r, unicode.Latin, unicode.Greek, unicode.Cyrillic) {
+			write(string(r))
+			continue
+		}
+
+		// split accented letters into their base letter and marks
+		for _, d := range norm.NFKD.String(string(r)) {
+			switch {
+			case unicode.Is(unicode.Mn, d):
+			case transliterations[d] != "":
+				write(transliterations[d])
+			case unicode.IsLetter(d) || unicode.IsDigit(d):
+				write(string(unicode.ToLower(d)))
+			default:
+				separate = true
+			}
+		}
+	}
+
+	return truncateSlug(slug.String(), MAX_SLUG_LENGTH)
+}
+
+func isApostrophe(r rune) bool {
+	return r == '\'' || r == '’' || r == 'ʼ' || r == '`'
+}
+
+// truncateSlug cuts a slug to at most limit characters, at
+// the last dash when the limit falls inside a word
+func truncateSlug(slug string, limit int) string {
+	if utf8.RuneCountInString(slug) <= limit {
+		return slug
+	}
+
+	runes := []rune(slug)
+	cut := string(runes[:limit])
+
+	if runes[limit] != '-' {
+		if i := strings.LastIndexByte(cut, '-'); i > 0 {
+			cut = cut[:i]
+		}
+	}
+
+	return strings.TrimSuffix(cut, "-")
+}
+
+// isReservedSlug reports whether the slug would clash with a route
+func isReservedSlug(slug string) bool {
+	return reservedSlugs[slug]
+}
@@ -0,0 +1,217 @@
+package blog
+
+import (
+	r "blog-api/repositories/blog"
+	"context"
+	"sort"
+	"strings"
+)
+
+const (
+	DEFAULT_SUGGESTIONS = 5
+	MAX_SUGGESTIONS     = 10
+
+	// a like says more about a post than a single view
+	RATING_WEIGHT = 5
+)
+
+const (
+	suggestPost = iota
+	suggestCategory
+	suggestAuthor
+)
+
+// suggestionTerm points a searchable suffix of a
+// title, category or username back at its entry
+type suggestionTerm struct {
+	text  string
+	kind  int
+	entry int
+}
+
+/*
+suggestionIndex answers prefix lookups over published titles,
+categories and authors. Every word start of each name is kept
+as a sorted suffix so "go" finds "Sorting in Go" with a binary
+search. An index is never modified once built, a rebuild swaps
+in a new one.
+*/
+type suggestionIndex struct {
+	posts          []r.PostSuggestion
+	postScores     []int
+	categories     []r.CategorySuggestion
+	categoryScores []int
+	authors        []r.AuthorSuggestion
+	authorScores   []int
+	terms          []suggestionTerm
+}
+
+/*
+GetSuggestions returns the most popular post titles, categories
+and authors with a word starting with the provided prefix.
+*/
+func (s *BlogService) GetSuggestions(prefix string, limit int) r.SuggestionResponse {
+	if limit <= 0 {
+		limit = DEFAULT_SUGGESTIONS
+	}
+
+	limit = min(limit, MAX_SUGGESTIONS)
+
+	index := s.suggestions.Load()
+	if index == nil {
+		return emptySuggestions()
+	}
+
+	return index.lookup(prefix, limit)
+}
+
+// RefreshSuggestions rebuilds the suggestion index from the published blogs
+func (s *BlogService) RefreshSuggestions(ctx context.Context) error {
+	// serialized so an older rebuild never replaces a newer one
+	s.suggestionsMu.Lock()
+	defer s.suggestionsMu.Unlock()
+
+	sources, err := s.blogRepo.GetSuggestionSources(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.suggestions.Store(buildSuggestionIndex(sources))
+
+	return nil
+}
+
+func buildSuggestionIndex(sources []r.SuggestionSource) *suggestionIndex {
+	index := &suggestionIndex{}
+
+	categories := map[string]int{}
+	authors := map[string]int{}
+
+	for _, source := range sources {
+		score := source.Views + source.Rating*RATING_WEIGHT
+
+		index.addTerms(source.Title, suggestPost, len(index.posts))
+		index.posts = append(index.posts, r.PostSuggestion{Title: source.Title, Slug: source.Slug})
+		index.postScores = append(index.postScores, score)
+
+		for _, category := range r.NormalizeCategories(source.Categories) {
+			entry, ok := categories[category]
+			if !ok {
+				entry = len(index.categories)
+				categories[category] = entry
+
+				index.addTerms(category, suggestCategory, entry)
+				index.categories = append(index.categories, r.CategorySuggestion{Name: category})
+				index.categoryScores = append(index.categoryScores, 0)
+			}
+
+			index.categories[entry].Count++
+			index.categoryScores[entry] += score
+		}
+
+		if source.Author == "" {
+			continue
+		}
+
+		entry, ok := authors[source.Author]
+		if !ok {
+			entry = len(index.authors)
+			authors[source.Author] = entry
+
+			index.addTerms(source.Author, suggestAuthor, entry)
+			index.authors = append(index.authors, r.AuthorSuggestion{Username: source.Author})
+			index.authorScores = append(index.authorScores, 0)
+		}
+
+		index.authors[entry].Count++
+		index.authorScores[entry] += score
+	}
+
+	sort.Slice(index.terms, func(i, j int) bool {
+		return index.terms[i].text < index.terms[j].text
+	})
+
+	return index
+}
+
+// addTerms indexes the text from the start of each of its words
+func (i *suggestionIndex) addTerms(text string, kind, entry int) {
+	words := strings.Fields(strings.ToLower(text))
+
+	for w := range words {
+		i.terms = append(i.terms, suggestionTerm{
+			text:  strings.Join(words[w:], " "),
+			kind:  kind,
+			entry: entry,
+		})
+	}
+}
+
+func (i *suggestionIndex) lookup(prefix string, limit int) r.SuggestionResponse {
+	response := emptySuggestions()
+
+	prefix = strings.Join(strings.Fields(strings.ToLower(prefix)), " ")
+	if prefix == "" {
+		return response
+	}
+
+	var posts, categories, authors []int
+	seen := map[[2]int]bool{}
+
+	start := sort.Search(len(i.terms), func(t int) bool {
+		return i.terms[t].text >= prefix
+	})
+
+	for _, term := range i.terms[start:] {
+		if !strings.HasPrefix(term.text, prefix) {
+			break
+		}
+
+		key := [2]int{term.kind, term.entry}
+		if seen[key] {
+			continue
+		}
+
+		seen[key] = true
+
+		switch term.kind {
+		case suggestPost:
+			posts = append(posts, term.entry)
+		case suggestCategory:
+			categories = append(categories, term.entry)
+		case suggestAuthor:
+			authors = append(authors, term.entry)
+		}
+	}
+
+	for _, entry := range rankEntries(posts, i.postScores, limit) {
+		response.Posts = append(response.Posts, i.posts[entry])
+	}
+
+	for _, entry := range rankEntries(categories, i.categoryScores, limit) {
+		response.Categories = append(response.Categories, i.categories[entry])
+	}
+
+	for _, entry := range rankEntries(authors, i.authorScores, limit) {
+		response.Authors = append(response.Authors, i.authors[entry])
+	}
+
+	return response
+}
+
+// rankEntries orders the matched entries by popularity and keeps the top limit
+func rankEntries(entries, scores []int, limit int) []int {
+	sort.SliceStable(entries, func(a, b int) bool {
+		return scores[entries[a]] > scores[entries[b]]
+	})
+
+	return entries[:min(limit, len(entries))]
+}
+
+func emptySuggestions() r.SuggestionResponse {
+	return r.SuggestionResponse{
+		Posts:      []r.PostSuggestion{},
+		Categories: []r.CategorySuggestion{},
+		Authors:    []r.AuthorSuggestion{},
+	}
+}
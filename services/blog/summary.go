@@ -0,0 +1,205 @@
+package blog
+
+import (
+	r "blog-api/repositories/blog"
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	xhtml "golang.org/x/net/html"
+)
+
+const (
+	// approximate length of an excerpt in bytes
+	EXCERPT_LENGTH = 280
+	// average adult reading speed
+	WORDS_PER_MINUTE = 200
+)
+
+// headings included in the table of contents
+var tocHeadings = map[string]int{"h1": 1, "h2": 2, "h3": 3, "h4": 4}
+
+// anchorID matches the ids headingID generates, the only ids the sanitizer keeps
+var anchorID = regexp.MustCompile(`^[\p{L}\p{N}]+(-[\p{L}\p{N}]+)*$`)
+
+// deriveText anchors the headings of the input's sanitized html and
+// fills the fields computed from it
+func deriveText(input *r.BaseBlogInput) {
+	input.Text, input.TOC = anchorHeadings(input.Text)
+	input.SearchText = plainText(input.Text)
+	input.WordCount = len(strings.Fields(input.SearchText))
+	input.ReadingTime = readingTime(input.WordCount)
+	input.Excerpt = excerpt(input.SearchText)
+}
+
+/*
+BackfillDerivedFields recomputes the heading anchors, search text,
+excerpt, word count, reading time and table of contents of every
+blog from its stored html. Returns the number of blogs updated.
+*/
+func (s *BlogService) BackfillDerivedFields(ctx context.Context) (int, error) {
+	blogs, err := s.blogRepo.GetBlogTexts(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, blog := range blogs {
+		input := &r.BaseBlogInput{Text: sanitizeHTML(blog.Text)}
+		deriveText(input)
+
+		if err := s.blogRepo.SetDerivedFields(ctx, blog.ID, input); err != nil {
+			return i, err
+		}
+	}
+
+	return len(blogs), nil
+}
+
+/*
+anchorHeadings gives every h1 to h4 heading an id derived from its
+text, so the same heading keeps the same anchor across edits, and
+returns the table of contents they make up. Headings sharing text
+are numbered. Everything other than the heading tags is copied
+through untouched.
+*/
+func anchorHeadings(text string) (string, []r.TOCEntry) {
+	var builder, headingBody, headingText strings.Builder
+	var heading *xhtml.Token
+	var headingRaw string
+
+	toc := []r.TOCEntry{}
+	used := map[string]int{}
+
+	tokenizer := xhtml.NewTokenizer(strings.NewReader(text))
+
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == xhtml.ErrorToken {
+			break
+		}
+
+		// copied first, reading the token reuses the buffer
+		raw := string(tokenizer.Raw())
+		token := tokenizer.Token()
+
+		switch {
+		case heading == nil && tokenType == xhtml.StartTagToken && tocHeadings[token.Data] > 0:
+			heading = &token
+			headingRaw = raw
+			headingBody.Reset()
+			headingText.Reset()
+		case heading != nil && tokenType == xhtml.EndTagToken && token.Data == heading.Data:
+			label := strings.Join(strings.Fields(headingText.String()), " ")
+
+			// empty editor lines are often left as headings
+			if label == "" {
+				builder.WriteString(headingRaw)
+			} else {
+				id := uniqueID(headingID(label), used)
+				setAttr(heading, "id", id)
+
+				builder.WriteString(heading.String())
+				toc = append(toc, r.TOCEntry{Level: tocHeadings[heading.Data], ID: id, Text: label})
+			}
+
+			builder.WriteString(headingBody.String())
+			builder.WriteString(raw)
+			heading = nil
+		case heading != nil:
+			headingBody.WriteString(raw)
+
+			if tokenType == xhtml.TextToken {
+				headingText.WriteString(token.Data)
+			}
+		default:
+			builder.WriteString(raw)
+		}
+	}
+
+	// an unclosed heading is left as it was
+	if heading != nil {
+		builder.WriteString(headingRaw)
+		builder.WriteString(headingBody.String())
+	}
+
+	return builder.String(), toc
+}
+
+// headingID lowercases the heading and joins its words with dashes
+func headingID(label string) string {
+	words := strings.FieldsFunc(strings.ToLower(label), func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsNumber(c)
+	})
+
+	if len(words) == 0 {
+		return "section"
+	}
+
+	return strings.Join(words, "-")
+}
+
+// uniqueID numbers repeated ids, the second "setup" becomes "setup-2"
+func uniqueID(id string, used map[string]int) string {
+	used[id]++
+
+	for used[id] > 1 {
+		candidate := id + "-" + strconv.Itoa(used[id])
+		if used[candidate] == 0 {
+			used[candidate]++
+			return candidate
+		}
+
+		used[id]++
+	}
+
+	return id
+}
+
+func setAttr(token *xhtml.Token, key, value string) {
+	for i, attr := range token.Attr {
+		if attr.Key == key {
+			token.Attr[i].Val = value
+			return
+		}
+	}
+
+	token.Attr = append(token.Attr, xhtml.Attribute{Key: key, Val: value})
+}
+
+// tableOfContents returns the blog's headings, never null in responses
+func tableOfContents(blog *r.BlogWithAuthor) []r.TOCEntry {
+	if blog.TOC == nil {
+		return []r.TOCEntry{}
+	}
+
+	return blog.TOC
+}
+
+func readingTime(words int) int {
+	if words == 0 {
+		return 0
+	}
+
+	return (words + WORDS_PER_MINUTE - 1) / WORDS_PER_MINUTE
+}
+
+// excerpt cuts plain text down to EXCERPT_LENGTH on a word boundary
+func excerpt(text string) string {
+	if len(text) <= EXCERPT_LENGTH {
+		return text
+	}
+
+	end := EXCERPT_LENGTH
+	if space := strings.LastIndexByte(text[:end], ' '); space > 0 {
+		end = space
+	}
+
+	for end > 0 && !utf8.RuneStart(text[end]) {
+		end--
+	}
+
+	return strings.TrimRight(text[:end], " ,.;:") + "…"
+}
@@ -0,0 +1,219 @@
+package comment
+
+import (
+	br "blog-api/repositories/blog"
+	r "blog-api/repositories/comment"
+	su "blog-api/utilities/service"
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type CommentService struct {
+	commentRepo r.CommentRepository
+	blogRepo    br.BlogRepository
+}
+
+func NewCommentService(commentRepo r.CommentRepository, blogRepo br.BlogRepository) *CommentService {
+	return &CommentService{
+		commentRepo: commentRepo,
+		blogRepo:    blogRepo,
+	}
+}
+
+/*
+CreateComment sanitizes and stores a reader's comment on a
+published blog. New comments wait in the moderation queue
+until the blog's author approves them.
+*/
+func (s *CommentService) CreateComment(ctx context.Context, blogID string, input *r.CommentPost) (r.CommentResponse, error) {
+	var response r.CommentResponse
+
+	blogObjectID, err := bson.ObjectIDFromHex(blogID)
+	if err != nil {
+		return response, err
+	}
+
+	blog, err := s.blogRepo.GetBlogById(ctx, blogObjectID)
+	if err != nil {
+		return response, err
+	}
+
+	if blog == nil || !blog.Published {
+		return response, fmt.Errorf("blog %s does not exist", blogID)
+	}
+
+	name := sanitizeName(input.Name)
+	body := sanitizeComment(input.Body)
+
+	if name == "" || body == "" {
+		return response, fmt.Errorf("comments require a name and body")
+	}
+
+	if utf8.RuneCountInString(name) > MAX_NAME_LENGTH || utf8.RuneCountInString(body) > MAX_BODY_LENGTH {
+		return response, fmt.Errorf("comment name or body is too long")
+	}
+
+	comment := &r.Comment{
+		Blog:       blog.ID,
+		BlogAuthor: blog.Author,
+		Name:       name,
+		Body:       body,
+		Status:     r.STATUS_PENDING,
+		CreatedAt:  time.Now(),
+	}
+
+	if input.Parent != "" {
+		parentObjectID, err := bson.ObjectIDFromHex(input.Parent)
+		if err != nil {
+			return response, err
+		}
+
+		parent, err := s.commentRepo.GetCommentByID(ctx, parentObjectID)
+		if err != nil {
+			return response, err
+		}
+
+		if parent.Blog != blog.ID || parent.Status != r.STATUS_APPROVED {
+			return response, fmt.Errorf("can not reply to comment %s", input.Parent)
+		}
+
+		thread := parent.ID
+		if parent.Thread != nil {
+			thread = *parent.Thread
+		}
+
+		comment.Parent = &parent.ID
+		comment.Thread = &thread
+	}
+
+	created, err := s.commentRepo.CreateComment(ctx, comment)
+	if err != nil {
+		return response, err
+	}
+
+	response.Comment = created
+
+	return response, nil
+}
+
+/*
+GetComments returns a page of approved top level comments on the
+blog, each with its approved replies.
+*/
+func (s *CommentService) GetComments(ctx context.Context, blogID string, offset int) (r.CommentIndexResponse, error) {
+	response := r.CommentIndexResponse{Comments: []r.CommentThread{}}
+
+	blogObjectID, err := bson.ObjectIDFromHex(blogID)
+	if err != nil {
+		return response, err
+	}
+
+	threads, hasMore, err := s.commentRepo.GetApprovedThreads(ctx, blogObjectID, offset)
+	if err != nil {
+		return response, err
+	}
+
+	if len(threads) == 0 {
+		return response, nil
+	}
+
+	threadIDs := make([]bson.ObjectID, len(threads))
+	for i, thread := range threads {
+		threadIDs[i] = thread.ID
+	}
+
+	replies, err := s.commentRepo.GetApprovedReplies(ctx, threadIDs)
+	if err != nil {
+		return response, err
+	}
+
+	repliesByThread := make(map[bson.ObjectID][]r.Comment)
+	for _, reply := range replies {
+		repliesByThread[*reply.Thread] = append(repliesByThread[*reply.Thread], reply)
+	}
+
+	for _, thread := range threads {
+		threadReplies := repliesByThread[thread.ID]
+		if threadReplies == nil {
+			threadReplies = []r.Comment{}
+		}
+
+		response.Comments = append(response.Comments, r.CommentThread{
+			Comment: thread,
+			Replies: threadReplies,
+		})
+	}
+
+	response.HasMore = hasMore
+
+	return response, nil
+}
+
+// GetModerationQueue lists comments on the author's blogs with the provided status
+func (s *CommentService) GetModerationQueue(ctx context.Context, status string, offset int) (r.CommentModerationResponse, error) {
+	var response r.CommentModerationResponse
+
+	if !isValidStatus(status) {
+		return response, fmt.Errorf("invalid comment status: %s", status)
+	}
+
+	authorObjectID, err := getAuthorObjectID(ctx)
+	if err != nil {
+		return response, err
+	}
+
+	comments, hasMore, err := s.commentRepo.GetCommentsByStatus(ctx, authorObjectID, status, offset)
+	if err != nil {
+		return response, err
+	}
+
+	response.Comments = comments
+	response.HasMore = hasMore
+
+	return response, nil
+}
+
+// ModerateComment approves or rejects a comment left on one of the author's blogs
+func (s *CommentService) ModerateComment(ctx context.Context, commentID, status string) (r.CommentResponse, error) {
+	var response r.CommentResponse
+
+	if status != r.STATUS_APPROVED && status != r.STATUS_REJECTED {
+		return response, fmt.Errorf("comments can only be approved or rejected")
+	}
+
+	commentObjectID, err := bson.ObjectIDFromHex(commentID)
+	if err != nil {
+		return response, err
+	}
+
+	authorObjectID, err := getAuthorObjectID(ctx)
+	if err != nil {
+		return response, err
+	}
+
+	comment, err := s.commentRepo.UpdateCommentStatus(ctx, commentObjectID, authorObjectID, status)
+	if err != nil {
+		return response, err
+	}
+
+	response.Comment = comment
+
+	return response, nil
+}
+
+func isValidStatus(status string) bool {
+	return status == r.STATUS_PENDING || status == r.STATUS_APPROVED || status == r.STATUS_REJECTED
+}
+
+func getAuthorObjectID(ctx context.Context) (bson.ObjectID, error) {
+	userID, ok := su.GetAuthorID(ctx)
+	if !ok {
+		return bson.NilObjectID, fmt.Errorf("failed to access context values")
+	}
+
+	return bson.ObjectIDFromHex(userID)
+}
@@ -0,0 +1,42 @@
+package comment
+
+import (
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+const (
+	MAX_NAME_LENGTH = 100
+	MAX_BODY_LENGTH = 5000
+)
+
+/*
+commentPolicy is stricter than the policy used for blog posts.
+Readers can only use basic inline formatting, paragraphs and
+links, and every link is marked nofollow.
+*/
+var commentPolicy = newCommentPolicy()
+
+func newCommentPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+
+	p.AllowElements("p", "br", "b", "strong", "i", "em", "code", "blockquote")
+
+	p.AllowStandardURLs()
+	p.AllowAttrs("href").OnElements("a")
+	p.RequireNoFollowOnLinks(true)
+	p.RequireNoReferrerOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+
+	return p
+}
+
+func sanitizeComment(body string) string {
+	return strings.TrimSpace(commentPolicy.Sanitize(body))
+}
+
+// sanitizeName strips all markup from a commenter's display name
+func sanitizeName(name string) string {
+	return strings.TrimSpace(bluemonday.StrictPolicy().Sanitize(name))
+}
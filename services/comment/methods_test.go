@@ -0,0 +1,39 @@
+package comment
+
+import (
+	"testing"
+)
+
+type CommentSanitizationTest struct {
+	Input string
+	Want  string
+}
+
+func TestSanitizeComment(t *testing.T) {
+	tests := []CommentSanitizationTest{
+		{
+			Input: "<p>great <strong>post</strong></p>",
+			Want:  "<p>great <strong>post</strong></p>",
+		},
+		{
+			Input: "<img src=\"https://example.com/a.png\"><script>alert(1)</script>nice",
+			Want:  "nice",
+		},
+		{
+			Input: "<pre class=\"ql-syntax\" data-language=\"go\">code</pre>",
+			Want:  "code",
+		},
+		{
+			Input: "<a href=\"https://example.com\" onclick=\"x()\">link</a>",
+			Want:  "<a href=\"https://example.com\" rel=\"nofollow noreferrer noopener\" target=\"_blank\">link</a>",
+		},
+	}
+
+	for _, test := range tests {
+		result := sanitizeComment(test.Input)
+
+		if result != test.Want {
+			t.Errorf("error sanitizing comment: wanted %s, got %s", test.Want, result)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package feed
+
+import (
+	br "blog-api/repositories/blog"
+	"context"
+	"encoding/xml"
+	"mime"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	FEED_LIMIT = 20
+
+	JSON_FEED_VERSION = "https://jsonfeed.org/version/1.1"
+)
+
+type FeedService struct {
+	blogRepo br.BlogRepository
+	siteURL  string
+	apiURL   string
+	siteName string
+}
+
+func NewFeedService(repo br.BlogRepository, siteURL, apiURL, siteName string) *FeedService {
+	return &FeedService{
+		blogRepo: repo,
+		siteURL:  strings.TrimSuffix(siteURL, "/"),
+		apiURL:   strings.TrimSuffix(apiURL, "/"),
+		siteName: siteName,
+	}
+}
+
+/*
+feedURL is the public url of the feed served at the path, with only
+the filters that select its blogs, so a feed keeps the same self link
+and atom id whatever host, scheme or extra params it is requested by.
+*/
+func (s *FeedService) feedURL(feedPath, category, userID string) string {
+	filters := url.Values{}
+	if category != "" {
+		filters.Set("category", category)
+	}
+	if userID != "" {
+		filters.Set("userID", userID)
+	}
+
+	if len(filters) == 0 {
+		return s.apiURL + feedPath
+	}
+
+	return s.apiURL + feedPath + "?" + filters.Encode()
+}
+
+/*
+getBlogs looks up the latest published blogs for a feed, filtered
+by the comma separated categories and the author's hex ID when
+either is provided.
+*/
+func (s *FeedService) getBlogs(ctx context.Context, category, userID string) ([]br.BlogWithAuthor, error) {
+	q := &br.FeedQuery{
+		Category: category,
+		Limit:    FEED_LIMIT,
+	}
+
+	if userID != "" {
+		userObjectID, err := bson.ObjectIDFromHex(userID)
+		if err != nil {
+			return nil, err
+		}
+
+		q.Author = userObjectID
+	}
+
+	return s.blogRepo.GetBlogFeed(ctx, q)
+}
+
+func (s *FeedService) GetRSS(ctx context.Context, category, userID, feedPath string) ([]byte, error) {
+	selfURL := s.feedURL(feedPath, category, userID)
+
+	blogs, err := s.getBlogs(ctx, category, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	feed := RSS{
+		Version:     "2.0",
+		DCNamespace: "http://purl.org/dc/elements/1.1/",
+		MediaNS:     "http://search.yahoo.com/mrss/",
+		AtomNS:      "http://www.w3.org/2005/Atom",
+		Channel: RSSChannel{
+			Title:       s.siteName,
+			Link:        s.siteURL,
+			Description: "Latest posts from " + s.siteName,
+			SelfLink:    AtomLink{Href: selfURL, Rel: "self", Type: "application/rss+xml"},
+			Items:       []RSSItem{},
+		},
+	}
+
+	if len(blogs) > 0 {
+		feed.Channel.LastBuildDate = lastUpdated(blogs).Format(time.RFC1123Z)
+	}
+
+	for _, blog := range blogs {
+		link := s.postURL(blog.Slug)
+
+		item := RSSItem{
+			Title:       blog.Title,
+			Link:        link,
+			GUID:        RSSGUID{IsPermaLink: true, Value: link},
+			PubDate:     blog.CreatedAt.Format(time.RFC1123Z),
+			Creator:     blog.Author.Username,
+			Categories:  blog.Categories,
+			Description: RSSCDATA{Value: blog.Text},
+		}
+
+		if blog.ImageLocation != "" {
+			imageType := imageContentType(blog.ImageLocation)
+
+			// the size is not stored, 0 is the accepted unknown length
+			item.Enclosure = &RSSEnclosure{URL: blog.ImageLocation, Length: 0, Type: imageType}
+			item.Media = &MediaContent{URL: blog.ImageLocation, Medium: "image", Type: imageType}
+		}
+
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+
+	return marshalXML(feed)
+}
+
+func (s *FeedService) GetAtom(ctx context.Context, category, userID, feedPath string) ([]byte, error) {
+	selfURL := s.feedURL(feedPath, category, userID)
+
+	blogs, err := s.getBlogs(ctx, category, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	feed := AtomFeed{
+		ID:    selfURL,
+		Title: s.siteName,
+		Links: []AtomLink{
+			{Href: selfURL, Rel: "self", Type: "application/atom+xml"},
+			{Href: s.siteURL, Rel: "alternate", Type: "text/html"},
+		},
+		Entries: []AtomEntry{},
+	}
+
+	updated := time.Now()
+	if len(blogs) > 0 {
+		updated = lastUpdated(blogs)
+	}
+
+	feed.Updated = updated.Format(time.RFC3339)
+
+	for _, blog := range blogs {
+		link := s.postURL(blog.Slug)
+
+		entry := AtomEntry{
+			ID:        link,
+			Title:     blog.Title,
+			Published: blog.CreatedAt.Format(time.RFC3339),
+			Updated:   updatedAt(blog).Format(time.RFC3339),
+			Links: []AtomLink{
+				{Href: link, Rel: "alternate", Type: "text/html"},
+			},
+			Author:     AtomAuthor{Name: blog.Author.Username},
+			Categories: []AtomCategory{},
+			Content:    AtomContent{Type: "html", Value: blog.Text},
+		}
+
+		for _, category := range blog.Categories {
+			entry.Categories = append(entry.Categories, AtomCategory{Term: category})
+		}
+
+		if blog.ImageLocation != "" {
+			entry.Links = append(entry.Links, AtomLink{
+				Href: blog.ImageLocation,
+				Rel:  "enclosure",
+				Type: imageContentType(blog.ImageLocation),
+			})
+		}
+
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	return marshalXML(feed)
+}
+
+func (s *FeedService) GetJSONFeed(ctx context.Context, category, userID, feedPath string) (JSONFeed, error) {
+	feed := JSONFeed{
+		Version:     JSON_FEED_VERSION,
+		Title:       s.siteName,
+		HomePageURL: s.siteURL,
+		FeedURL:     s.feedURL(feedPath, category, userID),
+		Items:       []JSONFeedItem{},
+	}
+
+	blogs, err := s.getBlogs(ctx, category, userID)
+	if err != nil {
+		return feed, err
+	}
+
+	for _, blog := range blogs {
+		link := s.postURL(blog.Slug)
+
+		tags := blog.Categories
+		if tags == nil {
+			tags = []string{}
+		}
+
+		feed.Items = append(feed.Items, JSONFeedItem{
+			ID:            link,
+			URL:           link,
+			Title:         blog.Title,
+			ContentHTML:   blog.Text,
+			Image:         blog.ImageLocation,
+			DatePublished: blog.CreatedAt.Format(time.RFC3339),
+			DateModified:  updatedAt(blog).Format(time.RFC3339),
+			Tags:          tags,
+			Authors:       []JSONFeedAuthor{{Name: blog.Author.Username}},
+		})
+	}
+
+	return feed, nil
+}
+
+func (s *FeedService) postURL(slug string) string {
+	return s.siteURL + "/blog/" + slug
+}
+
+func marshalXML(v any) ([]byte, error) {
+	body, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), body...), nil
+}
+
+// updatedAt falls back to the creation date for
+// blogs saved before updatedAt was tracked
+func updatedAt(blog br.BlogWithAuthor) time.Time {
+	if blog.UpdatedAt.IsZero() {
+		return blog.CreatedAt
+	}
+
+	return blog.UpdatedAt
+}
+
+func lastUpdated(blogs []br.BlogWithAuthor) time.Time {
+	var latest time.Time
+
+	for _, blog := range blogs {
+		if t := updatedAt(blog); t.After(latest) {
+			latest = t
+		}
+	}
+
+	return latest
+}
+
+func imageContentType(location string) string {
+	if contentType := mime.TypeByExtension(strings.ToLower(path.Ext(location))); contentType != "" {
+		return contentType
+	}
+
+	return "image/jpeg"
+}
@@ -0,0 +1,141 @@
+package feed
+
+import (
+	br "blog-api/repositories/blog"
+	ur "blog-api/repositories/user"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+	"time"
+)
+
+// feedRepository stubs the single repository method feeds rely on
+type feedRepository struct {
+	br.BlogRepository
+	blogs []br.BlogWithAuthor
+}
+
+func (r *feedRepository) GetBlogFeed(ctx context.Context, q *br.FeedQuery) ([]br.BlogWithAuthor, error) {
+	return r.blogs, nil
+}
+
+func newTestFeedService() *FeedService {
+	created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	repo := &feedRepository{
+		blogs: []br.BlogWithAuthor{
+			{
+				Title:         "Sorting in Go",
+				Slug:          "sorting-in-go",
+				Text:          "<p>sort <b>all</b> the things</p>",
+				Categories:    []string{"go", "algorithms"},
+				Author:        ur.User{Username: "jonah"},
+				ImageLocation: "https://bucket.s3.us-east-1.amazonaws.com/featured.png",
+				CreatedAt:     created,
+				UpdatedAt:     created.Add(time.Hour),
+			},
+		},
+	}
+
+	return NewFeedService(repo, "https://www.example.com/", "https://api.example.com/", "Example")
+}
+
+func TestRSSFeed(t *testing.T) {
+	body, err := newTestFeedService().GetRSS(context.Background(), "", "", "/blog/feed.rss")
+	if err != nil {
+		t.Fatalf("failed to build rss feed: %v", err)
+	}
+
+	var feed struct {
+		Items []struct {
+			Link        string   `xml:"link"`
+			Description string   `xml:"description"`
+			Categories  []string `xml:"category"`
+			Enclosure   struct {
+				URL  string `xml:"url,attr"`
+				Type string `xml:"type,attr"`
+			} `xml:"enclosure"`
+		} `xml:"channel>item"`
+	}
+
+	if err := xml.Unmarshal(body, &feed); err != nil {
+		t.Fatalf("rss feed is not valid xml: %v", err)
+	}
+
+	if len(feed.Items) != 1 {
+		t.Fatalf("invalid amount of rss items: wanted 1, got %d", len(feed.Items))
+	}
+
+	item := feed.Items[0]
+
+	if item.Link != "https://www.example.com/blog/sorting-in-go" {
+		t.Errorf("invalid rss item link: got %s", item.Link)
+	}
+
+	if item.Description != "<p>sort <b>all</b> the things</p>" {
+		t.Errorf("invalid rss item description: got %s", item.Description)
+	}
+
+	if len(item.Categories) != 2 || item.Enclosure.Type != "image/png" {
+		t.Errorf("invalid rss item categories or enclosure: got %v, %s", item.Categories, item.Enclosure.Type)
+	}
+}
+
+func TestAtomFeed(t *testing.T) {
+	body, err := newTestFeedService().GetAtom(context.Background(), "go", "", "/blog/feed.atom")
+	if err != nil {
+		t.Fatalf("failed to build atom feed: %v", err)
+	}
+
+	var feed struct {
+		ID      string `xml:"id"`
+		Updated string `xml:"updated"`
+		Entries []struct {
+			Author  string `xml:"author>name"`
+			Content string `xml:"content"`
+		} `xml:"entry"`
+	}
+
+	if err := xml.Unmarshal(body, &feed); err != nil {
+		t.Fatalf("atom feed is not valid xml: %v", err)
+	}
+
+	// the id comes from the configured api url, never the request
+	if feed.ID != "https://api.example.com/blog/feed.atom?category=go" {
+		t.Errorf("invalid atom id: got %s", feed.ID)
+	}
+
+	if feed.Updated != "2025-03-01T13:00:00Z" {
+		t.Errorf("invalid atom updated time: got %s", feed.Updated)
+	}
+
+	if len(feed.Entries) != 1 || feed.Entries[0].Author != "jonah" || feed.Entries[0].Content != "<p>sort <b>all</b> the things</p>" {
+		t.Errorf("invalid atom entries: got %+v", feed.Entries)
+	}
+}
+
+func TestJSONFeed(t *testing.T) {
+	feed, err := newTestFeedService().GetJSONFeed(context.Background(), "", "", "/blog/feed.json")
+	if err != nil {
+		t.Fatalf("failed to build json feed: %v", err)
+	}
+
+	body, err := json.Marshal(feed)
+	if err != nil {
+		t.Fatalf("failed to encode json feed: %v", err)
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatalf("json feed is not valid json: %v", err)
+	}
+
+	if decoded["version"] != JSON_FEED_VERSION {
+		t.Errorf("invalid json feed version: got %v", decoded["version"])
+	}
+
+	if len(feed.Items) != 1 || feed.Items[0].Image == "" || feed.Items[0].Authors[0].Name != "jonah" {
+		t.Errorf("invalid json feed items: got %+v", feed.Items)
+	}
+}
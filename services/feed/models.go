@@ -0,0 +1,120 @@
+package feed
+
+import "encoding/xml"
+
+// RSS 2.0
+type RSS struct {
+	XMLName     xml.Name   `xml:"rss"`
+	Version     string     `xml:"version,attr"`
+	DCNamespace string     `xml:"xmlns:dc,attr"`
+	MediaNS     string     `xml:"xmlns:media,attr"`
+	AtomNS      string     `xml:"xmlns:atom,attr"`
+	Channel     RSSChannel `xml:"channel"`
+}
+
+type RSSChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	SelfLink      AtomLink  `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []RSSItem `xml:"item"`
+}
+
+type RSSItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	GUID        RSSGUID       `xml:"guid"`
+	PubDate     string        `xml:"pubDate"`
+	Creator     string        `xml:"dc:creator,omitempty"`
+	Categories  []string      `xml:"category"`
+	Description RSSCDATA      `xml:"description"`
+	Enclosure   *RSSEnclosure `xml:"enclosure"`
+	Media       *MediaContent `xml:"media:content"`
+}
+
+type RSSGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type RSSCDATA struct {
+	Value string `xml:",cdata"`
+}
+
+type RSSEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int    `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type MediaContent struct {
+	URL    string `xml:"url,attr"`
+	Medium string `xml:"medium,attr"`
+	Type   string `xml:"type,attr,omitempty"`
+}
+
+// Atom 1.0
+type AtomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []AtomLink  `xml:"link"`
+	Entries []AtomEntry `xml:"entry"`
+}
+
+type AtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type AtomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Links      []AtomLink     `xml:"link"`
+	Author     AtomAuthor     `xml:"author"`
+	Categories []AtomCategory `xml:"category"`
+	Content    AtomContent    `xml:"content"`
+}
+
+type AtomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type AtomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type AtomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// JSON Feed 1.1
+type JSONFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Items       []JSONFeedItem `json:"items"`
+}
+
+type JSONFeedItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url"`
+	Title         string           `json:"title"`
+	ContentHTML   string           `json:"content_html"`
+	Image         string           `json:"image,omitempty"`
+	DatePublished string           `json:"date_published"`
+	DateModified  string           `json:"date_modified"`
+	Tags          []string         `json:"tags"`
+	Authors       []JSONFeedAuthor `json:"authors"`
+}
+
+type JSONFeedAuthor struct {
+	Name string `json:"name"`
+}
@@ -0,0 +1,313 @@
+package media
+
+import (
+	"blog-api/images"
+	"blog-api/objectstore"
+	br "blog-api/repositories/blog"
+	r "blog-api/repositories/media"
+	rr "blog-api/repositories/revision"
+	su "blog-api/utilities/service"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var (
+	ErrMediaInUse   = errors.New("media is used by a blog")
+	ErrProfileMedia = errors.New("profile images are replaced by uploading a new one")
+)
+
+type MediaService struct {
+	mediaRepo    r.MediaRepository
+	blogRepo     br.BlogRepository
+	revisionRepo rr.RevisionRepository
+	store        objectstore.ObjectStore
+}
+
+func NewMediaService(
+	mediaRepo r.MediaRepository,
+	blogRepo br.BlogRepository,
+	revisionRepo rr.RevisionRepository,
+	store objectstore.ObjectStore,
+) *MediaService {
+	return &MediaService{
+		mediaRepo:    mediaRepo,
+		blogRepo:     blogRepo,
+		revisionRepo: revisionRepo,
+		store:        store,
+	}
+}
+
+func (s *MediaService) EnsureIndexes(ctx context.Context) error {
+	return s.mediaRepo.EnsureIndexes(ctx)
+}
+
+// GetMedia returns a page of the author's uploads, newest first
+func (s *MediaService) GetMedia(ctx context.Context, offset int) (r.MediaIndexResponse, error) {
+	var response r.MediaIndexResponse
+
+	uploader, err := authorObjectID(ctx)
+	if err != nil {
+		return response, err
+	}
+
+	media, hasMore, err := s.mediaRepo.GetMediaByUploader(ctx, uploader, offset)
+	if err != nil {
+		return response, err
+	}
+
+	response.Media = media
+	response.HasMore = hasMore
+
+	return response, nil
+}
+
+/*
+UploadMedia stores an image in the author's library for use in blog
+text. Uploading a file with the same name replaces the stored one.
+*/
+func (s *MediaService) UploadMedia(ctx context.Context, input *r.MediaUploadInput) (*r.MediaResponse, error) {
+	response := new(r.MediaResponse)
+
+	if input.Image == nil || len(input.ImageBytes) == 0 {
+		return response, fmt.Errorf("missing required form value: image")
+	}
+
+	uploader, err := authorObjectID(ctx)
+	if err != nil {
+		return response, err
+	}
+
+	key := objectstore.BuildKey(objectstore.MEDIA_LIBRARY, uploader.Hex(), input.Image.Filename)
+
+	url, err := s.store.Put(ctx, key, input.ImageBytes)
+	if err != nil {
+		return response, err
+	}
+
+	media, err := s.mediaRepo.SaveMedia(ctx, NewMedia(key, url, input.ImageBytes, r.KIND_LIBRARY, uploader))
+	if err != nil {
+		return response, err
+	}
+
+	response.Media = media
+
+	return response, nil
+}
+
+/*
+UploadBodyImage stores an image pasted into a blog's text. The key
+is derived from the image's content rather than its name, editors
+name every pasted image alike, so uploads never replace another
+image and the same image uploaded twice is stored once. Images left
+unused are removed by the storage collector.
+*/
+func (s *MediaService) UploadBodyImage(ctx context.Context, input *r.MediaUploadInput) (*r.MediaResponse, error) {
+	response := new(r.MediaResponse)
+
+	if input.Image == nil || len(input.ImageBytes) == 0 {
+		return response, fmt.Errorf("missing required form value: image")
+	}
+
+	uploader, err := authorObjectID(ctx)
+	if err != nil {
+		return response, err
+	}
+
+	key := objectstore.BuildKey(objectstore.BODY_IMAGES, uploader.Hex(), contentName(input.ImageBytes, input.Image.Filename))
+
+	url, err := s.store.Put(ctx, key, input.ImageBytes)
+	if err != nil {
+		return response, err
+	}
+
+	media, err := s.mediaRepo.SaveMedia(ctx, NewMedia(key, url, input.ImageBytes, r.KIND_BODY, uploader))
+	if err != nil {
+		return response, err
+	}
+
+	response.Media = media
+
+	return response, nil
+}
+
+/*
+DeleteMedia removes one of the author's uploads from storage with
+any resized variants. It is refused with ErrMediaInUse, listing the
+blogs, while a blog uses it as a featured image or in its text, or
+one of its revisions in the text a restore would bring back.
+*/
+func (s *MediaService) DeleteMedia(ctx context.Context, id string) (*r.MediaDeleteResponse, error) {
+	response := new(r.MediaDeleteResponse)
+
+	mediaObjectID, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return response, err
+	}
+
+	uploader, err := authorObjectID(ctx)
+	if err != nil {
+		return response, err
+	}
+
+	media, err := s.mediaRepo.GetMedia(ctx, mediaObjectID, uploader)
+	if err != nil || media == nil {
+		return response, err
+	}
+
+	if media.Kind == r.KIND_PROFILE {
+		return response, ErrProfileMedia
+	}
+
+	keys := append([]string{media.Key}, media.Variants...)
+
+	references, err := s.imageReferences(ctx, keys)
+	if err != nil {
+		return response, err
+	}
+
+	if len(references) > 0 {
+		response.References = references
+		return response, ErrMediaInUse
+	}
+
+	for _, key := range keys {
+		if err := s.store.Delete(ctx, key); err != nil {
+			return response, err
+		}
+	}
+
+	deleted, err := s.mediaRepo.DeleteMedia(ctx, media.ID)
+	if err != nil {
+		return response, err
+	}
+
+	if deleted {
+		response.Affected = 1
+	}
+
+	return response, nil
+}
+
+// RecordUpload adds an upload made outside the library, a featured
+// or profile image, to its uploader's media
+func (s *MediaService) RecordUpload(ctx context.Context, media *r.Media) error {
+	_, err := s.mediaRepo.SaveMedia(ctx, media)
+	return err
+}
+
+/*
+DeleteUnreferenced removes each of the keys from storage, and from
+the media library, unless a blog or revision still uses it. Returns the number
+of keys removed.
+*/
+func (s *MediaService) DeleteUnreferenced(ctx context.Context, keys []string) (int, error) {
+	deleted := 0
+
+	for _, key := range keys {
+		references, err := s.imageReferences(ctx, []string{key})
+		if err != nil {
+			return deleted, err
+		}
+
+		if len(references) > 0 {
+			continue
+		}
+
+		if err := s.store.Delete(ctx, key); err != nil {
+			return deleted, err
+		}
+
+		if _, err := s.mediaRepo.DeleteMediaByKey(ctx, key); err != nil {
+			return deleted, err
+		}
+
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+// imageReferences lists the blogs using any of the keys, then the
+// blogs only the text of an earlier revision of which uses them
+func (s *MediaService) imageReferences(ctx context.Context, keys []string) ([]br.ImageReference, error) {
+	references, err := s.blogRepo.GetImageReferences(ctx, keys)
+	if err != nil {
+		return references, err
+	}
+
+	revisions, err := s.revisionRepo.GetImageReferences(ctx, keys)
+	if err != nil {
+		return references, err
+	}
+
+	seen := map[bson.ObjectID]bool{}
+	for _, reference := range references {
+		seen[reference.ID] = true
+	}
+
+	for _, revision := range revisions {
+		if seen[revision.Blog] {
+			continue
+		}
+		seen[revision.Blog] = true
+
+		references = append(references, br.ImageReference{
+			ID:        revision.Blog,
+			Title:     revision.Title,
+			Slug:      revision.Slug,
+			Published: revision.Published,
+			Revision:  true,
+		})
+	}
+
+	return references, nil
+}
+
+// NewMedia describes an upload of the data for the media library
+func NewMedia(key, url string, data []byte, kind string, uploader bson.ObjectID) *r.Media {
+	media := &r.Media{
+		Key:         key,
+		URL:         url,
+		Size:        int64(len(data)),
+		ContentType: objectstore.DefaultContentType,
+		Kind:        kind,
+		Uploader:    uploader,
+		CreatedAt:   time.Now(),
+	}
+
+	if contentType, err := images.Sniff(data); err == nil {
+		media.ContentType = contentType
+	}
+
+	if width, height, err := images.Dimensions(data); err == nil {
+		media.Width = width
+		media.Height = height
+	}
+
+	return media
+}
+
+// contentName names a file by the hash of its data, keeping the
+// extension the upload was validated with
+func contentName(data []byte, filename string) string {
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:16]) + strings.ToLower(path.Ext(filename))
+}
+
+func authorObjectID(ctx context.Context) (bson.ObjectID, error) {
+	userID, ok := su.GetAuthorID(ctx)
+	if !ok {
+		return bson.NilObjectID, fmt.Errorf("failed to access context values")
+	}
+
+	return bson.ObjectIDFromHex(userID)
+}
@@ -0,0 +1,108 @@
+package media
+
+import (
+	ck "blog-api/contextkeys"
+	"blog-api/objectstore"
+	br "blog-api/repositories/blog"
+	r "blog-api/repositories/media"
+	rr "blog-api/repositories/revision"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// the repository stubs hold a single upload and its references
+type mediaRepository struct {
+	r.MediaRepository
+	media   *r.Media
+	deleted bool
+}
+
+func (m *mediaRepository) GetMedia(ctx context.Context, id, uploader bson.ObjectID) (*r.Media, error) {
+	return m.media, nil
+}
+
+func (m *mediaRepository) DeleteMedia(ctx context.Context, id bson.ObjectID) (bool, error) {
+	m.deleted = true
+	return true, nil
+}
+
+type blogRepository struct {
+	br.BlogRepository
+	references []br.ImageReference
+}
+
+func (b *blogRepository) GetImageReferences(ctx context.Context, keys []string) ([]br.ImageReference, error) {
+	return b.references, nil
+}
+
+type revisionRepository struct {
+	rr.RevisionRepository
+	revisions []rr.Revision
+}
+
+func (v *revisionRepository) GetImageReferences(ctx context.Context, keys []string) ([]rr.Revision, error) {
+	return v.revisions, nil
+}
+
+func TestContentName(t *testing.T) {
+	first := contentName([]byte("first image"), "image.PNG")
+	again := contentName([]byte("first image"), "pasted.PNG")
+	second := contentName([]byte("second image"), "image.PNG")
+
+	if first != again {
+		t.Errorf("wanted the same image to get the same name, got %s and %s", first, again)
+	}
+
+	if first == second {
+		t.Errorf("wanted different images to get different names, got %s", first)
+	}
+
+	if !strings.HasSuffix(first, ".png") || len(first) != 32+len(".png") {
+		t.Errorf("wanted a hash with a lowercase extension, got %s", first)
+	}
+}
+
+func TestDeleteMediaUsedByRevision(t *testing.T) {
+	author := bson.NewObjectID()
+	ctx := context.WithValue(context.Background(), ck.UserIDKey, author.Hex())
+
+	store := objectstore.NewMemoryStore("http://localhost:8080/files")
+	key := objectstore.BuildKey(objectstore.MEDIA_LIBRARY, author.Hex(), "photo.png")
+	store.Put(ctx, key, []byte("image"))
+
+	blog := bson.NewObjectID()
+	media := &mediaRepository{media: &r.Media{ID: bson.NewObjectID(), Key: key, Kind: r.KIND_LIBRARY}}
+	revisions := &revisionRepository{revisions: []rr.Revision{
+		{Blog: blog, Title: "Before the edit", Slug: "before-the-edit"},
+		{Blog: blog, Title: "Even earlier", Slug: "even-earlier"},
+	}}
+
+	service := NewMediaService(media, &blogRepository{}, revisions, store)
+
+	response, err := service.DeleteMedia(ctx, media.media.ID.Hex())
+	if !errors.Is(err, ErrMediaInUse) {
+		t.Fatalf("wanted ErrMediaInUse, got %v", err)
+	}
+
+	if len(response.References) != 1 || response.References[0].ID != blog || !response.References[0].Revision {
+		t.Errorf("wanted the blog listed once as a revision reference, got %+v", response.References)
+	}
+
+	if _, found := store.Get(key); !found || media.deleted {
+		t.Error("wanted the media kept")
+	}
+
+	revisions.revisions = nil
+
+	if _, err := service.DeleteMedia(ctx, media.media.ID.Hex()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, found := store.Get(key); found || !media.deleted {
+		t.Error("wanted the media deleted once unused")
+	}
+}
@@ -0,0 +1,231 @@
+package series
+
+import (
+	br "blog-api/repositories/blog"
+	r "blog-api/repositories/series"
+	su "blog-api/utilities/service"
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type SeriesService struct {
+	seriesRepo r.SeriesRepository
+	blogRepo   br.BlogRepository
+}
+
+func NewSeriesService(seriesRepo r.SeriesRepository, blogRepo br.BlogRepository) *SeriesService {
+	return &SeriesService{
+		seriesRepo: seriesRepo,
+		blogRepo:   blogRepo,
+	}
+}
+
+func (s *SeriesService) GetSeriesIndex(ctx context.Context, userID string) (r.SeriesIndexResponse, error) {
+	var response r.SeriesIndexResponse
+
+	filter := bson.M{}
+
+	if userID != "" {
+		userObjectID, err := bson.ObjectIDFromHex(userID)
+		if err != nil {
+			return response, err
+		}
+
+		filter["author"] = userObjectID
+	}
+
+	series, err := s.seriesRepo.GetSeriesIndex(ctx, filter)
+	if err != nil {
+		return response, err
+	}
+
+	response.Series = series
+
+	return response, nil
+}
+
+/*
+GetSeries returns the series along with its published
+posts in series order.
+*/
+func (s *SeriesService) GetSeries(ctx context.Context, id string) (r.SeriesResponse, error) {
+	var response r.SeriesResponse
+
+	seriesObjectID, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return response, err
+	}
+
+	series, err := s.seriesRepo.GetSeriesByID(ctx, seriesObjectID)
+	if err != nil {
+		return response, err
+	}
+
+	blogs, err := s.blogRepo.GetBlogsByIDs(ctx, series.Posts, bson.M{"published": true})
+	if err != nil {
+		return response, err
+	}
+
+	response.Series = series
+	response.Posts = r.OrderBlogs(series.Posts, blogs)
+
+	return response, nil
+}
+
+func (s *SeriesService) CreateSeries(ctx context.Context, input *r.SeriesInput) (r.SeriesResponse, error) {
+	var response r.SeriesResponse
+
+	authorObjectID, err := getAuthorObjectID(ctx)
+	if err != nil {
+		return response, err
+	}
+
+	posts, err := s.validatePosts(ctx, input.Posts, authorObjectID, bson.NilObjectID)
+	if err != nil {
+		return response, err
+	}
+
+	series := &r.Series{
+		Author:      authorObjectID,
+		Title:       input.Title,
+		Description: input.Description,
+		Posts:       posts,
+	}
+
+	created, err := s.seriesRepo.CreateSeries(ctx, series)
+	if err != nil {
+		return response, err
+	}
+
+	response.Series = created
+
+	return response, nil
+}
+
+/*
+UpdateSeries replaces the series title and description when
+provided and the post order when a post list is provided.
+*/
+func (s *SeriesService) UpdateSeries(ctx context.Context, id string, input *r.SeriesInput) (r.SeriesResponse, error) {
+	var response r.SeriesResponse
+
+	seriesObjectID, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return response, err
+	}
+
+	authorObjectID, err := getAuthorObjectID(ctx)
+	if err != nil {
+		return response, err
+	}
+
+	updates := bson.M{}
+
+	if input.Title != "" {
+		updates["title"] = input.Title
+	}
+
+	if input.Description != "" {
+		updates["description"] = input.Description
+	}
+
+	if input.Posts != nil {
+		posts, err := s.validatePosts(ctx, input.Posts, authorObjectID, seriesObjectID)
+		if err != nil {
+			return response, err
+		}
+
+		updates["posts"] = posts
+	}
+
+	series, err := s.seriesRepo.UpdateSeries(ctx, seriesObjectID, authorObjectID, updates)
+	if err != nil {
+		return response, err
+	}
+
+	response.Series = series
+
+	return response, nil
+}
+
+func (s *SeriesService) DeleteSeries(ctx context.Context, id string) (*br.GenericUpdateResponse, error) {
+	response := new(br.GenericUpdateResponse)
+
+	seriesObjectID, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return response, err
+	}
+
+	authorObjectID, err := getAuthorObjectID(ctx)
+	if err != nil {
+		return response, err
+	}
+
+	affected, err := s.seriesRepo.DeleteSeries(ctx, seriesObjectID, authorObjectID)
+	if err != nil {
+		return response, err
+	}
+
+	response.Affected = affected
+
+	return response, nil
+}
+
+/*
+validatePosts converts the provided post IDs and ensures each post
+exists, is owned by the author, is listed once and is not already
+part of another series.
+*/
+func (s *SeriesService) validatePosts(ctx context.Context, postIDs []string, author, series bson.ObjectID) ([]bson.ObjectID, error) {
+	posts := []bson.ObjectID{}
+	seen := make(map[bson.ObjectID]struct{}, len(postIDs))
+
+	for _, postID := range postIDs {
+		postObjectID, err := bson.ObjectIDFromHex(postID)
+		if err != nil {
+			return posts, err
+		}
+
+		if _, ok := seen[postObjectID]; ok {
+			return posts, fmt.Errorf("post %s is listed more than once", postID)
+		}
+
+		seen[postObjectID] = struct{}{}
+		posts = append(posts, postObjectID)
+	}
+
+	if len(posts) == 0 {
+		return posts, nil
+	}
+
+	blogs, err := s.blogRepo.GetBlogsByIDs(ctx, posts, bson.M{"author": author})
+	if err != nil {
+		return posts, err
+	}
+
+	if len(blogs) != len(posts) {
+		return posts, fmt.Errorf("every post in a series must exist and belong to the author")
+	}
+
+	taken, err := s.seriesRepo.HasPostsInOtherSeries(ctx, posts, series)
+	if err != nil {
+		return posts, err
+	}
+
+	if taken {
+		return posts, fmt.Errorf("a post can only be part of one series")
+	}
+
+	return posts, nil
+}
+
+func getAuthorObjectID(ctx context.Context) (bson.ObjectID, error) {
+	userID, ok := su.GetAuthorID(ctx)
+	if !ok {
+		return bson.NilObjectID, fmt.Errorf("failed to access context values")
+	}
+
+	return bson.ObjectIDFromHex(userID)
+}
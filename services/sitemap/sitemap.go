@@ -0,0 +1,210 @@
+package sitemap
+
+import (
+	br "blog-api/repositories/blog"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// the sitemap protocol allows at most 50,000 URLs per file
+	MAX_SITEMAP_URLS = 50000
+
+	LASTMOD_FORMAT = "2006-01-02"
+
+	POST_PATH     = "/blog/"
+	CATEGORY_PATH = "/blog/category/"
+	AUTHOR_PATH   = "/blog/author/"
+)
+
+type SitemapService struct {
+	blogRepo br.BlogRepository
+	siteURL  string
+	disallow []string
+	urlLimit int
+}
+
+/*
+NewSitemapService builds the sitemap of the site. Crawlers only
+accept a sitemap listing pages of its own host, so its files are
+located under the site url and the site proxies them to the api.
+*/
+func NewSitemapService(repo br.BlogRepository, config SitemapConfig) *SitemapService {
+	return &SitemapService{
+		blogRepo: repo,
+		siteURL:  strings.TrimSuffix(config.SiteURL, "/"),
+		disallow: config.Disallow,
+		urlLimit: MAX_SITEMAP_URLS,
+	}
+}
+
+/*
+GetSitemap returns the sitemap served at /sitemap.xml. While every
+URL fits in a single file that is a url set, otherwise it is an
+index pointing at each numbered sitemap page.
+*/
+func (s *SitemapService) GetSitemap(ctx context.Context) ([]byte, error) {
+	urls, err := s.getURLs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(urls) <= s.urlLimit {
+		return marshalXML(URLSet{XMLNS: SITEMAP_NAMESPACE, URLs: urls})
+	}
+
+	index := SitemapIndex{XMLNS: SITEMAP_NAMESPACE}
+
+	for page := 1; (page-1)*s.urlLimit < len(urls); page++ {
+		index.Sitemaps = append(index.Sitemaps, SitemapEntry{
+			Loc:     s.siteURL + "/sitemaps/" + pageFilename(page),
+			LastMod: latestLastMod(pageURLs(urls, page, s.urlLimit)),
+		})
+	}
+
+	return marshalXML(index)
+}
+
+/*
+GetSitemapPage returns a single numbered page of the sitemap
+index. Returns an error when the page does not exist.
+*/
+func (s *SitemapService) GetSitemapPage(ctx context.Context, page int) ([]byte, error) {
+	urls, err := s.getURLs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pageSlice := pageURLs(urls, page, s.urlLimit)
+	if len(pageSlice) == 0 {
+		return nil, fmt.Errorf("sitemap page %d does not exist", page)
+	}
+
+	return marshalXML(URLSet{XMLNS: SITEMAP_NAMESPACE, URLs: pageSlice})
+}
+
+// GetRobots returns the site's robots.txt pointing crawlers at the sitemap
+func (s *SitemapService) GetRobots() []byte {
+	var builder strings.Builder
+
+	builder.WriteString("User-agent: *\n")
+
+	if len(s.disallow) == 0 {
+		builder.WriteString("Allow: /\n")
+	}
+
+	for _, path := range s.disallow {
+		builder.WriteString("Disallow: " + path + "\n")
+	}
+
+	builder.WriteString("\nSitemap: " + s.siteURL + "/sitemap.xml\n")
+
+	return []byte(builder.String())
+}
+
+// getURLs collects every published blog, category and author page
+func (s *SitemapService) getURLs(ctx context.Context) ([]URL, error) {
+	slugs, err := s.blogRepo.GetPublishedSlugs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	categories, err := s.blogRepo.GetCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	authors, err := s.blogRepo.GetAuthors(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	urls := make([]URL, 0, len(slugs)+len(categories)+len(authors))
+
+	for _, blog := range slugs {
+		lastMod := blog.UpdatedAt
+		if lastMod.IsZero() {
+			lastMod = blog.CreatedAt
+		}
+
+		urls = append(urls, URL{
+			Loc:     s.siteURL + POST_PATH + url.PathEscape(blog.Slug),
+			LastMod: formatLastMod(lastMod),
+		})
+	}
+
+	for _, category := range categories {
+		urls = append(urls, URL{
+			Loc:     s.siteURL + CATEGORY_PATH + url.PathEscape(category.Name),
+			LastMod: formatLastMod(category.LatestPost),
+		})
+	}
+
+	for _, author := range authors {
+		urls = append(urls, URL{
+			Loc:     s.siteURL + AUTHOR_PATH + url.PathEscape(author.Username),
+			LastMod: formatLastMod(author.LastUpdated),
+		})
+	}
+
+	return urls, nil
+}
+
+// pageURLs returns the 1 based page of urls
+func pageURLs(urls []URL, page, limit int) []URL {
+	start := (page - 1) * limit
+	if page < 1 || start >= len(urls) {
+		return nil
+	}
+
+	return urls[start:min(start+limit, len(urls))]
+}
+
+func pageFilename(page int) string {
+	return fmt.Sprintf("sitemap-%d.xml", page)
+}
+
+// ParsePageFilename reads the page number out of a sitemap-{n}.xml filename
+func ParsePageFilename(filename string) (int, error) {
+	var page int
+
+	if _, err := fmt.Sscanf(filename, "sitemap-%d.xml", &page); err != nil || pageFilename(page) != filename {
+		return 0, fmt.Errorf("invalid sitemap filename: %s", filename)
+	}
+
+	return page, nil
+}
+
+func latestLastMod(urls []URL) string {
+	latest := ""
+
+	// lastmod values share a fixed width format so they sort as strings
+	for _, u := range urls {
+		if u.LastMod > latest {
+			latest = u.LastMod
+		}
+	}
+
+	return latest
+}
+
+func formatLastMod(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(LASTMOD_FORMAT)
+}
+
+func marshalXML(v any) ([]byte, error) {
+	body, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), body...), nil
+}
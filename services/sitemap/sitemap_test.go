@@ -0,0 +1,143 @@
+package sitemap
+
+import (
+	br "blog-api/repositories/blog"
+	"context"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sitemapRepository stubs the repository methods sitemaps rely on
+type sitemapRepository struct {
+	br.BlogRepository
+}
+
+var updated = time.Date(2025, 3, 2, 12, 0, 0, 0, time.UTC)
+
+func (r *sitemapRepository) GetPublishedSlugs(ctx context.Context) ([]br.BlogSlug, error) {
+	return []br.BlogSlug{
+		{Slug: "sorting-in-go", CreatedAt: updated.Add(-time.Hour), UpdatedAt: updated},
+		{Slug: "first-post", CreatedAt: updated.Add(-48 * time.Hour)},
+	}, nil
+}
+
+func (r *sitemapRepository) GetCategories(ctx context.Context) ([]br.CategorySummary, error) {
+	return []br.CategorySummary{{Name: "web dev", Count: 2, LatestPost: updated}}, nil
+}
+
+func (r *sitemapRepository) GetAuthors(ctx context.Context) ([]br.AuthorSummary, error) {
+	return []br.AuthorSummary{{Username: "jonah", Count: 2, LastUpdated: updated}}, nil
+}
+
+func newTestSitemapService() *SitemapService {
+	return NewSitemapService(&sitemapRepository{}, SitemapConfig{
+		SiteURL: "https://www.example.com/",
+	})
+}
+
+func TestSitemap(t *testing.T) {
+	body, err := newTestSitemapService().GetSitemap(context.Background())
+	if err != nil {
+		t.Fatalf("failed to build sitemap: %v", err)
+	}
+
+	var urlSet URLSet
+	if err := xml.Unmarshal(body, &urlSet); err != nil {
+		t.Fatalf("sitemap is not valid xml: %v", err)
+	}
+
+	expected := []URL{
+		{Loc: "https://www.example.com/blog/sorting-in-go", LastMod: "2025-03-02"},
+		{Loc: "https://www.example.com/blog/first-post", LastMod: "2025-02-28"},
+		{Loc: "https://www.example.com/blog/category/web%20dev", LastMod: "2025-03-02"},
+		{Loc: "https://www.example.com/blog/author/jonah", LastMod: "2025-03-02"},
+	}
+
+	if len(urlSet.URLs) != len(expected) {
+		t.Fatalf("expected %d urls, got %d", len(expected), len(urlSet.URLs))
+	}
+
+	for i, url := range expected {
+		if urlSet.URLs[i] != url {
+			t.Errorf("url %d: expected %+v, got %+v", i, url, urlSet.URLs[i])
+		}
+	}
+}
+
+func TestSitemapIndex(t *testing.T) {
+	service := newTestSitemapService()
+	service.urlLimit = 3
+
+	body, err := service.GetSitemap(context.Background())
+	if err != nil {
+		t.Fatalf("failed to build sitemap: %v", err)
+	}
+
+	var index SitemapIndex
+	if err := xml.Unmarshal(body, &index); err != nil {
+		t.Fatalf("sitemap index is not valid xml: %v", err)
+	}
+
+	if len(index.Sitemaps) != 2 {
+		t.Fatalf("expected 2 sitemap pages, got %d", len(index.Sitemaps))
+	}
+
+	if index.Sitemaps[1].Loc != "https://www.example.com/sitemaps/sitemap-2.xml" {
+		t.Errorf("unexpected sitemap page location: %s", index.Sitemaps[1].Loc)
+	}
+
+	page, err := service.GetSitemapPage(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("failed to build sitemap page: %v", err)
+	}
+
+	var urlSet URLSet
+	if err := xml.Unmarshal(page, &urlSet); err != nil {
+		t.Fatalf("sitemap page is not valid xml: %v", err)
+	}
+
+	if len(urlSet.URLs) != 1 || urlSet.URLs[0].Loc != "https://www.example.com/blog/author/jonah" {
+		t.Errorf("unexpected urls on last page: %+v", urlSet.URLs)
+	}
+
+	if _, err := service.GetSitemapPage(context.Background(), 3); err == nil {
+		t.Error("expected an error for a page past the end")
+	}
+}
+
+func TestParsePageFilename(t *testing.T) {
+	if page, err := ParsePageFilename("sitemap-12.xml"); err != nil || page != 12 {
+		t.Errorf("expected page 12, got %d (%v)", page, err)
+	}
+
+	for _, filename := range []string{"sitemap.xml", "sitemap-1.xml.gz", "sitemap-01.xml", "robots.txt"} {
+		if _, err := ParsePageFilename(filename); err == nil {
+			t.Errorf("expected %s to be rejected", filename)
+		}
+	}
+}
+
+func TestRobots(t *testing.T) {
+	robots := string(newTestSitemapService().GetRobots())
+
+	if !strings.Contains(robots, "Allow: /\n") {
+		t.Errorf("expected everything to be allowed:\n%s", robots)
+	}
+
+	if !strings.HasSuffix(robots, "Sitemap: https://www.example.com/sitemap.xml\n") {
+		t.Errorf("expected the sitemap under the site url:\n%s", robots)
+	}
+
+	service := NewSitemapService(&sitemapRepository{}, SitemapConfig{
+		SiteURL:  "https://www.example.com",
+		Disallow: []string{"/drafts"},
+	})
+
+	robots = string(service.GetRobots())
+
+	if !strings.Contains(robots, "Disallow: /drafts\n") || strings.Contains(robots, "Allow: /\n") {
+		t.Errorf("expected /drafts to be disallowed:\n%s", robots)
+	}
+}
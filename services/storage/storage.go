@@ -0,0 +1,260 @@
+package storage
+
+import (
+	"blog-api/objectstore"
+	br "blog-api/repositories/blog"
+	mr "blog-api/repositories/media"
+	rr "blog-api/repositories/revision"
+	ur "blog-api/repositories/user"
+	bs "blog-api/services/blog"
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// uploads younger than this may belong to a blog still being saved
+const DEFAULT_GRACE_PERIOD = 24 * time.Hour
+
+type StorageService struct {
+	blogRepo     br.BlogRepository
+	revisionRepo rr.RevisionRepository
+	userRepo     ur.UserRepository
+	mediaRepo    mr.MediaRepository
+	store        objectstore.ObjectStore
+}
+
+func NewStorageService(
+	blogRepo br.BlogRepository,
+	revisionRepo rr.RevisionRepository,
+	userRepo ur.UserRepository,
+	mediaRepo mr.MediaRepository,
+	store objectstore.ObjectStore,
+) *StorageService {
+	return &StorageService{
+		blogRepo:     blogRepo,
+		revisionRepo: revisionRepo,
+		userRepo:     userRepo,
+		mediaRepo:    mediaRepo,
+		store:        store,
+	}
+}
+
+type CollectOptions struct {
+	DryRun bool
+	Grace  time.Duration
+}
+
+// CollectReport summarizes a garbage collection run
+type CollectReport struct {
+	DryRun     bool                 `json:"dryRun"`
+	Grace      string               `json:"grace"`
+	Scanned    int                  `json:"scanned"`
+	Referenced int                  `json:"referenced"`
+	Recent     int                  `json:"recent"` // unreferenced but within the grace period
+	Orphaned   int                  `json:"orphaned"`
+	Deleted    int                  `json:"deleted"`
+	Bytes      int64                `json:"bytes"` // size of the orphaned objects
+	Orphans    []objectstore.Object `json:"orphans"`
+	Failed     []string             `json:"failed"`
+	Duration   time.Duration        `json:"-"`
+}
+
+func (r *CollectReport) String() string {
+	action := "deleted"
+	if r.DryRun {
+		action = "would delete"
+	}
+
+	return fmt.Sprintf(
+		"scanned %d objects: %d referenced, %d recent, %d orphaned (%d bytes), %s %d, %d failed in %s",
+		r.Scanned, r.Referenced, r.Recent, r.Orphaned, r.Bytes, action, r.orphansActedOn(), len(r.Failed), r.Duration.Round(time.Millisecond),
+	)
+}
+
+func (r *CollectReport) orphansActedOn() int {
+	if r.DryRun {
+		return r.Orphaned
+	}
+
+	return r.Deleted
+}
+
+/*
+CollectGarbage lists the objects under the upload prefixes and removes
+those no blog, profile or media library entry references once they
+are older than the grace period. A dry run only reports them.
+*/
+func (s *StorageService) CollectGarbage(ctx context.Context, opts CollectOptions) (*CollectReport, error) {
+	started := time.Now()
+
+	if opts.Grace <= 0 {
+		opts.Grace = DEFAULT_GRACE_PERIOD
+	}
+
+	report := &CollectReport{
+		DryRun:  opts.DryRun,
+		Grace:   opts.Grace.String(),
+		Orphans: []objectstore.Object{},
+		Failed:  []string{},
+	}
+
+	// read the references before listing so an upload made during
+	// the run is at worst recent, never unreferenced and old
+	referenced, err := s.referencedKeys(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	objects := []objectstore.Object{}
+	for _, prefix := range objectstore.UPLOAD_PREFIXES {
+		listed, err := s.store.List(ctx, prefix)
+		if err != nil {
+			return report, err
+		}
+
+		objects = append(objects, listed...)
+	}
+
+	report.Scanned = len(objects)
+	report.Orphans, report.Referenced, report.Recent = findOrphans(objects, referenced, started.Add(-opts.Grace))
+	report.Orphaned = len(report.Orphans)
+
+	for _, orphan := range report.Orphans {
+		report.Bytes += orphan.Size
+	}
+
+	if !opts.DryRun {
+		for _, orphan := range report.Orphans {
+			if err := ctx.Err(); err != nil {
+				return report, err
+			}
+
+			if err := s.store.Delete(ctx, orphan.Key); err != nil {
+				report.Failed = append(report.Failed, orphan.Key)
+				continue
+			}
+
+			if _, err := s.mediaRepo.DeleteMediaByKey(ctx, orphan.Key); err != nil {
+				log.Printf("storage collector: removing media record %s: %v", orphan.Key, err)
+			}
+
+			report.Deleted++
+		}
+	}
+
+	report.Duration = time.Since(started)
+
+	return report, nil
+}
+
+/*
+RunStorageCollector collects garbage on every interval until the
+provided context is cancelled, logging each run's report.
+*/
+func (s *StorageService) RunStorageCollector(ctx context.Context, interval time.Duration, opts CollectOptions) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		report, err := s.CollectGarbage(ctx, opts)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("storage collector: %v", err)
+			}
+			continue
+		}
+
+		log.Printf("storage collector: %s", report)
+	}
+}
+
+/*
+referencedKeys returns every key in use: the featured images and
+their variants, the images of blog text, the images of revision text
+a restore would bring back, profile images and the author's media
+library, which is only emptied by the author.
+*/
+func (s *StorageService) referencedKeys(ctx context.Context) (map[string]bool, error) {
+	referenced := map[string]bool{}
+
+	blogs, err := s.blogRepo.GetImageUsage(ctx)
+	if err != nil {
+		return referenced, err
+	}
+
+	for i := range blogs {
+		keys, err := bs.ReferencedKeys(s.store, &blogs[i])
+		if err != nil {
+			return referenced, err
+		}
+
+		for _, key := range keys {
+			referenced[key] = true
+		}
+	}
+
+	revisions, err := s.revisionRepo.GetImageUsage(ctx)
+	if err != nil {
+		return referenced, err
+	}
+
+	// a restore only brings back the text, the featured image of a
+	// revision is kept by the blog using it, along with its variants
+	for _, revision := range revisions {
+		keys, err := bs.ReferencedKeys(s.store, &br.Blog{Text: revision.Text})
+		if err != nil {
+			return referenced, err
+		}
+
+		for _, key := range keys {
+			referenced[key] = true
+		}
+	}
+
+	profiles, err := s.userRepo.GetProfileImages(ctx)
+	if err != nil {
+		return referenced, err
+	}
+
+	for _, profile := range profiles {
+		referenced[objectstore.BuildKey(objectstore.USER_PROFILE, profile.ID.Hex(), profile.ImageKey)] = true
+	}
+
+	library, err := s.mediaRepo.GetKeysByKind(ctx, mr.KIND_LIBRARY)
+	if err != nil {
+		return referenced, err
+	}
+
+	for _, key := range library {
+		referenced[key] = true
+	}
+
+	return referenced, nil
+}
+
+// findOrphans returns the unreferenced objects last modified before
+// the cutoff, with the number of referenced and recent objects
+func findOrphans(objects []objectstore.Object, referenced map[string]bool, cutoff time.Time) ([]objectstore.Object, int, int) {
+	orphans := []objectstore.Object{}
+	referencedCount, recent := 0, 0
+
+	for _, object := range objects {
+		switch {
+		case referenced[object.Key]:
+			referencedCount++
+		case object.LastModified.After(cutoff):
+			recent++
+		default:
+			orphans = append(orphans, object)
+		}
+	}
+
+	return orphans, referencedCount, recent
+}
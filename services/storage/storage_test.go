@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"blog-api/objectstore"
+	br "blog-api/repositories/blog"
+	mr "blog-api/repositories/media"
+	rr "blog-api/repositories/revision"
+	ur "blog-api/repositories/user"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// the repository stubs return the references a collection reads
+type blogRepository struct {
+	br.BlogRepository
+	blogs []br.Blog
+}
+
+func (r *blogRepository) GetImageUsage(ctx context.Context) ([]br.Blog, error) {
+	return r.blogs, nil
+}
+
+type revisionRepository struct {
+	rr.RevisionRepository
+	revisions []rr.Revision
+}
+
+func (r *revisionRepository) GetImageUsage(ctx context.Context) ([]rr.Revision, error) {
+	return r.revisions, nil
+}
+
+type userRepository struct {
+	ur.UserRepository
+	profiles []ur.UserProfileImage
+}
+
+func (r *userRepository) GetProfileImages(ctx context.Context) ([]ur.UserProfileImage, error) {
+	return r.profiles, nil
+}
+
+type mediaRepository struct {
+	mr.MediaRepository
+	library []string
+	deleted []string
+}
+
+func (r *mediaRepository) GetKeysByKind(ctx context.Context, kind string) ([]string, error) {
+	return r.library, nil
+}
+
+func (r *mediaRepository) DeleteMediaByKey(ctx context.Context, key string) (bool, error) {
+	r.deleted = append(r.deleted, key)
+	return true, nil
+}
+
+func TestFindOrphans(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	cutoff := now.Add(-DEFAULT_GRACE_PERIOD)
+
+	objects := []objectstore.Object{
+		{Key: "featured_images/users/1/used.png", LastModified: now.Add(-48 * time.Hour)},
+		{Key: "featured_images/users/1/replaced.png", Size: 10, LastModified: now.Add(-48 * time.Hour)},
+		{Key: "featured_images/users/1/uploading.png", LastModified: now.Add(-time.Hour)},
+		{Key: "uploads/users/1/old-avatar.png", Size: 5, LastModified: now.Add(-72 * time.Hour)},
+	}
+
+	referenced := map[string]bool{"featured_images/users/1/used.png": true}
+
+	orphans, referencedCount, recent := findOrphans(objects, referenced, cutoff)
+
+	if referencedCount != 1 || recent != 1 {
+		t.Errorf("wanted 1 referenced and 1 recent, got %d and %d", referencedCount, recent)
+	}
+
+	if len(orphans) != 2 || orphans[0].Key != "featured_images/users/1/replaced.png" || orphans[1].Key != "uploads/users/1/old-avatar.png" {
+		t.Errorf("wanted the replaced image and old avatar, got %+v", orphans)
+	}
+}
+
+func TestCollectReportString(t *testing.T) {
+	report := &CollectReport{DryRun: true, Scanned: 4, Referenced: 1, Recent: 1, Orphaned: 2, Bytes: 15}
+
+	if got := report.String(); !strings.Contains(got, "would delete 2") {
+		t.Errorf("wanted a dry run to report what it would delete, got %s", got)
+	}
+
+	report.DryRun = false
+	report.Deleted = 1
+	report.Failed = []string{"uploads/users/1/old-avatar.png"}
+
+	if got := report.String(); !strings.Contains(got, "deleted 1, 1 failed") {
+		t.Errorf("wanted the deleted and failed counts, got %s", got)
+	}
+}
+
+func TestCollectGarbage(t *testing.T) {
+	ctx := context.Background()
+	store := objectstore.NewMemoryStore("http://localhost:8080/files")
+	author := bson.NewObjectID()
+
+	for _, key := range []string{
+		"featured_images/users/1/cover.png",
+		"featured_images/users/1/cover-thumbnail.jpg",
+		"featured_images/users/1/replaced.png",
+		"featured_images/users/1/earlier.png",
+		"body_images/users/1/pasted.png",
+		"body_images/users/1/removed.png",
+		"media/users/1/library.png",
+		"uploads/users/" + author.Hex() + "/me.png",
+	} {
+		store.Put(ctx, key, []byte("image"))
+	}
+
+	blogs := &blogRepository{blogs: []br.Blog{{
+		ImageKey: "featured_images/users/1/cover.png",
+		ImageVariants: []br.ImageVariant{
+			{Key: "featured_images/users/1/cover-thumbnail.jpg"},
+			{Key: "featured_images/users/1/cover.png"},
+		},
+		Text: `<img src="` + store.URL("body_images/users/1/pasted.png") + `">`,
+	}}}
+	revisions := &revisionRepository{revisions: []rr.Revision{{
+		ImageKey: "featured_images/users/1/earlier.png",
+		Text:     `<img src="` + store.URL("body_images/users/1/removed.png") + `">`,
+	}}}
+	users := &userRepository{profiles: []ur.UserProfileImage{{ID: author, ImageKey: "me.png"}}}
+	media := &mediaRepository{library: []string{"media/users/1/library.png"}}
+
+	service := NewStorageService(blogs, revisions, users, media, store)
+
+	// every upload is recent until the grace period is tiny
+	report, err := service.CollectGarbage(ctx, CollectOptions{Grace: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Scanned != 8 || report.Recent != 2 || report.Deleted != 0 {
+		t.Errorf("wanted the unreferenced upload kept as recent, got %s", report)
+	}
+
+	time.Sleep(time.Millisecond)
+
+	report, err = service.CollectGarbage(ctx, CollectOptions{Grace: time.Nanosecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a revision's featured image is never restored, only its text
+	orphans := []string{"featured_images/users/1/earlier.png", "featured_images/users/1/replaced.png"}
+
+	if report.Referenced != 6 || report.Deleted != 2 || report.Orphans[0].Key != orphans[0] || report.Orphans[1].Key != orphans[1] {
+		t.Errorf("wanted the replaced image and the revision's featured image deleted, got %s", report)
+	}
+
+	for _, key := range orphans {
+		if _, found := store.Get(key); found {
+			t.Errorf("wanted %s removed from the store", key)
+		}
+	}
+
+	if strings.Join(media.deleted, ",") != strings.Join(orphans, ",") {
+		t.Errorf("wanted the orphans' media records removed, got %v", media.deleted)
+	}
+}
@@ -1,10 +1,12 @@
 package user
 
 import (
+	"blog-api/objectstore"
+	mr "blog-api/repositories/media"
 	prr "blog-api/repositories/passwordreset"
 	r "blog-api/repositories/user"
-	"blog-api/s3"
 	es "blog-api/services/email"
+	ms "blog-api/services/media"
 	prs "blog-api/services/passwordreset"
 	u "blog-api/utilities"
 	"context"
@@ -20,13 +22,23 @@ type UserService struct {
 	userRepo             r.UserRepository
 	passwordResetService prs.PasswordResetService
 	emailService         es.EmailService
+	mediaService         *ms.MediaService
+	store                objectstore.ObjectStore
 }
 
-func NewUserService(userRepo r.UserRepository, passwordResetService prs.PasswordResetService, emailService es.EmailService) *UserService {
+func NewUserService(
+	userRepo r.UserRepository,
+	passwordResetService prs.PasswordResetService,
+	emailService es.EmailService,
+	mediaService *ms.MediaService,
+	store objectstore.ObjectStore,
+) *UserService {
 	return &UserService{
 		userRepo:             userRepo,
 		passwordResetService: passwordResetService,
 		emailService:         emailService,
+		mediaService:         mediaService,
+		store:                store,
 	}
 }
 
@@ -195,13 +207,23 @@ func (s *UserService) UpdateUser(ctx context.Context, input *r.UserUpdatePost) (
 		return user, fmt.Errorf("failed to get author ID")
 	}
 
-	key := s3.BuildS3Key(s3.USER_PROFILE, authorId, input.Image.Filename)
+	key := objectstore.BuildKey(objectstore.USER_PROFILE, authorId, input.Image.Filename)
 
-	imageUri, err := s3.UploadToS3New(input.Image, input.ImageBytes, key)
+	imageUri, err := s.store.Put(ctx, key, input.ImageBytes)
 	if err != nil {
 		return user, err
 	}
 
+	uploader, err := bson.ObjectIDFromHex(authorId)
+	if err != nil {
+		return user, err
+	}
+
+	media := ms.NewMedia(key, imageUri, input.ImageBytes, mr.KIND_PROFILE, uploader)
+	if err := s.mediaService.RecordUpload(ctx, media); err != nil {
+		return user, err
+	}
+
 	// set filename and url
 	input.ImageLocation = imageUri
 	input.ImageKey = input.Image.Filename
@@ -9,3 +9,8 @@ func GetAuthorID(ctx context.Context) (string, bool) {
 	authorID, ok := ctx.Value(ck.UserIDKey).(string)
 	return authorID, ok
 }
+
+func GetVisitorID(ctx context.Context) (string, bool) {
+	visitorID, ok := ctx.Value(ck.VisitorIDKey).(string)
+	return visitorID, ok && visitorID != ""
+}
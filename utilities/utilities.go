@@ -2,20 +2,24 @@ package handlers
 
 import (
 	ck "blog-api/contextkeys"
+	"blog-api/images"
 	br "blog-api/repositories/blog"
 	ur "blog-api/repositories/user"
 
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
+	"net/textproto"
 	"net/url"
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type ErrorResponse struct {
@@ -34,9 +38,9 @@ ParseBlogQueryParams modifies a pointer to BlogQuery in place.
 It looks up the required query values from the url.Values map,
 parsing those values and loading them into the BlogQuery struct
 if present, otherwise using the default initialized values from
-the struct.
+the struct. Returns an error describing the first invalid value.
 */
-func ParseBlogQueryParams(q *br.BlogQuery, v url.Values) {
+func ParseBlogQueryParams(q *br.BlogQuery, v url.Values) error {
 	offset := v.Get("offset")
 
 	if offset != "" {
@@ -45,6 +49,91 @@ func ParseBlogQueryParams(q *br.BlogQuery, v url.Values) {
 			q.Offset = parsedOffset
 		}
 	}
+
+	q.Cursor = v.Get("cursor")
+
+	if limit := v.Get("limit"); limit != "" {
+		parsedLimit, err := strconv.Atoi(limit)
+		if err != nil || parsedLimit < 1 || parsedLimit > br.MAX_PAGE_SIZE {
+			return fmt.Errorf("limit must be a number from 1 to %d", br.MAX_PAGE_SIZE)
+		}
+
+		q.Limit = parsedLimit
+	}
+
+	if sort := v.Get("sort"); sort != "" {
+		if !br.ValidSort(sort, true) {
+			return fmt.Errorf(
+				"sort must be one of %s, %s, %s, %s or %s (search only)",
+				br.SORT_NEWEST, br.SORT_OLDEST, br.SORT_MOST_VIEWED, br.SORT_MOST_LIKED, br.SORT_RELEVANCE,
+			)
+		}
+
+		q.Sort = sort
+	}
+
+	var err error
+
+	if q.From, q.To, err = ParseDateRange(v); err != nil {
+		return err
+	}
+
+	if category := v.Get("category"); category != "" {
+		q.Categories = strings.Split(category, ",")
+	}
+
+	switch match := v.Get("match"); match {
+	case "":
+	case br.CATEGORY_MATCH_ALL, br.CATEGORY_MATCH_ANY:
+		q.CategoryMatch = match
+	default:
+		return fmt.Errorf("match must be %s or %s", br.CATEGORY_MATCH_ALL, br.CATEGORY_MATCH_ANY)
+	}
+
+	return nil
+}
+
+// ParseDateRange parses the optional from and to query values,
+// to is exclusive unless it is a date which includes that day
+func ParseDateRange(v url.Values) (*time.Time, *time.Time, error) {
+	from, err := parseDateParam(v.Get("from"), false)
+	if err != nil {
+		return nil, nil, fmt.Errorf("from %v", err)
+	}
+
+	to, err := parseDateParam(v.Get("to"), true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("to %v", err)
+	}
+
+	if from != nil && to != nil && !from.Before(*to) {
+		return nil, nil, fmt.Errorf("from must be before to")
+	}
+
+	return from, to, nil
+}
+
+// parseDateParam accepts an RFC 3339 time or a date. A date used
+// as the end of a range includes the whole of that day.
+func parseDateParam(value string, end bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return &parsed, nil
+	}
+
+	parsed, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, fmt.Errorf("must be a date (2006-01-02) or RFC 3339 time")
+	}
+
+	if end {
+		parsed = parsed.AddDate(0, 0, 1)
+	}
+
+	return &parsed, nil
 }
 
 func WriteJSONErr(w http.ResponseWriter, status int, err error) {
@@ -65,6 +154,14 @@ func WriteJSON(w http.ResponseWriter, status int, data any) {
 	}
 }
 
+// WriteBody writes a pre-encoded response body such as xml
+func WriteBody(w http.ResponseWriter, status int, contentType string, body []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+
+	w.Write(body)
+}
+
 func handleFallBackResponse(w http.ResponseWriter) {
 	fallback := `{"error": "Internal Server Error - failed to encode response"}`
 
@@ -113,17 +210,23 @@ func ParseMultiPartForm[T any](reader *multipart.Reader, input *T) error {
 				continue
 			}
 
+			// trust the bytes over the client's filename and content type
+			upload, err := images.Validate(fileBuffer.Bytes())
+			if err != nil {
+				return err
+			}
+
 			fileHeader := &multipart.FileHeader{
-				Filename: part.FileName(),
-				Header:   part.Header,
-				Size:     size,
+				Filename: images.WithExtension(part.FileName(), upload.ContentType),
+				Header:   textproto.MIMEHeader{"Content-Type": {upload.ContentType}},
+				Size:     int64(len(upload.Data)),
 			}
 
 			if field := value.FieldByName("Image"); field.IsValid() {
 				field.Set(reflect.ValueOf(fileHeader))
 			}
 			if field := value.FieldByName("ImageBytes"); field.IsValid() {
-				field.Set(reflect.ValueOf(fileBuffer.Bytes()))
+				field.Set(reflect.ValueOf(upload.Data))
 			}
 			continue
 		}
@@ -155,6 +258,14 @@ func ParseMultiPartForm[T any](reader *multipart.Reader, input *T) error {
 				return err
 			}
 			field.SetBool(parsedBool)
+		case reflect.Ptr:
+			if field.Type() == reflect.TypeOf(&time.Time{}) && fieldValue != "" {
+				parsedTime, err := time.Parse(time.RFC3339, fieldValue)
+				if err != nil {
+					return err
+				}
+				field.Set(reflect.ValueOf(&parsedTime))
+			}
 		case reflect.Slice:
 			if field.Type().Elem().Kind() == reflect.String {
 				var parsedSlice []string
@@ -169,6 +280,24 @@ func ParseMultiPartForm[T any](reader *multipart.Reader, input *T) error {
 	return nil
 }
 
+/*
+UploadErrorStatus returns the response status for an error parsing an
+upload: 415 for files that aren't an allowed image type, 413 for bodies
+or image dimensions over the limit, otherwise the fallback status.
+*/
+func UploadErrorStatus(err error, fallback int) int {
+	var maxBytesErr *http.MaxBytesError
+
+	switch {
+	case errors.Is(err, images.ErrUnsupportedType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, images.ErrTooLarge), errors.As(err, &maxBytesErr):
+		return http.StatusRequestEntityTooLarge
+	}
+
+	return fallback
+}
+
 func ParseMultiPartFormBlogUpdate(reader *multipart.Reader) (*br.UpdateBlogInput, error) {
 	input := &br.UpdateBlogInput{}
 	err := ParseMultiPartForm(reader, input)